@@ -13,6 +13,10 @@
 //
 // 	sauron --conf=sauron.conf
 //
+// Without --conf nor --config-dir, every command loads sauron.conf next to the
+// binary. The reload and stop commands check the AllowedUsers of the config
+// the running sauron loaded, as recorded next to its pid file.
+//
 // Most of the code for this tool is available as a standalone package,
 // checkout the https://github.com/kykim79/sauron package.
 package main
@@ -48,13 +52,143 @@ func main() {
 			Name:  "prefix-path",
 			Usage: "prefix file path to every output line (default)",
 		},
+		cli.DurationFlag{
+			Name:  "duration",
+			Usage: "stop gracefully after running this long, such as 1h, or never when 0",
+		},
+		cli.BoolFlag{
+			Name:  "quiet",
+			Usage: "write no operational logs, config nor summary, only the matched lines",
+		},
+		cli.BoolFlag{
+			Name:  "once",
+			Usage: "read existing files from the beginning, print matching lines and exit",
+		},
+		cli.BoolFlag{
+			Name:  "count",
+			Usage: "write how many lines each watch matched, periodically and on exit, instead of the lines",
+		},
 		cli.BoolFlag{
 			Name:  "prefix-time",
 			Usage: "prefix time to every output line",
 		},
+		cli.BoolFlag{
+			Name:  "prefix-host",
+			Usage: "prefix host name to every output line",
+		},
+		cli.BoolFlag{
+			Name:  "prefix-id",
+			Usage: "prefix the ID of its watch to every output line",
+		},
 		cli.StringFlag{
 			Name:  "conf",
-			Usage: "config file",
+			Usage: "config file, or - to read it from the standard input, sauron.conf next to the binary unless --config-dir is given",
+		},
+		cli.StringFlag{
+			Name:  "config-dir",
+			Usage: "directory of *.toml config files to load",
+		},
+		cli.BoolFlag{
+			Name:  "print-config",
+			Usage: "print the loaded config to standard output",
+		},
+		cli.BoolFlag{
+			Name:  "no-pid-file",
+			Usage: "neither write the pid file nor check for a running instance",
+		},
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "only write lines timestamped after this time (RFC 3339) or duration ago, see TimestampPattern",
+		},
+	}
+
+	app.Commands = []cli.Command{
+		{
+			Name:   "reload",
+			Usage:  "ask the running sauron to reload its config",
+			Action: console.ReloadAction,
+		},
+		{
+			Name:   "stop",
+			Usage:  "ask the running sauron to shut down",
+			Action: console.StopAction,
+		},
+		{
+			Name:   "validate",
+			Usage:  "check the patterns of the config, reporting the invalid and slow ones",
+			Action: console.ValidateAction,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "conf",
+					Usage: "config file, sauron.conf next to the binary unless --config-dir is given",
+				},
+				cli.StringFlag{
+					Name:  "config-dir",
+					Usage: "directory of *.toml config files to load",
+				},
+				cli.BoolFlag{
+					Name:  "strict",
+					Usage: "exit with an error when a pattern has an issue",
+				},
+			},
+		},
+		{
+			Name:   "check-health",
+			Usage:  "check that sauron runs, its outputs are writable and its paths accessible; exits with 2, 3 or 4 on the first failing check",
+			Action: console.CheckHealthAction,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "conf",
+					Usage: "config file, sauron.conf next to the binary unless --config-dir is given",
+				},
+				cli.StringFlag{
+					Name:  "config-dir",
+					Usage: "directory of *.toml config files to load",
+				},
+			},
+		},
+		{
+			Name:   "list-files",
+			Usage:  "list the files under the paths of every watch, whether they would be followed and why not",
+			Action: console.ListFilesAction,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "conf",
+					Usage: "config file, sauron.conf next to the binary unless --config-dir is given",
+				},
+				cli.StringFlag{
+					Name:  "config-dir",
+					Usage: "directory of *.toml config files to load",
+				},
+			},
+		},
+		{
+			Name:      "test-line",
+			Usage:     "show which watches would write a sample line, and how",
+			ArgsUsage: "\"sample line\"",
+			Action:    console.TestLineAction,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "conf",
+					Usage: "config file, sauron.conf next to the binary unless --config-dir is given",
+				},
+				cli.StringFlag{
+					Name:  "config-dir",
+					Usage: "directory of *.toml config files to load",
+				},
+				cli.BoolTFlag{
+					Name:  "prefix-path",
+					Usage: "prefix file path to every output line (default)",
+				},
+				cli.BoolFlag{
+					Name:  "prefix-time",
+					Usage: "prefix time to every output line",
+				},
+				cli.BoolFlag{
+					Name:  "prefix-id",
+					Usage: "prefix the ID of its watch to every output line",
+				},
+			},
 		},
 	}
 
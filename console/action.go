@@ -3,68 +3,79 @@ package console
 
 import (
 	"../eye"
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"github.com/BurntSushi/toml"
 	"github.com/Sirupsen/logrus"
 	"github.com/jasonlvhit/gocron"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 	"gopkg.in/urfave/cli.v1"
+	"io"
 	"io/ioutil"
+	"math"
+	"net"
 	"os"
 	"os/signal"
+	"os/user"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strconv"
-	"syscall"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-type duration struct {
-	time.Duration
-}
-
-func (d *duration) UnmarshalText(text []byte) error {
-	var err error
-	d.Duration, err = time.ParseDuration(string(text))
-	return err
-}
-
-type Config struct {
-	Watch      []watch
-	Log        string // sauron log
-	Pool       bool
-	LogLevel   string
-	PrefixTime bool // prefix time to every output line
-	PrefixPath bool // prefix file path to every output line (default)
-}
-
-type watch struct {
-	Paths              []string
-	FilePattern        string // file extension pattern
-	FileIgnorePattern  string
-	FileIgnoreDuration duration
-	FileFollowDuration duration
-	PathPattern        string // path pattern
-	LinePattern        string // pattern to match
-	LineIgnorePattern  string // pattern to ignore
-	Out                string // file to write
-	Desc               string
-}
+var logger = logrus.New()
 
-var logger *logrus.Logger
+// quiet silences the operational logs, the config print and the summary,
+// leaving only the lines written to the outputs. It is set from the config at
+// startup.
+var quiet bool
 
 // MainAction is the main action executed when using Sauron.
 func MainAction(c *cli.Context) {
 	done := make(chan bool)
-	writePidFile(c)
 
 	conf, result := setConfig(c)
 	if !result {
 		return
 	}
 
+	// Containers and read-only filesystems have no use for the pid file.
+	if !c.Bool("no-pid-file") && !conf.NoPidFile {
+		writePidFile(c)
+	}
+
 	setLogger(conf)
+	watchLevelSignal()
+	watchReloadSignal(func() {
+		reloadLogging(c)
+		runReloadHooks()
+	})
+
+	if c.Bool("print-config") && !quiet {
+		printConfig(conf, os.Stdout)
+	}
+
+	if c.Bool("prefix-host") || conf.PrefixHost {
+		host = resolveHostname()
+	}
+
+	if value := c.String("since"); value != "" {
+		cutoff, err := parseSince(value, time.Now())
+		if err != nil {
+			logger.Errorln("Invalid --since " + value + ": " + err.Error())
+			return
+		}
+		since = cutoff
+	}
+
+	if conf.HealthAddr != "" && !c.Bool("once") {
+		serveHealth(conf.HealthAddr, status)
+	}
 
 	options := &eye.TrailOptions{
 		PollChanges: conf.Pool,
@@ -77,107 +88,584 @@ func MainAction(c *cli.Context) {
 		<-s.Start()
 	}
 
+	setBreakerSettings(conf)
+
+	if conf.OutSyncInterval.Duration != 0 {
+		syncInterval = conf.OutSyncInterval.Duration
+	}
+	syncEveryLine = conf.OutSyncEveryLine
+
+	if conf.TimeZone != "" {
+		if location, err := time.LoadLocation(conf.TimeZone); err == nil {
+			outLocation = location
+		} else {
+			logger.Errorln("Unknown time zone " + conf.TimeZone + ", using the local one")
+		}
+	}
+
+	if conf.WebhookBatchSize > 0 {
+		webhookBatchSize = conf.WebhookBatchSize
+	}
+	if conf.WebhookFlushInterval.Duration > 0 {
+		webhookFlushInterval = conf.WebhookFlushInterval.Duration
+	}
+	for class, retries := range map[webhookFailure]int{
+		dnsFailure:     conf.WebhookDNSRetries,
+		connectFailure: conf.WebhookConnectRetries,
+		timeoutFailure: conf.WebhookTimeoutRetries,
+		serverFailure:  conf.WebhookServerRetries,
+	} {
+		if retries != 0 {
+			webhookRetries[class] = retries
+		}
+	}
+	if conf.WebhookRetryDelay.Duration > 0 {
+		webhookRetryDelay = conf.WebhookRetryDelay.Duration
+	}
+	if conf.CountInterval.Duration > 0 {
+		countInterval = conf.CountInterval.Duration
+	}
+
+	// Handlers run on a bounded pool of workers shared by every watch when
+	// HandlerWorkers is set.
+	var pool *eye.WorkerPool
+	if conf.HandlerWorkers > 0 {
+		pool = eye.NewWorkerPool(conf.HandlerWorkers, logger)
+	}
+
+	if conf.Combined != "" {
+		if err := openCombined(conf.Combined, conf.CombinedWindow.Duration); err != nil {
+			logger.Errorln(err)
+			return
+		}
+	}
+
+	// The signals of every watch, which each report on done once stopped.
+	var shutdowns []chan os.Signal
+
 	for _, w := range conf.Watch {
-		if outLog, err := os.OpenFile(w.Out, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+		if combined != nil {
+			w = combineWatch(w, conf.Combined)
+		}
 
-			if len(w.FilePattern) > 0 {
-				if r, err := regexp.Compile(w.FilePattern); err == nil {
-					logger.Debugln("FilePatternRegex created")
-					options.FileReg = r
-				} else {
-					logger.Errorln(err)
-				}
+		if routes, err := openRoutes(w); err == nil {
+			setTrailOptions(options, conf, w)
+			dirOptions := &eye.DirectoryWatcherOptions{
+				Recursive:      w.Recursive,
+				MaxWatches:     w.MaxWatches,
+				FollowSymlinks: w.FollowSymlinks,
+				Logger:         options.Logger,
 			}
 
-			if len(w.FileIgnorePattern) > 0 {
-				if r, err := regexp.Compile(w.FileIgnorePattern); err == nil {
-					options.FileIgnoreReg = r
-				} else {
-					logger.Errorln(err)
-				}
-			}
+			ignoreReg := compilePattern(anyPattern(w.LineIgnorePattern, w.LineIgnorePatterns))
 
-			if w.FileIgnoreDuration.Duration > 0 {
-				options.FileIgnoreDuration = w.FileIgnoreDuration.Duration
-			} else {
-				d, _ := time.ParseDuration("24h")
-				options.FileIgnoreDuration = d * 7
+			handler := getHandler(c, routes, ignoreReg, w)
+			if pool != nil {
+				handler = pool.Handler(handler)
 			}
 
-			if w.FileFollowDuration.Duration > 0 {
-				options.FileFollowDuration = w.FileFollowDuration.Duration
-			} else {
-				d, _ := time.ParseDuration("24h")
-				options.FileFollowDuration = d * 7
-			}
+			var (
+				trails      []*eye.Trail
+				schedules   = make(map[*eye.Trail]chan bool)
+				trailsMutex sync.Mutex
+				stopping    bool
+				waiting     = make(chan bool)
+			)
+
+			// follow creates the trail of a path and begins following it.
+			// No trail is created once stopping.
+			follow := func(watcher eye.Watcher, trailOptions *eye.TrailOptions) (*eye.Trail, error) {
+				trailsMutex.Lock()
+				defer trailsMutex.Unlock()
+
+				if stopping {
+					return nil, nil
+				}
+
+				trail := eye.NewTrailWithOptions(watcher, trailOptions)
+				if err := trail.Follow(handler); err != nil {
+					return nil, err
+				}
 
-			if len(w.PathPattern) > 0 {
-				if r, err := regexp.Compile(w.PathPattern); err == nil {
-					options.PathReg = r
+				trails = append(trails, trail)
+				status.add(1)
+
+				go func() {
+					trail.AddUnfollower()
+				}()
+
+				// How long the handler takes per line is served on /metrics,
+				// or logged periodically when there is none.
+				if conf.HealthAddr != "" {
+					addLatencyTrail(trail, trailOptions.Desc)
 				} else {
-					logger.Errorln(err)
+					s := gocron.NewScheduler()
+					s.Every(60).Seconds().Do(trail.LogHandlerLatency)
+					schedules[trail] = s.Start()
 				}
+
+				return trail, nil
 			}
 
-			var lineReg *regexp.Regexp
-			if len(w.LinePattern) > 0 {
-				if r, err := regexp.Compile(w.LinePattern); err == nil {
-					lineReg = r
-				} else {
-					logger.Errorln(err)
+			// unfollow ends a trail which is no longer needed.
+			unfollow := func(trail *eye.Trail) {
+				trailsMutex.Lock()
+				defer trailsMutex.Unlock()
+
+				for i, t := range trails {
+					if t == trail {
+						trails = append(trails[:i], trails[i+1:]...)
+						status.add(-1)
+						break
+					}
+				}
+
+				if stop, ok := schedules[trail]; ok {
+					stop <- true
+					delete(schedules, trail)
 				}
+				removeLatencyTrail(trail)
+
+				trail.End()
 			}
 
-			var ignoreReg *regexp.Regexp
-			if len(w.LineIgnorePattern) > 0 {
-				if r, err := regexp.Compile(w.LineIgnorePattern); err == nil {
-					ignoreReg = r
+			// Paths listed in a PathsFile are read through along with the
+			// others in once mode, and followed by trails of their own
+			// otherwise, so that they can be added and dropped on reload.
+			paths := w.Paths
+			if w.PathsFile != "" && c.Bool("once") {
+				if listed, err := readPathsFile(w.PathsFile); err == nil {
+					paths = append(append([]string{}, paths...), listed...)
 				} else {
 					logger.Errorln(err)
 				}
 			}
 
-			var trails []*eye.Trail
-			for _, directory := range w.Paths {
-				if watcher, err := eye.NewDirectoryWatcher(directory); err == nil {
-					// Create the new instance of the trail and begin following it.
-					trail := eye.NewTrailWithOptions(watcher, options)
+			for _, path := range paths {
+				// Paths that don't exist yet are followed once they get
+				// created.
+				if _, err := os.Stat(path); os.IsNotExist(err) && w.WaitForPath && !c.Bool("once") {
+					logger.Infoln("Waiting for " + path + " to be created")
+
+					waitOptions := *options
+					go func(path string) {
+						if ok, err := eye.WaitForPath(path, waiting); !ok {
+							if err != nil {
+								logger.Errorln(err)
+							}
+							return
+						}
+
+						logger.Infoln("Created: " + path)
+
+						watcher, trailOptions, err := newWatcher(path, &waitOptions, dirOptions)
+						if err == nil {
+							_, err = follow(watcher, trailOptions)
+						}
+
+						if err != nil {
+							logger.Errorln(err)
+						}
+					}(path)
+
+					continue
+				}
+
+				watcher, trailOptions, err := newWatcher(path, options, dirOptions)
 
-					if err = trail.Follow(getHandler(c, outLog, lineReg, ignoreReg, w)); err == nil {
-						trails = append(trails, trail)
-					} else {
+				// In once mode, existing files are read through and nothing
+				// is followed.
+				if err == nil && c.Bool("once") {
+					trail := eye.NewTrailWithOptions(watcher, trailOptions)
+					if err = trail.Once(handler); err != nil {
 						logger.Errorln(err)
+					}
+
+					continue
+				}
+
+				if err == nil {
+					_, err = follow(watcher, trailOptions)
+				}
+
+				if err != nil {
+					logger.Errorln(err)
+
+					// In once mode, the other paths are read through anyway.
+					if c.Bool("once") {
+						continue
+					}
+
+					// Watchers which fail to start are restarted until they
+					// succeed, as they may recover from a transient failure.
+					delay, maxDelay := restartDelays(w)
+					if delay < 0 {
 						return
 					}
 
-					go func() {
-						trail.AddUnfollower()
-					}()
+					restartOptions := *options
+					go restartWatch(path, delay, maxDelay, func() error {
+						watcher, trailOptions, err := newWatcher(path, &restartOptions, dirOptions)
+						if err != nil {
+							return err
+						}
+
+						_, err = follow(watcher, trailOptions)
+						return err
+					}, waiting)
+				}
+			}
+
+			if w.PathsFile != "" && !c.Bool("once") {
+				listedOptions := *options
+				listed := newPathsFileTrails(w.PathsFile, func(path string) (*eye.Trail, error) {
+					watcher, trailOptions, err := newWatcher(path, &listedOptions, dirOptions)
+					if err != nil {
+						return nil, err
+					}
+
+					return follow(watcher, trailOptions)
+				}, unfollow)
+
+				listed.sync()
+				onReload(listed.sync)
+			}
+
+			// The start event is written once per watch, counting the files
+			// of the trails of every path, and not again as trails are
+			// restarted or added later on.
+			if w.EmitStartEvent && !c.Bool("once") {
+				trailsMutex.Lock()
+				line := startLine(options.Desc, trails)
+				trailsMutex.Unlock()
+
+				if err := handler(line); err != nil {
+					logger.Errorln(err)
+				}
+			}
+
+			var remotes []*eye.RemoteTrail
+			if w.Remote != nil && !c.Bool("once") {
+				if remoteTrail, err := newRemoteTrail(*w.Remote, options.LineReg); err == nil {
+					remoteTrail.Follow(handler)
+					remotes = append(remotes, remoteTrail)
+					status.add(1)
 				} else {
 					logger.Errorln(err)
 					return
 				}
 			}
 
-			// Wait for an interrupt or kill signal.
+			if c.Bool("once") {
+				continue
+			}
+
+			// Wait for an interrupt or termination signal.
 			signalChan := make(chan os.Signal, 1)
-			signal.Notify(signalChan, os.Interrupt)
+			signal.Notify(signalChan, shutdownSignals...)
+			shutdowns = append(shutdowns, signalChan)
 			go func() {
 				for sig := range signalChan {
-					if sig == os.Interrupt || sig == os.Kill {
+					if isShutdownSignal(sig) {
+						status.stop()
+						trailsMutex.Lock()
+						if !stopping {
+							stopping = true
+							close(waiting)
+						}
 						for _, trail := range trails {
 							trail.End()
 						}
+						trailsMutex.Unlock()
+						for _, remoteTrail := range remotes {
+							remoteTrail.End()
+						}
 						done <- true
 					}
 				}
 			}()
 		} else {
 			logger.Errorln(err)
+
+			// In once mode, the other watches are read through anyway.
+			if c.Bool("once") {
+				continue
+			}
 			return
 		}
 	}
-	<-done
+
+	if c.Bool("once") {
+		if pool != nil {
+			pool.Close()
+		}
+		stopHeartbeats()
+		stopMatchCounters()
+		stopExecRunners()
+		closeOutputs()
+		reportSummary()
+		return
+	}
+
+	// Once the duration elapsed, every watch stops as on an interrupt.
+	if duration := c.Duration("duration"); duration > 0 {
+		time.AfterFunc(duration, func() {
+			logger.Infoln("Ran for " + duration.String() + ", stopping")
+			for _, shutdown := range shutdowns {
+				select {
+				case shutdown <- os.Interrupt:
+				default:
+				}
+			}
+		})
+	}
+
+	for range shutdowns {
+		<-done
+	}
+	if pool != nil {
+		pool.Close()
+	}
+	stopHeartbeats()
+	stopMatchCounters()
+	stopExecRunners()
+	closeOutputs()
+	reportSummary()
+}
+
+// reportSummary logs the activity of the session and prints it to the
+// standard error, unless running quietly.
+func reportSummary() {
+	if quiet {
+		return
+	}
+
+	logger.Infoln(report.String())
+	fmt.Fprintln(os.Stderr, report.String())
+}
+
+// setTrailOptions sets the options of the trails of a watch, logging the
+// invalid ones.
+func setTrailOptions(options *eye.TrailOptions, conf Config, w watch) {
+	options.Desc = w.Desc
+	options.Logger = watchLogger(w)
+	options.SkipBinary = w.SkipBinary
+	options.InitialOrder = w.InitialOrder
+	options.SeekStart = w.SeekStart
+	options.MinFileSize = w.MinFileSize
+	options.MaxFileSize = w.MaxFileSize
+	options.LatestOnly = w.LatestOnly
+	options.MaxFiles = w.MaxFiles
+	options.DisableUnfollower = w.EnableUnfollower != nil && !*w.EnableUnfollower
+	options.ActiveWindow = w.ActiveWindow.Duration
+	options.ReOpen = w.ReOpen
+	options.ReopenOnTruncate = w.ReopenOnTruncate
+	options.FollowAfterRemove = w.FollowAfterRemove.Duration
+	options.TailErrorRetries = w.TailErrorRetries
+	options.MaxLineSize = w.TailMaxLineSize
+	options.RateLimitSize = tailRateLimit(w.TailRateLimit)
+	options.RateLimitInterval = w.TailRateInterval.Duration
+	options.HandlerBufferSize = w.HandlerBufferSize
+	options.HandlerBufferPolicy = w.HandlerBufferPolicy
+	options.DebounceInterval = w.DebounceInterval.Duration
+	options.NewFileGrace = w.NewFileGrace.Duration
+	options.Snapshot = w.Snapshot
+	options.LineReg = compilePattern(anyPattern(w.LinePattern, w.LinePatterns))
+
+	switch w.SeekStart {
+	case "", eye.SeekFromEnd, eye.SeekFromStart:
+	default:
+		logger.Errorln("Unknown seek start " + w.SeekStart + ", following from the end instead")
+	}
+
+	switch w.InitialOrder {
+	case "", eye.OrderPath, eye.OrderModTime:
+	default:
+		logger.Errorln("Unknown initial order " + w.InitialOrder + ", sorting by path instead")
+	}
+
+	switch w.HandlerBufferPolicy {
+	case "", eye.BufferBlock, eye.BufferDropOldest:
+	default:
+		logger.Errorln("Unknown handler buffer policy " + w.HandlerBufferPolicy + ", blocking instead")
+	}
+
+	if len(w.FilePattern) > 0 {
+		if r, err := regexp.Compile(w.FilePattern); err == nil {
+			logger.Debugln("FilePatternRegex created")
+			options.FileReg = r
+		} else {
+			logger.Errorln(err)
+		}
+	}
+
+	options.GzipStreamReg = nil
+	if len(w.GzipStreamPattern) > 0 {
+		if r, err := regexp.Compile(w.GzipStreamPattern); err == nil {
+			options.GzipStreamReg = r
+		} else {
+			logger.Errorln(err)
+		}
+	}
+
+	if len(w.FileIgnorePattern) > 0 {
+		if r, err := regexp.Compile(w.FileIgnorePattern); err == nil {
+			options.FileIgnoreReg = r
+		} else {
+			logger.Errorln(err)
+		}
+	}
+
+	options.FileIgnoreDuration = fileDuration(w.FileIgnoreDuration, conf.DefaultFileIgnoreDuration)
+
+	options.FileModifiedAfter = nil
+	if w.FileModifiedAfter != "" {
+		if m, err := eye.ParseModifiedAfter(w.FileModifiedAfter); err == nil {
+			options.FileModifiedAfter = m
+		} else {
+			logger.Errorln(err)
+		}
+	}
+
+	options.FileFollowDuration = fileDuration(w.FileFollowDuration, conf.DefaultFileFollowDuration)
+
+	if len(w.PathPattern) > 0 {
+		if r, err := regexp.Compile(w.PathPattern); err == nil {
+			options.PathReg = r
+		} else {
+			logger.Errorln(err)
+		}
+	}
+
+	options.PathIgnoreReg = nil
+	if len(w.PathIgnorePattern) > 0 {
+		if r, err := regexp.Compile(w.PathIgnorePattern); err == nil {
+			options.PathIgnoreReg = r
+		} else {
+			logger.Errorln(err)
+		}
+	}
+
+	options.FullPathReg = nil
+	if len(w.FullPathPattern) > 0 {
+		if r, err := regexp.Compile(w.FullPathPattern); err == nil {
+			options.FullPathReg = r
+		} else {
+			logger.Errorln(err)
+		}
+	}
+}
+
+// newWatcher creates the watcher for a path of a watch. Regular files are
+// followed directly, in which case the file filters of the options are dropped
+// since the file was named explicitly. With FollowSymlinks, a symlink to a
+// file is followed to its target, and to the new one when it is repointed.
+// Anything else is watched as a directory, with dirOptions.
+func newWatcher(path string, options *eye.TrailOptions, dirOptions *eye.DirectoryWatcherOptions) (eye.Watcher, *eye.TrailOptions, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+		var watcher eye.Watcher
+		if dirOptions.FollowSymlinks && eye.IsSymlink(path) {
+			watcher, err = eye.NewSymlinkWatcher(path)
+		} else {
+			watcher, err = eye.NewFileWatcher(path)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		fileOptions := *options
+		fileOptions.FileReg = nil
+		fileOptions.FileIgnoreReg = nil
+		fileOptions.PathReg = nil
+		fileOptions.PathIgnoreReg = nil
+		fileOptions.FullPathReg = nil
+
+		return watcher, &fileOptions, nil
+	}
+
+	watcher, err := eye.NewDirectoryWatcherWithOptions(path, dirOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return watcher, options, nil
+}
+
+// newRemoteTrail creates a trail following a file over SFTP, authenticating
+// with the configured private key and verifying the host against known_hosts.
+// Lines matching lineReg are flagged as matched.
+func newRemoteTrail(r remote, lineReg *regexp.Regexp) (*eye.RemoteTrail, error) {
+	key, err := ioutil.ReadFile(r.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	knownHosts := r.KnownHosts
+	if knownHosts == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	hostKeyCallback, err := knownhosts.New(knownHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := r.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	return eye.NewRemoteTrail(&eye.RemoteOptions{
+		Addr: addr,
+		Path: r.Path,
+		ClientConfig: &ssh.ClientConfig{
+			User:            r.User,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         10 * time.Second,
+		},
+		LineReg: lineReg,
+		Logger:  logger,
+	}), nil
+}
+
+// startLine returns the line written with EmitStartEvent, counting the files
+// the trails of a watch found when they began following.
+func startLine(desc string, trails []*eye.Trail) eye.Line {
+	files := 0
+	for _, trail := range trails {
+		files += trail.FoundFiles()
+	}
+
+	return eye.StartLine(desc, files)
+}
+
+// toggleLogLevel switches the logger between the info and debug levels. Any
+// level other than debug is switched to debug.
+func toggleLogLevel() {
+	level := logrus.DebugLevel
+	if logger.GetLevel() == logrus.DebugLevel {
+		level = logrus.InfoLevel
+	}
+
+	logger.SetLevel(level)
+	logger.Infoln("Log level changed to " + level.String())
+
+	watchLoggersMutex.Lock()
+	defer watchLoggersMutex.Unlock()
+
+	for _, l := range watchLoggers {
+		l.SetLevel(level)
+	}
 }
 
 func setLogger(conf Config) {
@@ -185,6 +673,11 @@ func setLogger(conf Config) {
 	logger = logrus.New()
 	logger.Level.UnmarshalText([]byte(conf.LogLevel))
 	logger.SetOutput(ioutil.Discard)
+	quiet = conf.Quiet
+	if quiet {
+		logger.SetLevel(logrus.PanicLevel)
+		return
+	}
 	if len(conf.Log) > 0 {
 		if f, err := os.OpenFile(conf.Log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
 			logger.SetOutput(f)
@@ -193,93 +686,854 @@ func setLogger(conf Config) {
 		}
 	}
 	if b, err := json.Marshal(conf); err == nil {
-		fmt.Println("config: " + string(b))
+		logger.Debugln("config: " + string(b))
 	}
 }
 
-func setConfig(c *cli.Context) (Config, bool) {
-	var conf Config
-	if _, err := toml.DecodeFile(c.String("conf"), &conf); err != nil {
+// watchLoggers are the loggers of the watches with their own Log, by path.
+var (
+	watchLoggers      = map[string]*logrus.Logger{}
+	watchLoggersMutex sync.Mutex
+)
+
+// watchLogger returns the logger of the operational logs of a watch's trail.
+// Watches with their own Log get a logger writing to it, at the level of the
+// global one and shared with the watches logging to the same file, while the
+// others, and every watch when running quietly, use the global logger.
+func watchLogger(w watch) *logrus.Logger {
+	if w.Log == "" || quiet {
+		return logger
+	}
+
+	watchLoggersMutex.Lock()
+	defer watchLoggersMutex.Unlock()
+
+	if l, ok := watchLoggers[w.Log]; ok {
+		return l
+	}
+
+	f, err := os.OpenFile(w.Log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
 		logger.Errorln(err)
-		return conf, false
+		return logger
 	}
 
-	if c.IsSet("pool") {
-		conf.Pool = c.Bool("pool")
+	l := logrus.New()
+	l.SetLevel(logger.GetLevel())
+	l.SetOutput(f)
+	watchLoggers[w.Log] = l
+
+	return l
+}
+
+// printConfig writes the marshaled config to out, for users who asked to see
+// it with --print-config.
+func printConfig(conf Config, out io.Writer) {
+	if b, err := json.Marshal(conf); err == nil {
+		fmt.Fprintln(out, "config: "+string(b))
+	}
+}
+
+// route is a destination for the lines of a watch matching a pattern.
+type route struct {
+	lineReg *regexp.Regexp
+	out     *output
+}
+
+// openRoutes opens the outputs of a watch, as listed by watchRules.
+func openRoutes(w watch) ([]route, error) {
+	var routes []route
+
+	for _, r := range watchRules(w) {
+		out, err := openOutRetrying(r.Out, w.OutOpenRetries)
+		if err != nil {
+			return nil, err
+		}
+		setOutPermissions(out, w)
+		setOutArchive(out, w)
+		setOutKafka(out, w)
+
+		routes = append(routes, route{lineReg: compilePattern(r.LinePattern), out: out})
+	}
+
+	return routes, nil
+}
+
+// watchRules lists the destinations of a watch: its own Out, filtered by its
+// LinePattern, followed by its rules. Out may be left empty when the watch has
+// rules.
+func watchRules(w watch) []rule {
+	var rules []rule
+
+	if w.Out != "" || len(w.Rules) == 0 {
+		rules = append(rules, rule{LinePattern: anyPattern(w.LinePattern, w.LinePatterns), Out: w.Out})
+	}
+
+	return append(rules, w.Rules...)
+}
+
+// tailRateLimit bounds the TailRateLimit of a watch to what the tail library
+// supports, treating negative values as disabled.
+func tailRateLimit(limit int) uint16 {
+	if limit <= 0 {
+		return 0
+	}
+
+	if limit > math.MaxUint16 {
+		return math.MaxUint16
+	}
+
+	return uint16(limit)
+}
+
+// defaultFileDuration is how long files are ignored or followed after their
+// last modification when neither the watch nor the config says.
+const defaultFileDuration = 7 * 24 * time.Hour
+
+// fileDuration returns the FileIgnoreDuration or FileFollowDuration of a
+// watch, inheriting the default of the config when the watch sets none.
+func fileDuration(d, inherited duration) time.Duration {
+	if d.Duration > 0 {
+		return d.Duration
+	}
+
+	if inherited.Duration > 0 {
+		return inherited.Duration
+	}
+
+	return defaultFileDuration
+}
+
+// anyPattern combines a pattern and a list of patterns into a single one,
+// matching whatever any of them matches. Flags set within a pattern, such as
+// (?i), only apply to that pattern.
+func anyPattern(pattern string, patterns []string) string {
+	var nonEmpty []string
+	for _, p := range append([]string{pattern}, patterns...) {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
 	}
 
-	if len(conf.LogLevel) == 0 {
-		conf.LogLevel = "info"
+	if len(nonEmpty) <= 1 {
+		return strings.Join(nonEmpty, "")
 	}
 
-	return conf, true
+	return "(?:" + strings.Join(nonEmpty, ")|(?:") + ")"
+}
+
+// compilePattern compiles a non-empty pattern, logging invalid ones.
+func compilePattern(pattern string) *regexp.Regexp {
+	if len(pattern) == 0 {
+		return nil
+	}
+
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		logger.Errorln(err)
+		return nil
+	}
+
+	return r
+}
+
+var (
+	outputs      = make(map[string]*output)
+	outputsMutex sync.Mutex
+)
+
+// setBreakerSettings applies the breaker settings of the config to the outputs
+// opened afterwards.
+func setBreakerSettings(conf Config) {
+	if conf.OutBreakerThreshold != 0 {
+		breakerThreshold = conf.OutBreakerThreshold
+	}
+
+	if conf.OutBreakerCooldown.Duration > 0 {
+		breakerCooldown = conf.OutBreakerCooldown.Duration
+	}
+
+	switch conf.OutBreakerPolicy {
+	case "":
+	case breakerDrop, breakerBuffer:
+		breakerPolicy = conf.OutBreakerPolicy
+	default:
+		logger.Errorln("Unknown output breaker policy " + conf.OutBreakerPolicy + ", dropping instead")
+	}
+}
+
+// outRetryDelay is how long opening an output is first delayed after a
+// failure. The delay doubles after every further failure.
+var outRetryDelay = time.Second
+
+// openOutRetrying opens an output like openOut, retrying with an exponential
+// backoff when that fails. Retries default to 3, and are disabled when
+// negative.
+func openOutRetrying(path string, retries int) (*output, error) {
+	if retries == 0 {
+		retries = 3
+	}
+
+	delay := outRetryDelay
+	for attempt := 0; ; attempt++ {
+		out, err := openOut(path)
+		if err == nil || attempt >= retries {
+			return out, err
+		}
+
+		logger.Errorln("Failed to open " + path + ": " + err.Error() + ". Retrying in " + delay.String())
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// openOut opens an output file for appending, creating its missing parent
+// directories. Files are opened once and shared by every watch or rule writing
+// to them. An output of "-" stands for the standard output, tcp://host:port
+// and udp://host:port for a network collector, http:// and https:// URLs for a
+// webhook, kafka://broker:port/topic for a Kafka topic, syslog://host:port for
+// a syslog daemon, and paths with dates
+// such as out-%Y-%m-%d.log for files following the date, while paths ending
+// in .gz are written as gzip streams.
+func openOut(path string) (*output, error) {
+	if path == "-" {
+		return &output{file: os.Stdout}, nil
+	}
+
+	if isNetOut(path) || isWebhookOut(path) || isKafkaOut(path) || isSyslogOut(path) {
+		return openSinkOut(path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	outputsMutex.Lock()
+	defer outputsMutex.Unlock()
+
+	if out, ok := outputs[abs]; ok {
+		return out, nil
+	}
+
+	if isDatedOut(abs) {
+		out, err := newDatedOutput(abs)
+		if err != nil {
+			return nil, err
+		}
+
+		outputs[abs] = out
+
+		return out, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(abs, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	out := newOutput(file, strings.HasSuffix(abs, ".gz"))
+	outputs[abs] = out
+
+	return out, nil
+}
+
+// openSinkOut connects to the collector of a network output or to a syslog
+// daemon, or sets up a webhook or a Kafka producer. They are shared like
+// files.
+func openSinkOut(path string) (*output, error) {
+	outputsMutex.Lock()
+	defer outputsMutex.Unlock()
+
+	if out, ok := outputs[path]; ok {
+		return out, nil
+	}
+
+	var sink io.WriteCloser
+	if isWebhookOut(path) {
+		sink = newWebhook(path)
+	} else if isKafkaOut(path) {
+		var err error
+		if sink, err = newKafkaProducer(path); err != nil {
+			return nil, err
+		}
+	} else if isSyslogOut(path) {
+		var err error
+		if sink, err = dialSyslogSink(path); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if sink, err = dialNetSink(path); err != nil {
+			return nil, err
+		}
+	}
+
+	out := newSinkOutput(path, sink)
+	outputs[path] = out
+
+	return out, nil
+}
+
+// setOutPermissions applies the OutMode, OutOwner and OutGroup of a watch to
+// one of its output files. Invalid modes fall back to 0644, while unknown
+// owners or groups are left unchanged. Failures are logged.
+func setOutPermissions(out *output, w watch) {
+	if out.file == nil || out.file == os.Stdout {
+		return
+	}
+
+	// The next files of dated outputs get the same permissions.
+	if out.pattern != "" {
+		out.mutex.Lock()
+		defer out.mutex.Unlock()
+
+		out.opened = func(file *os.File) { setFilePermissions(file, w) }
+	}
+
+	setFilePermissions(out.file, w)
+}
+
+// setFilePermissions applies the OutMode, OutOwner and OutGroup of a watch to
+// an output file.
+func setFilePermissions(file *os.File, w watch) {
+	if w.OutMode != "" {
+		mode, err := strconv.ParseUint(w.OutMode, 8, 32)
+		if err != nil || mode > 0777 {
+			logger.Errorln("Invalid output mode " + w.OutMode + ", using 0644 instead")
+			mode = 0644
+		}
+
+		if err := file.Chmod(os.FileMode(mode)); err != nil {
+			logger.Errorln(err)
+		}
+	}
+
+	if w.OutOwner == "" && w.OutGroup == "" {
+		return
+	}
+
+	uid, gid := -1, -1
+
+	if w.OutOwner != "" {
+		if id, err := lookupID(w.OutOwner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		}); err == nil {
+			uid = id
+		} else {
+			logger.Errorln(err)
+		}
+	}
+
+	if w.OutGroup != "" {
+		if id, err := lookupID(w.OutGroup, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		}); err == nil {
+			gid = id
+		} else {
+			logger.Errorln(err)
+		}
+	}
+
+	if err := file.Chown(uid, gid); err != nil {
+		logger.Errorln(err)
+	}
+}
+
+// lookupID resolves a user or group given by name or numeric id.
+func lookupID(name string, lookup func(name string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+
+	id, err := lookup(name)
+	if err != nil {
+		return -1, err
+	}
+
+	return strconv.Atoi(id)
+}
+
+// closeOutputs closes every output file opened so far, completing their gzip
+// streams if any, once the lines held by the combined output are written.
+func closeOutputs() {
+	if combined != nil {
+		combined.close()
+		combined = nil
+	}
+
+	outputsMutex.Lock()
+	defer outputsMutex.Unlock()
+
+	for path, out := range outputs {
+		if err := out.Close(); err != nil {
+			logger.Errorln(err)
+		}
+		delete(outputs, path)
+	}
 }
 
 // getHandler builds the handler function to be used while following a trail.
-func getHandler(c *cli.Context, outLog *os.File, lineReg *regexp.Regexp, ignoreReg *regexp.Regexp, w watch) eye.LineHandler {
+func getHandler(c *cli.Context, routes []route, ignoreReg *regexp.Regexp, w watch) eye.LineHandler {
+	terminator := lineTerminator(w.LineTerminator)
+	recentLines := recentFor(w)
+	counts := report.countsFor(w)
+	filter := newSinceFilter(since, w)
+	levels := newLevelFilter(w)
+	correlations := newCorrelator(w)
+	samples := newSampler(w)
+	redactor := newRedactor(w)
+	fields := newFieldFormatter(w)
+	groups := newGroupExtractor(w)
+	docker := newDockerDecoder(w)
+	syslogLevels := newSeverityMapper(w)
+	spaces := newSpaceCleaner(w)
+	lineTimes := newTimestampParser(w.TimePattern, w.TimeLayout)
+	combine := combined
+	var timestamps *timestampParser
+	if combine != nil {
+		timestamps = newTimestampParser(w.TimestampPattern, w.TimestampLayout)
+	}
+
+	var beat *heartbeat
+	if w.HeartbeatInterval.Duration > 0 && !c.Bool("once") {
+		beat = startHeartbeat(w.HeartbeatInterval.Duration, func(now time.Time) {
+			writeHeartbeat(c, routes, w, now)
+		})
+	}
+
+	// With --count, the lines matched are counted rather than written.
+	var matches *matchCounter
+	if c.Bool("count") {
+		name := w.ID
+		if name == "" {
+			name = "watch"
+		}
+		matches = startMatchCounter(name, routes, countInterval, func(r route, text string, now time.Time) {
+			writeNote(c, []route{r}, w, text, now)
+		})
+	}
+
+	var runner *execRunner
+	if w.Exec != "" {
+		var err error
+		if runner, err = newExecRunner(w); err != nil {
+			logger.Errorln(err)
+		}
+	}
+
 	return func(line eye.Line) error {
-		if ignoreReg != nil && ignoreReg.MatchString(line.Text) {
+		// Start events are Sauron's own, written whatever the filters.
+		if line.Start {
+			writeNote(c, routes, w, line.Text, line.Time)
+			return nil
+		}
+
+		if docker != nil {
+			var complete bool
+			if line, complete = docker.decode(line); !complete {
+				return nil
+			}
+		}
+
+		atomic.AddUint64(&counts.read, 1)
+
+		if spaces != nil && spaces.beforeMatch {
+			line.Text = spaces.clean(line.Text)
+		}
+
+		// Lines are timed by their own timestamp when found, by when they
+		// were read otherwise.
+		if lineTimes != nil {
+			if t, ok := lineTimes.timestamp(line.Text); ok {
+				line.Time = t
+			}
+		}
+
+		// Every line counts in the correlation window, even those
+		// filtered out afterwards.
+		if correlations != nil && !correlations.keep(line) {
 			return nil
 		}
 
-		output := ""
+		if filter != nil && !filter.keep(line.Text) {
+			return nil
+		}
 
-		if c.BoolT("prefix-path") {
-			output += "[" + line.Path + "] "
+		if levels != nil && !levels.keep(line.Text) {
+			return nil
 		}
 
-		if c.Bool("prefix-time") {
-			output += "[" + line.Time.Format("Jan 2, 2006 at 3:04pm (MST)") + "] "
+		// Lines are matched as read, but written as their fields and
+		// redacted.
+		text := line.Text
+		var extracted map[string]string
+		if groups != nil {
+			extracted = groups.extract(text)
+		}
+		if fields != nil {
+			line.Text = fields.format(line.Text)
+		}
+		if redactor != nil {
+			line.Text = redactor.redact(line.Text)
+			for name, value := range extracted {
+				extracted[name] = redactor.redact(value)
+			}
+		}
+		if spaces != nil && !spaces.beforeMatch {
+			line.Text = spaces.clean(line.Text)
 		}
 
-		if w.Desc != "" {
-			output += "[" + w.Desc + "] "
+		var matched []route
+		for _, r := range routes {
+			if matchLine(text, r.lineReg, ignoreReg, w) {
+				matched = append(matched, r)
+			}
 		}
 
-		if lineReg != nil {
-			if lineReg.MatchString(line.Text) {
-				write(output, line, outLog)
+		if len(matched) > 0 && samples != nil {
+			if !samples.keep() {
+				return nil
 			}
+
+			if note := samples.note(time.Now()); note != "" {
+				writeNote(c, matched, w, note, time.Now())
+			}
+		}
+
+		if len(matched) == 0 {
+			return nil
+		}
+
+		if matches != nil {
+			matches.add(matched)
+			atomic.AddUint64(&counts.matched, 1)
+			return nil
+		}
+
+		// Lines are formatted into a pooled buffer, along with their
+		// terminator, which every output writes as is.
+		buf := getBuffer()
+		defer putBuffer(buf)
+
+		formatLineTo(buf, c, line, w, extracted)
+		size := buf.Len()
+		buf.WriteString(terminator)
+
+		if combine != nil {
+			var timestamp time.Time
+			if timestamps != nil {
+				timestamp, _ = timestamps.timestamp(text)
+			}
+			combine.add(timestamp, buf)
 		} else {
-			write(output, line, outLog)
+			key := kafkaKey(w, line)
+			severity := syslogInfo
+			if syslogLevels != nil {
+				severity = syslogLevels.severity(text)
+			}
+			for _, r := range matched {
+				if _, ok := r.out.sink.(*syslogSink); ok {
+					writeSeverity(buf, severity, r.out)
+				} else {
+					writeKeyed(buf, key, r.out)
+				}
+			}
+		}
+
+		atomic.AddUint64(&counts.matched, 1)
+
+		if beat != nil {
+			beat.touch()
+		}
+
+		if runner != nil {
+			runner.submit(line)
+		}
+
+		if recentLines != nil {
+			recentLines.add(string(buf.Bytes()[:size]))
 		}
 
 		return nil
 	}
 }
 
-func write(output string, line eye.Line, outLog *os.File) {
-	output += line.Text
+// writeHeartbeat writes a heartbeat line to every output of a watch, formatted
+// like its other lines.
+func writeHeartbeat(c *cli.Context, routes []route, w watch, now time.Time) {
+	text := w.HeartbeatText
+	if text == "" {
+		text = defaultHeartbeatText
+	}
+
+	writeNote(c, routes, w, text, now)
+}
+
+// writeNote writes a line of Sauron's own, such as a heartbeat, once to every
+// output of the routes, formatted like the other lines of the watch.
+func writeNote(c *cli.Context, routes []route, w watch, text string, now time.Time) {
+	buf := getBuffer()
+	defer putBuffer(buf)
 
-	if _, err := outLog.WriteString(output + "\n"); err != nil {
+	formatLineTo(buf, c, eye.Line{Text: text, Time: now, Offset: -1}, w, nil)
+	buf.WriteString(lineTerminator(w.LineTerminator))
+
+	written := make(map[*output]bool)
+	for _, r := range routes {
+		if !written[r.out] {
+			write(buf, r.out)
+			written[r.out] = true
+		}
+	}
+}
+
+// formatLine formats a line as written to the outputs of its watch: as a JSON
+// object when the watch's Format is json, or as its text after its prefixes
+// otherwise.
+func formatLine(c *cli.Context, line eye.Line, w watch) string {
+	return formatLineWithFields(c, line, w, nil)
+}
+
+// formatLineWithFields formats a line like formatLine, adding fields to its
+// JSON object when there are some.
+func formatLineWithFields(c *cli.Context, line eye.Line, w watch, fields map[string]string) string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	formatLineTo(buf, c, line, w, fields)
+
+	return buf.String()
+}
+
+// formatLineTo appends a line to buf, formatted like formatLineWithFields.
+func formatLineTo(buf *bytes.Buffer, c *cli.Context, line eye.Line, w watch, fields map[string]string) {
+	if w.Format != "json" {
+		writeLinePrefix(buf, c, line, w)
+		buf.WriteString(line.Text)
+		return
+	}
+
+	start := buf.Len()
+	err := json.NewEncoder(buf).Encode(jsonLine{
+		Host:   host,
+		ID:     w.ID,
+		Path:   line.Path,
+		Time:   line.Time,
+		Desc:   w.Desc,
+		Offset: line.Offset,
+		Inode:  line.Inode,
+		Dev:    line.Dev,
+		Stream: line.Stream,
+		Text:   line.Text,
+		Fields: fields,
+	})
+	if err != nil {
 		logger.Errorln(err)
+		buf.Truncate(start)
+		buf.WriteString(line.Text)
+		return
 	}
+
+	// The encoder ends the object with a newline, the terminator of the
+	// watch being written instead.
+	buf.Truncate(buf.Len() - 1)
 }
 
-func writePidFile(c *cli.Context) error {
+// jsonLine is a line written in the json format.
+type jsonLine struct {
+	Host   string            `json:"host,omitempty"`
+	ID     string            `json:"id,omitempty"`
+	Path   string            `json:"path"`
+	Time   time.Time         `json:"time"`
+	Desc   string            `json:"desc,omitempty"`
+	Offset int64             `json:"offset"`
+	Inode  uint64            `json:"inode,omitempty"`
+	Dev    uint64            `json:"dev,omitempty"`
+	Stream string            `json:"stream,omitempty"`
+	Text   string            `json:"text"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// host is the name of the machine prefixed to every line, when enabled with
+// PrefixHost. It is resolved once at startup.
+var host string
+
+// resolveHostname returns the name of the machine, or "unknown" when it can't
+// be told.
+func resolveHostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		logger.Errorln(err)
+		return "unknown"
+	}
+
+	return name
+}
+
+// writeLinePrefix appends the prefixes written before a line to buf: the
+// host, the ID of its watch, its path, its time and the description of its
+// watch, as enabled.
+func writeLinePrefix(buf *bytes.Buffer, c *cli.Context, line eye.Line, w watch) {
+	prefix := func(value string) {
+		buf.WriteByte('[')
+		buf.WriteString(value)
+		buf.WriteString("] ")
+	}
+
+	if host != "" {
+		prefix(host)
+	}
+
+	if c.Bool("prefix-id") && w.ID != "" {
+		prefix(w.ID)
+	}
+
+	if c.BoolT("prefix-path") {
+		prefix(line.Path)
+	}
+
+	if c.Bool("prefix-time") {
+		prefix(line.Time.Format("Jan 2, 2006 at 3:04pm (MST)"))
+	}
+
+	if w.Desc != "" {
+		prefix(w.Desc)
+	}
+}
+
+// matchLine decides whether a line should be written. Every configured
+// condition must hold: the line pattern and at least one of the LineContains
+// substrings must be present, while neither the ignore pattern nor any of the
+// LineNotContains substrings may be.
+func matchLine(text string, lineReg *regexp.Regexp, ignoreReg *regexp.Regexp, w watch) bool {
+	if ignoreReg != nil && ignoreReg.MatchString(text) {
+		return false
+	}
+
+	if lineReg != nil && !lineReg.MatchString(text) {
+		return false
+	}
+
+	if len(w.LineContains) > 0 && !containsAny(text, w.LineContains) {
+		return false
+	}
+
+	return !containsAny(text, w.LineNotContains)
+}
+
+// containsAny reports whether text contains any of the given substrings.
+func containsAny(text string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(text, substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lineTerminator returns the terminator written after every output line for
+// one of the names lf, crlf or null. It defaults to lf.
+func lineTerminator(name string) string {
+	switch name {
+	case "", "lf":
+		return "\n"
+	case "crlf":
+		return "\r\n"
+	case "null":
+		return "\x00"
+	default:
+		logger.Errorln("Unknown line terminator " + name + ", using lf instead")
+		return "\n"
+	}
+}
+
+// write writes a record formatted in buf, its terminator included, to an
+// output, logging failures.
+func write(buf *bytes.Buffer, out *output) {
+	if _, err := out.Write(buf.Bytes()); err != nil {
+		logger.Errorln(err)
+	}
+}
+
+// pidFilePath returns the path of the pid file, next to the executable.
+func pidFilePath() string {
 	var pidFile string
 	if dir, err := filepath.Abs(filepath.Dir(os.Args[0])); err == nil {
 		pidFile = filepath.Join(dir, "sauron.pid")
 	}
 
+	return pidFile
+}
+
+// defaultConfigPath returns the path of the config loaded when none is given,
+// sauron.conf next to the executable.
+func defaultConfigPath() string {
+	var conf string
+	if dir, err := filepath.Abs(filepath.Dir(os.Args[0])); err == nil {
+		conf = filepath.Join(dir, "sauron.conf")
+	}
+
+	return conf
+}
+
+// configRecordPath returns the path of the file recording the config sources
+// of the running Sauron, next to its pid file.
+func configRecordPath() string {
+	var record string
+	if dir, err := filepath.Abs(filepath.Dir(os.Args[0])); err == nil {
+		record = filepath.Join(dir, "sauron.pid.config")
+	}
+
+	return record
+}
+
+func writePidFile(c *cli.Context) error {
+	pidFile := pidFilePath()
+
 	// Read in the pid file as a slice of bytes.
 	if piddata, err := ioutil.ReadFile(pidFile); err == nil {
 		// Convert the file contents to an integer.
 		if pid, err := strconv.Atoi(string(piddata)); err == nil {
 			// Look for the pid in the process list.
-			if process, err := os.FindProcess(pid); err == nil {
-				// Send the process a signal zero kill.
-				if err := process.Signal(syscall.Signal(0)); err == nil {
-					// We only get an error if the pid isn't running, or it's not ours.
-					return fmt.Errorf("pid already running: %d", pid)
-				}
+			if processRunning(pid) {
+				return fmt.Errorf("pid already running: %d", pid)
 			}
 		}
 	}
 
 	// If we get here, then the pidfile didn't exist,
 	// or the pid in it doesn't belong to the user running this app.
-	return ioutil.WriteFile(pidFile, []byte(fmt.Sprintf("%d", os.Getpid())), 0664)
+	if err := ioutil.WriteFile(pidFile, []byte(fmt.Sprintf("%d", os.Getpid())), 0664); err != nil {
+		return err
+	}
+
+	return writeConfigRecord(configRecordPath(), c)
+}
+
+// isShutdownSignal reports whether sig is one of the shutdownSignals.
+func isShutdownSignal(sig os.Signal) bool {
+	for _, shutdown := range shutdownSignals {
+		if sig == shutdown {
+			return true
+		}
+	}
+
+	return false
 }
 
 func task() {
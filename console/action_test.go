@@ -0,0 +1,792 @@
+package console
+
+import (
+	"../eye"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func TestMatchLineContains(t *testing.T) {
+	w := watch{LineContains: []string{"ERROR", "WARN"}}
+
+	assert.True(t, matchLine("ERROR disk full", nil, nil, w))
+	assert.True(t, matchLine("WARN disk almost full", nil, nil, w))
+	assert.False(t, matchLine("INFO all good", nil, nil, w))
+}
+
+func TestMatchLineNotContains(t *testing.T) {
+	w := watch{LineNotContains: []string{"healthcheck"}}
+
+	assert.True(t, matchLine("GET /users", nil, nil, w))
+	assert.False(t, matchLine("GET /healthcheck", nil, nil, w))
+}
+
+func TestMatchLineContainsAndNotContains(t *testing.T) {
+	w := watch{
+		LineContains:    []string{"ERROR"},
+		LineNotContains: []string{"expected"},
+	}
+
+	assert.True(t, matchLine("ERROR disk full", nil, nil, w))
+	assert.False(t, matchLine("ERROR expected failure", nil, nil, w))
+	assert.False(t, matchLine("INFO disk full", nil, nil, w))
+}
+
+func TestMatchLineEmptyLists(t *testing.T) {
+	w := watch{LineContains: []string{}, LineNotContains: []string{}}
+
+	assert.True(t, matchLine("anything", nil, nil, w))
+	assert.True(t, matchLine("", nil, nil, w))
+}
+
+func TestMatchLineWithPatterns(t *testing.T) {
+	lineReg := regexp.MustCompile("(?i)error")
+	ignoreReg := regexp.MustCompile("timeout")
+	w := watch{
+		LineContains:    []string{"db"},
+		LineNotContains: []string{"retrying"},
+	}
+
+	assert.True(t, matchLine("ERROR db down", lineReg, ignoreReg, w))
+	assert.False(t, matchLine("INFO db up", lineReg, ignoreReg, w))
+	assert.False(t, matchLine("ERROR cache down", lineReg, ignoreReg, w))
+	assert.False(t, matchLine("ERROR db timeout", lineReg, ignoreReg, w))
+	assert.False(t, matchLine("ERROR db down, retrying", lineReg, ignoreReg, w))
+}
+
+func TestNewWatcherFile(t *testing.T) {
+	options := &eye.TrailOptions{
+		FileReg: regexp.MustCompile(`\.txt$`),
+		PathReg: regexp.MustCompile("archive"),
+	}
+
+	watcher, fileOptions, err := newWatcher("../_resources/example.log", options, &eye.DirectoryWatcherOptions{})
+
+	assert.Nil(t, err)
+	assert.IsType(t, &eye.FileWatcher{}, watcher)
+	assert.Nil(t, fileOptions.FileReg)
+	assert.Nil(t, fileOptions.PathReg)
+	assert.NotNil(t, options.FileReg)
+}
+
+func TestNewWatcherDirectory(t *testing.T) {
+	options := &eye.TrailOptions{
+		FileReg: regexp.MustCompile(`\.log$`),
+	}
+
+	watcher, dirOptions, err := newWatcher("../_resources", options, &eye.DirectoryWatcherOptions{})
+
+	assert.Nil(t, err)
+	assert.IsType(t, &eye.DirectoryWatcher{}, watcher)
+	assert.Equal(t, options, dirOptions)
+}
+
+func TestNewWatcherMissing(t *testing.T) {
+	_, _, err := newWatcher("../_resources/missing", &eye.TrailOptions{}, &eye.DirectoryWatcherOptions{})
+
+	assert.NotNil(t, err)
+}
+
+// captureStdout returns everything written to the standard output by fn.
+func captureStdout(t *testing.T, fn func()) string {
+	reader, writer, err := os.Pipe()
+	assert.Nil(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	fn()
+	os.Stdout = stdout
+	writer.Close()
+
+	out, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+
+	return string(out)
+}
+
+func TestSetLoggerDoesNotPrintConfig(t *testing.T) {
+	defer func() { logger = logrus.New() }()
+
+	conf := Config{LogLevel: "debug", Watch: []watch{{Paths: []string{"/secret"}, Out: "-"}}}
+
+	out := captureStdout(t, func() {
+		setLogger(conf)
+	})
+
+	assert.Equal(t, "", out)
+}
+
+func TestPrintConfig(t *testing.T) {
+	conf := Config{Watch: []watch{{Paths: []string{"/var/log"}, Out: "-"}}}
+
+	out := captureStdout(t, func() {
+		printConfig(conf, os.Stdout)
+	})
+
+	assert.Contains(t, out, "config: ")
+	assert.Contains(t, out, "/var/log")
+}
+
+func TestNewRemoteTrailMissingKey(t *testing.T) {
+	_, err := newRemoteTrail(remote{
+		Host: "example.com",
+		User: "sauron",
+		Key:  "../_resources/missing_key",
+		Path: "/var/log/app.log",
+	}, nil)
+
+	assert.NotNil(t, err)
+}
+
+// newTestContext creates a CLI context with the output prefixes disabled.
+func newTestContext() *cli.Context {
+	set := flag.NewFlagSet("test", 0)
+	set.Bool("prefix-path", false, "")
+
+	return cli.NewContext(nil, set, nil)
+}
+
+func TestOpenOutShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	first, err := openOut(filepath.Join(dir, "out.log"))
+	assert.Nil(t, err)
+
+	second, err := openOut(filepath.Join(dir, ".", "out.log"))
+	assert.Nil(t, err)
+
+	assert.True(t, first == second)
+}
+
+func TestGetHandlerRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	w := watch{
+		LineIgnorePattern: "healthcheck",
+		Rules: []rule{
+			{LinePattern: "ERROR", Out: filepath.Join(dir, "errors.log")},
+			{LinePattern: "WARN", Out: filepath.Join(dir, "warnings.log")},
+		},
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(routes))
+
+	handler := getHandler(newTestContext(), routes, compilePattern(w.LineIgnorePattern), w)
+	for _, text := range []string{"ERROR one", "WARN two", "INFO three", "ERROR healthcheck", "ERROR four"} {
+		assert.Nil(t, handler(eye.Line{Text: text}))
+	}
+
+	errors, err := ioutil.ReadFile(filepath.Join(dir, "errors.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ERROR one\nERROR four\n", string(errors))
+
+	warnings, err := ioutil.ReadFile(filepath.Join(dir, "warnings.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "WARN two\n", string(warnings))
+}
+
+func TestGetHandlerStartEvent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	w := watch{
+		Rules: []rule{
+			{LinePattern: "ERROR", Out: filepath.Join(dir, "errors.log")},
+			{LinePattern: "WARN", Out: filepath.Join(dir, "errors.log")},
+		},
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	// Start events are written once to every output, whatever they match.
+	handler := getHandler(newTestContext(), routes, nil, w)
+	assert.Nil(t, handler(eye.Line{Text: "[app] sauron started watching 2 files", Start: true}))
+	assert.Nil(t, handler(eye.Line{Text: "ERROR one"}))
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "errors.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "[app] sauron started watching 2 files\nERROR one\n", string(out))
+}
+
+func TestLineTerminator(t *testing.T) {
+	assert.Equal(t, "\n", lineTerminator(""))
+	assert.Equal(t, "\n", lineTerminator("lf"))
+	assert.Equal(t, "\r\n", lineTerminator("crlf"))
+	assert.Equal(t, "\x00", lineTerminator("null"))
+	assert.Equal(t, "\n", lineTerminator("tab"))
+}
+
+func TestGetHandlerLineTerminator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	for _, name := range []string{"crlf", "null"} {
+		w := watch{Out: filepath.Join(dir, name+".log"), LineTerminator: name}
+
+		routes, err := openRoutes(w)
+		assert.Nil(t, err)
+
+		handler := getHandler(newTestContext(), routes, nil, w)
+		assert.Nil(t, handler(eye.Line{Text: "one"}))
+		assert.Nil(t, handler(eye.Line{Text: "two"}))
+	}
+
+	crlf, err := ioutil.ReadFile(filepath.Join(dir, "crlf.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "one\r\ntwo\r\n", string(crlf))
+
+	null, err := ioutil.ReadFile(filepath.Join(dir, "null.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "one\x00two\x00", string(null))
+}
+
+func TestOpenOutCreatesParents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	_, err = openOut(filepath.Join(dir, "a", "b", "out.log"))
+	assert.Nil(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "a", "b", "out.log"))
+	assert.Nil(t, err)
+}
+
+func TestOpenOutRetrying(t *testing.T) {
+	outRetryDelay = 10 * time.Millisecond
+	defer func() { outRetryDelay = time.Second }()
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	// A file stands where the parent directory should be until it goes away.
+	blocked := filepath.Join(dir, "mnt")
+	assert.Nil(t, ioutil.WriteFile(blocked, []byte{}, 0644))
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		os.Remove(blocked)
+	}()
+
+	_, err = openOutRetrying(filepath.Join(blocked, "out.log"), 5)
+	assert.Nil(t, err)
+
+	_, err = os.Stat(filepath.Join(blocked, "out.log"))
+	assert.Nil(t, err)
+}
+
+func TestOpenOutRetryingGivesUp(t *testing.T) {
+	outRetryDelay = time.Millisecond
+	defer func() { outRetryDelay = time.Second }()
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	blocked := filepath.Join(dir, "mnt")
+	assert.Nil(t, ioutil.WriteFile(blocked, []byte{}, 0644))
+
+	_, err = openOutRetrying(filepath.Join(blocked, "out.log"), 2)
+	assert.NotNil(t, err)
+}
+
+func TestResolveHostname(t *testing.T) {
+	name, err := os.Hostname()
+	assert.Nil(t, err)
+
+	assert.Equal(t, name, resolveHostname())
+}
+
+func TestFormatLineHost(t *testing.T) {
+	host = resolveHostname()
+	defer func() { host = "" }()
+
+	line := eye.Line{Path: "/var/log/app.log", Text: "ERROR disk full", Time: time.Unix(0, 0).UTC(), Offset: 12}
+
+	assert.Equal(t, "["+host+"] [app] ERROR disk full", formatLine(newTestContext(), line, watch{Desc: "app"}))
+
+	var record map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(formatLine(newTestContext(), line, watch{Desc: "app", Format: "json"})), &record))
+	assert.Equal(t, host, record["host"])
+	assert.Equal(t, "/var/log/app.log", record["path"])
+	assert.Equal(t, "app", record["desc"])
+	assert.Equal(t, float64(12), record["offset"])
+	assert.Equal(t, "ERROR disk full", record["text"])
+}
+
+func TestFormatLineID(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	set.Bool("prefix-path", false, "")
+	set.Bool("prefix-id", true, "")
+	c := cli.NewContext(nil, set, nil)
+
+	line := eye.Line{Path: "/var/log/app.log", Text: "ERROR disk full"}
+	w := watch{ID: "watch-2", Desc: "app"}
+
+	assert.Equal(t, "[watch-2] [app] ERROR disk full", formatLine(c, line, w))
+	assert.Equal(t, "[app] ERROR disk full", formatLine(newTestContext(), line, w))
+
+	w.Format = "json"
+	for _, context := range []*cli.Context{c, newTestContext()} {
+		var record map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(formatLine(context, line, w)), &record))
+		assert.Equal(t, "watch-2", record["id"])
+		assert.Equal(t, "app", record["desc"])
+	}
+}
+
+func TestFormatLineFileID(t *testing.T) {
+	line := eye.Line{Path: "/var/log/app.log", Text: "ERROR disk full", Inode: 1234, Dev: 56}
+
+	var record map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(formatLine(newTestContext(), line, watch{Format: "json"})), &record))
+	assert.Equal(t, float64(1234), record["inode"])
+	assert.Equal(t, float64(56), record["dev"])
+
+	// Unknown file identities are left out.
+	line.Inode, line.Dev = 0, 0
+	assert.NotContains(t, formatLine(newTestContext(), line, watch{Format: "json"}), "inode")
+}
+
+func TestFormatLineWithoutHost(t *testing.T) {
+	line := eye.Line{Text: "ERROR disk full"}
+
+	assert.Equal(t, "ERROR disk full", formatLine(newTestContext(), line, watch{}))
+	assert.NotContains(t, formatLine(newTestContext(), line, watch{Format: "json"}), "host")
+}
+
+func TestAnyPattern(t *testing.T) {
+	assert.Equal(t, "", anyPattern("", nil))
+	assert.Equal(t, "ERROR", anyPattern("ERROR", nil))
+	assert.Equal(t, "WARN", anyPattern("", []string{"", "WARN"}))
+
+	lineReg := regexp.MustCompile(anyPattern("", []string{"^ERROR", "(?i)panic", `timeout after \d+s$`}))
+
+	assert.True(t, lineReg.MatchString("ERROR disk full"))
+	assert.True(t, lineReg.MatchString("goroutine PANIC"))
+	assert.True(t, lineReg.MatchString("db timeout after 30s"))
+	assert.False(t, lineReg.MatchString("INFO error recovered"))
+	assert.False(t, lineReg.MatchString("timeout after 30s, retrying"))
+
+	// Flags don't leak from one pattern to the next.
+	assert.False(t, regexp.MustCompile(anyPattern("(?i)panic", []string{"ERROR"})).MatchString("error"))
+}
+
+func TestGetHandlerLinePatterns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	w := watch{
+		LinePattern:        "ERROR",
+		LinePatterns:       []string{"WARN", "FATAL"},
+		LineIgnorePatterns: []string{"healthcheck", "expected"},
+		Out:                filepath.Join(dir, "out.log"),
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, compilePattern(anyPattern(w.LineIgnorePattern, w.LineIgnorePatterns)), w)
+	for _, text := range []string{"ERROR one", "WARN two", "FATAL three", "INFO four", "ERROR healthcheck", "WARN expected"} {
+		assert.Nil(t, handler(eye.Line{Text: text}))
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "out.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ERROR one\nWARN two\nFATAL three\n", string(out))
+}
+
+func TestWatchLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	global := filepath.Join(dir, "sauron.log")
+	own := filepath.Join(dir, "watch.log")
+
+	defer func() {
+		logger = logrus.New()
+		watchLoggers = map[string]*logrus.Logger{}
+	}()
+	setLogger(Config{Log: global, LogLevel: "debug"})
+
+	assert.Equal(t, logger, watchLogger(watch{}))
+
+	w := watch{Paths: []string{filepath.Join(dir, "app.txt")}, Log: own, Desc: "app"}
+	assert.Nil(t, ioutil.WriteFile(w.Paths[0], []byte("line\n"), 0644))
+
+	watcher, options, err := newWatcher(w.Paths[0], &eye.TrailOptions{
+		Logger:             watchLogger(w),
+		FileIgnoreDuration: time.Hour,
+	}, &eye.DirectoryWatcherOptions{})
+	assert.Nil(t, err)
+
+	trail := eye.NewTrailWithOptions(watcher, options)
+	assert.Nil(t, trail.Once(func(eye.Line) error { return nil }))
+
+	ownLog, err := ioutil.ReadFile(own)
+	assert.Nil(t, err)
+	assert.Contains(t, string(ownLog), "Reading: "+w.Paths[0])
+
+	globalLog, err := ioutil.ReadFile(global)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(globalLog), "Reading: ")
+
+	assert.Equal(t, watchLogger(w), watchLogger(watch{Log: own}))
+}
+
+func TestTailRateLimit(t *testing.T) {
+	assert.Equal(t, uint16(0), tailRateLimit(-1))
+	assert.Equal(t, uint16(0), tailRateLimit(0))
+	assert.Equal(t, uint16(500), tailRateLimit(500))
+	assert.Equal(t, uint16(65535), tailRateLimit(100000))
+}
+
+func TestFileDuration(t *testing.T) {
+	week := 7 * 24 * time.Hour
+
+	// Neither the watch nor the config sets one.
+	assert.Equal(t, week, fileDuration(duration{}, duration{}))
+
+	// Watches inherit the default of the config...
+	assert.Equal(t, 2*time.Hour, fileDuration(duration{}, duration{2 * time.Hour}))
+
+	// ...unless they set their own.
+	assert.Equal(t, time.Hour, fileDuration(duration{time.Hour}, duration{2 * time.Hour}))
+	assert.Equal(t, time.Hour, fileDuration(duration{time.Hour}, duration{}))
+}
+
+func TestLoadConfigDefaultFileDurations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeConfigFile(t, filepath.Join(dir, "sauron.toml"), `
+defaultFileIgnoreDuration = "48h"
+defaultFileFollowDuration = "12h"
+
+[[watch]]
+paths = ["/var/log/a"]
+
+[[watch]]
+paths = ["/var/log/b"]
+fileIgnoreDuration = "1h"
+fileFollowDuration = "30m"
+`)
+
+	var conf Config
+	assert.Nil(t, loadConfig(filepath.Join(dir, "sauron.toml"), &conf, nil))
+
+	inheriting, overriding := conf.Watch[0], conf.Watch[1]
+	assert.Equal(t, 48*time.Hour, fileDuration(inheriting.FileIgnoreDuration, conf.DefaultFileIgnoreDuration))
+	assert.Equal(t, 12*time.Hour, fileDuration(inheriting.FileFollowDuration, conf.DefaultFileFollowDuration))
+	assert.Equal(t, time.Hour, fileDuration(overriding.FileIgnoreDuration, conf.DefaultFileIgnoreDuration))
+	assert.Equal(t, 30*time.Minute, fileDuration(overriding.FileFollowDuration, conf.DefaultFileFollowDuration))
+}
+
+func TestSetTrailOptionsResetsPatterns(t *testing.T) {
+	// The options are shared by the watches, which must not inherit the
+	// patterns of the previous ones.
+	options := &eye.TrailOptions{}
+
+	setTrailOptions(options, Config{}, watch{PathIgnorePattern: `/archive/`, FullPathPattern: `\.log$`, GzipStreamPattern: `^app\.log\.gz$`})
+	assert.NotNil(t, options.PathIgnoreReg)
+	assert.NotNil(t, options.FullPathReg)
+	assert.NotNil(t, options.GzipStreamReg)
+
+	// Without a GzipStreamPattern of its own, the second watch tails its gzip
+	// files rather than decoding them as streams.
+	setTrailOptions(options, Config{}, watch{})
+	assert.Nil(t, options.PathIgnoreReg)
+	assert.Nil(t, options.FullPathReg)
+	assert.Nil(t, options.GzipStreamReg)
+}
+
+func TestMainActionNoPidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer func() { logger = logrus.New() }()
+	defer signal.Reset()
+
+	logs := filepath.Join(dir, "logs")
+	assert.Nil(t, os.MkdirAll(logs, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(logs, "app.log"), []byte("ERROR boom\n"), 0644))
+
+	out := filepath.Join(dir, "out.log")
+	conf := filepath.Join(dir, "sauron.toml")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`
+[[Watch]]
+Paths = ["`+filepath.ToSlash(logs)+`"]
+FileIgnoreDuration = "1h"
+Out = "`+filepath.ToSlash(out)+`"
+`), 0644))
+
+	set := flag.NewFlagSet("test", 0)
+	set.Bool("once", true, "")
+	set.Bool("no-pid-file", true, "")
+	set.String("conf", conf, "")
+
+	os.Remove(pidFilePath())
+	MainAction(cli.NewContext(nil, set, nil))
+
+	_, err = os.Stat(pidFilePath())
+	assert.True(t, os.IsNotExist(err))
+
+	written, err := ioutil.ReadFile(out)
+	assert.Nil(t, err)
+	assert.Equal(t, "ERROR boom\n", string(written))
+}
+
+func TestMainActionOnceSkipsFailedWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer func() { logger = logrus.New() }()
+	defer signal.Reset()
+
+	logs := filepath.Join(dir, "logs")
+	assert.Nil(t, os.MkdirAll(logs, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(logs, "app.log"), []byte("ERROR boom\n"), 0644))
+
+	// Compressed lines are only flushed once the outputs are closed.
+	out := filepath.Join(dir, "out.log.gz")
+	conf := filepath.Join(dir, "sauron.toml")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`
+[[Watch]]
+Paths = ["`+filepath.ToSlash(logs)+`"]
+FileIgnoreDuration = "1h"
+Out = "kafka://broker:9092"
+
+[[Watch]]
+Paths = ["`+filepath.ToSlash(filepath.Join(dir, "missing"))+`", "`+filepath.ToSlash(logs)+`"]
+FileIgnoreDuration = "1h"
+Out = "`+filepath.ToSlash(out)+`"
+`), 0644))
+
+	set := flag.NewFlagSet("test", 0)
+	set.Bool("once", true, "")
+	set.Bool("no-pid-file", true, "")
+	set.String("conf", conf, "")
+
+	MainAction(cli.NewContext(nil, set, nil))
+
+	file, err := os.Open(out)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	assert.Nil(t, err)
+	written, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "ERROR boom\n", string(written))
+}
+
+func TestMainActionQuiet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer func() { logger, quiet = logrus.New(), false }()
+	defer signal.Reset()
+
+	logs := filepath.Join(dir, "logs")
+	assert.Nil(t, os.MkdirAll(logs, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(logs, "app.log"), []byte("ERROR boom\nINFO fine\n"), 0644))
+
+	out := filepath.Join(dir, "out.log")
+	log := filepath.Join(dir, "sauron.log")
+	conf := filepath.Join(dir, "sauron.toml")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`
+Log = "`+filepath.ToSlash(log)+`"
+LogLevel = "debug"
+
+[[Watch]]
+Paths = ["`+filepath.ToSlash(logs)+`"]
+FileIgnoreDuration = "1h"
+LinePattern = "ERROR"
+Out = "`+filepath.ToSlash(out)+`"
+`), 0644))
+
+	// Whatever is printed rather than written to Out is operational output.
+	printed := filepath.Join(dir, "printed")
+	console, err := os.Create(printed)
+	assert.Nil(t, err)
+	defer console.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = console, console
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	set := flag.NewFlagSet("test", 0)
+	set.Bool("once", true, "")
+	set.Bool("quiet", true, "")
+	set.Bool("print-config", true, "")
+	set.Bool("no-pid-file", true, "")
+	set.String("conf", conf, "")
+
+	MainAction(cli.NewContext(nil, set, nil))
+	os.Stdout, os.Stderr = stdout, stderr
+
+	written, err := ioutil.ReadFile(out)
+	assert.Nil(t, err)
+	assert.Equal(t, "ERROR boom\n", string(written))
+
+	output, err := ioutil.ReadFile(printed)
+	assert.Nil(t, err)
+	assert.Empty(t, string(output))
+
+	_, err = os.Stat(log)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMainActionDuration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer func() { logger = logrus.New() }()
+	defer signal.Reset()
+	defer func(interval time.Duration) { syncInterval = interval }(syncInterval)
+
+	logs := filepath.Join(dir, "logs")
+	assert.Nil(t, os.MkdirAll(logs, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(logs, "app.log"), []byte("ERROR boom\n"), 0644))
+
+	// Compressed lines are only flushed on shutdown.
+	out := filepath.Join(dir, "out.log.gz")
+	conf := filepath.Join(dir, "sauron.toml")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`
+OutSyncInterval = "-1s"
+
+[[Watch]]
+Paths = ["`+filepath.ToSlash(logs)+`"]
+FileIgnoreDuration = "1h"
+SeekStart = "start"
+Out = "`+filepath.ToSlash(out)+`"
+`), 0644))
+
+	set := flag.NewFlagSet("test", 0)
+	set.Bool("no-pid-file", true, "")
+	set.Duration("duration", 300*time.Millisecond, "")
+	set.String("conf", conf, "")
+
+	start := time.Now()
+	finished := make(chan bool)
+	go func() {
+		MainAction(cli.NewContext(nil, set, nil))
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(10 * time.Second):
+		t.Fatal("still running after its duration")
+	}
+	assert.True(t, time.Since(start) >= 300*time.Millisecond)
+
+	file, err := os.Open(out)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	assert.Nil(t, err)
+	written, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "ERROR boom\n", string(written))
+}
+
+func BenchmarkGetHandler(b *testing.B) {
+	dir, err := ioutil.TempDir("", "sauron")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	line := eye.Line{Path: "/var/log/app.log", Text: "2020-01-02 03:04:05 ERROR something failed", Time: time.Now()}
+
+	for _, format := range []string{"text", "json"} {
+		b.Run(format, func(b *testing.B) {
+			w := watch{
+				LinePattern: "ERROR",
+				Desc:        "app",
+				Format:      format,
+				Out:         filepath.Join(dir, format+".log"),
+			}
+
+			routes, err := openRoutes(w)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			handler := getHandler(newTestContext(), routes, nil, w)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				handler(line)
+			}
+		})
+	}
+}
+
+func TestStartLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// A watch of two paths writes one start line counting the files of both.
+	var trails []*eye.Trail
+	for path, files := range map[string][]string{
+		"api": {"access.log", "error.log"},
+		"db":  {"slow.log"},
+	} {
+		path = filepath.Join(dir, path)
+		assert.Nil(t, os.Mkdir(path, 0755))
+		for _, file := range files {
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(path, file), []byte("line\n"), 0644))
+		}
+
+		watcher, err := eye.NewDirectoryWatcher(path)
+		assert.Nil(t, err)
+
+		trail := eye.NewTrailWithOptions(watcher, &eye.TrailOptions{FileIgnoreDuration: time.Hour})
+		assert.Nil(t, trail.Follow(func(line eye.Line) error { return nil }))
+		defer trail.End()
+
+		trails = append(trails, trail)
+	}
+
+	line := startLine("app", trails)
+	assert.Equal(t, "[app] sauron started watching 3 files", line.Text)
+	assert.True(t, line.Start)
+}
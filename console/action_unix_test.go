@@ -0,0 +1,100 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenRoutesOutMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	for mode, expected := range map[string]os.FileMode{
+		"0640":    0640,
+		"600":     0600,
+		"abc":     0644,
+		"0777777": 0644,
+	} {
+		path := filepath.Join(dir, mode+".log")
+
+		_, err := openRoutes(watch{Out: path, OutMode: mode})
+		assert.Nil(t, err)
+
+		info, err := os.Stat(path)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, info.Mode().Perm(), mode)
+	}
+}
+
+func TestOpenRoutesOutOwner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	owner := func(path string) (int, int) {
+		info, err := os.Stat(path)
+		assert.Nil(t, err)
+		stat := info.Sys().(*syscall.Stat_t)
+
+		return int(stat.Uid), int(stat.Gid)
+	}
+
+	// Giving the file to its current owner is always allowed.
+	path := filepath.Join(dir, "owned.log")
+	_, err = openRoutes(watch{
+		Out:      path,
+		OutOwner: strconv.Itoa(os.Getuid()),
+		OutGroup: strconv.Itoa(os.Getgid()),
+	})
+	assert.Nil(t, err)
+
+	uid, gid := owner(path)
+	assert.Equal(t, os.Getuid(), uid)
+	assert.Equal(t, os.Getgid(), gid)
+
+	if os.Getuid() != 0 {
+		t.Skip("giving files to other users needs root")
+	}
+
+	path = filepath.Join(dir, "given.log")
+	_, err = openRoutes(watch{Out: path, OutOwner: "12345", OutGroup: "23456"})
+	assert.Nil(t, err)
+
+	uid, gid = owner(path)
+	assert.Equal(t, 12345, uid)
+	assert.Equal(t, 23456, gid)
+}
+
+func TestOpenRoutesOutModeDated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	routes, err := openRoutes(watch{Out: filepath.Join(dir, "out-%Y-%m-%d.log"), OutMode: "0600"})
+	assert.Nil(t, err)
+
+	out := routes[0].out
+	out.mutex.Lock()
+	out.now = func() time.Time { return time.Date(2030, 1, 2, 12, 0, 0, 0, time.Local) }
+	out.mutex.Unlock()
+
+	writeText("next file", out, "\n")
+
+	info, err := os.Stat(filepath.Join(dir, "out-2030-01-02.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
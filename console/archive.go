@@ -0,0 +1,190 @@
+package console
+
+import (
+	"compress/gzip"
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Settings of the archivers created afterwards. Failed uploads are retried
+// after archiveRetryDelay, twice as long after every failure.
+var (
+	archiveRetryDelay = time.Second
+	archiveTimeout    = time.Minute
+)
+
+// archiver uploads files to an S3 bucket with the AWS SDK, streaming them
+// from disk, in parts once they are large.
+type archiver struct {
+	bucket   string
+	prefix   string
+	retries  int
+	uploader *manager.Uploader
+}
+
+// newArchiver creates the archiver of an Archive block, reading the
+// credentials from the environment.
+func newArchiver(a archive) *archiver {
+	region := a.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	retries := a.Retries
+	if retries == 0 {
+		retries = 3
+	}
+
+	credentials := aws.Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+
+	// Uploads are retried by archive, and only checksummed when S3 requires
+	// it, as S3 compatible endpoints may not support the trailing checksums.
+	client := s3.New(s3.Options{
+		Region: region,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return credentials, nil
+		}),
+		HTTPClient:                 &http.Client{Timeout: archiveTimeout},
+		Retryer:                    aws.NopRetryer{},
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	}, func(o *s3.Options) {
+		if a.Endpoint != "" {
+			o.BaseEndpoint = aws.String(strings.TrimSuffix(a.Endpoint, "/"))
+			o.UsePathStyle = true
+		}
+	})
+
+	return &archiver{
+		bucket:   a.S3Bucket,
+		prefix:   a.Prefix,
+		retries:  retries,
+		uploader: manager.NewUploader(client),
+	}
+}
+
+// setOutArchive makes a dated output archive the files it rolls away from as
+// set by the Archive block of a watch.
+func setOutArchive(out *output, w watch) {
+	if w.Archive == nil {
+		return
+	}
+
+	if out.pattern == "" {
+		logger.Warnln("Only dated outputs are archived, not archiving " + w.Out)
+		return
+	}
+
+	a := newArchiver(*w.Archive)
+
+	out.mutex.Lock()
+	defer out.mutex.Unlock()
+
+	out.rolled = func(path string) { go a.archive(path) }
+}
+
+// archive compresses a file unless it already is, uploads it and deletes it.
+// The compressed file is kept when the upload keeps failing.
+func (a *archiver) archive(path string) {
+	if !strings.HasSuffix(path, ".gz") {
+		compressed, err := compressFile(path)
+		if err != nil {
+			logger.Errorln("Failed to compress " + path + ": " + err.Error())
+			return
+		}
+		path = compressed
+	}
+
+	delay := archiveRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := a.upload(path)
+		if err == nil {
+			break
+		}
+
+		if attempt >= a.retries {
+			logger.Errorln("Failed to archive " + path + ", keeping it: " + err.Error())
+			return
+		}
+
+		logger.Errorln("Failed to archive " + path + ": " + err.Error() + ". Retrying in " + delay.String())
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	logger.Infoln("Archived " + path)
+
+	if err := os.Remove(path); err != nil {
+		logger.Errorln(err)
+	}
+}
+
+// compressFile writes a file as a gzip stream next to it, with the .gz
+// extension, and deletes it. It returns the path of the compressed file.
+func compressFile(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	compressed := path + ".gz"
+	out, err := os.OpenFile(compressed, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	writer := gzip.NewWriter(out)
+	if _, err := io.Copy(writer, in); err != nil {
+		out.Close()
+		os.Remove(compressed)
+		return "", err
+	}
+
+	if err := writer.Close(); err != nil {
+		out.Close()
+		os.Remove(compressed)
+		return "", err
+	}
+
+	if err := out.Close(); err != nil {
+		os.Remove(compressed)
+		return "", err
+	}
+
+	return compressed, os.Remove(path)
+}
+
+// upload puts a file in the bucket, under the prefix followed by its name.
+func (a *archiver) upload(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = a.uploader.Upload(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(a.bucket),
+		Key:         aws.String(a.prefix + filepath.Base(path)),
+		Body:        f,
+		ContentType: aws.String("application/gzip"),
+	})
+
+	return err
+}
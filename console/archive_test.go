@@ -0,0 +1,266 @@
+package console
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCredentials are the credentials of the examples of the AWS Signature
+// Version 4 documentation.
+var testCredentials = aws.Credentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+// withTestCredentials sets testCredentials in the environment of the
+// archivers created until the returned function is called.
+func withTestCredentials() func() {
+	os.Setenv("AWS_ACCESS_KEY_ID", testCredentials.AccessKeyID)
+	os.Setenv("AWS_SECRET_ACCESS_KEY", testCredentials.SecretAccessKey)
+
+	return func() {
+		os.Unsetenv("AWS_ACCESS_KEY_ID")
+		os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+	}
+}
+
+// verifySigV4 checks that a request received was signed with testCredentials
+// for a region and a service, by signing its signed headers again.
+func verifySigV4(r *http.Request, region, service string) error {
+	authorization := r.Header.Get("Authorization")
+
+	i := strings.Index(authorization, "SignedHeaders=")
+	if i < 0 {
+		return errors.New("unsigned request")
+	}
+	signed := strings.SplitN(authorization[i+len("SignedHeaders="):], ",", 2)[0]
+
+	at, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		sum := sha256.Sum256(nil)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+
+	u := *r.URL
+	u.Scheme, u.Host = "http", r.Host
+	clone, err := http.NewRequest(r.Method, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	clone.ContentLength = r.ContentLength
+	for _, name := range strings.Split(signed, ";") {
+		if name != "host" && name != "content-length" {
+			clone.Header[http.CanonicalHeaderKey(name)] = r.Header.Values(name)
+		}
+	}
+
+	// S3 paths are escaped once, rather than twice as for other services.
+	signer := v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = service == "s3" })
+	if err := signer.SignHTTP(context.Background(), testCredentials, clone, payloadHash, service, region, at); err != nil {
+		return err
+	}
+
+	if expected := clone.Header.Get("Authorization"); authorization != expected {
+		return errors.New("signature mismatch, expected " + expected + ", got " + authorization)
+	}
+
+	return nil
+}
+
+// s3Upload is an object put to a mocked S3 endpoint.
+type s3Upload struct {
+	path          string
+	authorization string
+	content       string
+}
+
+// newMockS3 starts an S3 endpoint of a region answering every upload with
+// status, passing the uploads to the returned channel. Uploads must be signed
+// with testCredentials, and carry the hash of their content.
+func newMockS3(t *testing.T, region string, status int) (*httptest.Server, chan s3Upload) {
+	uploads := make(chan s3Upload, 8)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PUT", r.Method)
+		assert.Nil(t, verifySigV4(r, region, "s3"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+
+		if hash := r.Header.Get("X-Amz-Content-Sha256"); hash != "UNSIGNED-PAYLOAD" {
+			sum := sha256.Sum256(body)
+			assert.Equal(t, hex.EncodeToString(sum[:]), hash)
+		}
+
+		content := ""
+		if reader, err := gzip.NewReader(bytes.NewReader(body)); err == nil {
+			data, _ := ioutil.ReadAll(reader)
+			content = string(data)
+		}
+
+		uploads <- s3Upload{path: r.URL.Path, authorization: r.Header.Get("Authorization"), content: content}
+		w.WriteHeader(status)
+	}))
+
+	return server, uploads
+}
+
+func TestArchiveRolledFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	defer withTestCredentials()()
+
+	server, uploads := newMockS3(t, "eu-west-1", http.StatusOK)
+	defer server.Close()
+
+	w := watch{
+		Out:     filepath.Join(dir, "out-%Y-%m-%d-%H.log"),
+		Archive: &archive{S3Bucket: "logs", Prefix: "web/", Region: "eu-west-1", Endpoint: server.URL},
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+	out := routes[0].out
+
+	now := time.Now()
+	first := strftime("out-%Y-%m-%d-%H.log", now)
+	out.mutex.Lock()
+	out.now = func() time.Time { return now }
+	out.mutex.Unlock()
+
+	writeText("first hour", out, "\n")
+	now = now.Add(time.Hour)
+	writeText("second hour", out, "\n")
+
+	select {
+	case upload := <-uploads:
+		assert.Equal(t, "/logs/web/"+first+".gz", upload.path)
+		assert.Equal(t, "first hour\n", upload.content)
+		assert.True(t, strings.HasPrefix(upload.authorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		assert.Contains(t, upload.authorization, "/eu-west-1/s3/aws4_request")
+	case <-time.After(5 * time.Second):
+		t.Fatal("rolled file not uploaded")
+	}
+
+	// Archived files are deleted, the current one is left alone.
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if files, _ := filepath.Glob(filepath.Join(dir, first+"*")); len(files) == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, strftime("out-%Y-%m-%d-%H.log", now))}, files)
+}
+
+func TestArchiveKeepsFileOnFailure(t *testing.T) {
+	defer func(delay time.Duration) { archiveRetryDelay = delay }(archiveRetryDelay)
+	archiveRetryDelay = time.Millisecond
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	defer withTestCredentials()()
+
+	server, uploads := newMockS3(t, "us-east-1", http.StatusForbidden)
+	defer server.Close()
+
+	path := filepath.Join(dir, "out.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("line\n"), 0644))
+
+	newArchiver(archive{S3Bucket: "logs", Endpoint: server.URL, Retries: 2}).archive(path)
+	assert.Equal(t, 3, len(uploads))
+
+	_, err = os.Stat(path + ".gz")
+	assert.Nil(t, err)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestVerifySigV4(t *testing.T) {
+	// The get-vanilla example of the AWS Signature Version 4 test suite.
+	r := httptest.NewRequest("GET", "http://example.amazonaws.com/", nil)
+	r.Header.Set("X-Amz-Date", "20150830T123600Z")
+	r.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31")
+	assert.Nil(t, verifySigV4(r, "us-east-1", "service"))
+
+	assert.NotNil(t, verifySigV4(r, "eu-west-1", "service"))
+
+	r.Header.Set("X-Amz-Date", "20150830T123601Z")
+	assert.NotNil(t, verifySigV4(r, "us-east-1", "service"))
+}
+
+func TestArchiveLargeFileInParts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer withTestCredentials()()
+
+	var mutex sync.Mutex
+	parts := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, verifySigV4(r, "us-east-1", "s3"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		query := r.URL.Query()
+		switch {
+		case r.Method == "POST" && query.Has("uploads"):
+			fmt.Fprint(w, `<InitiateMultipartUploadResult><Bucket>logs</Bucket><Key>big.log.gz</Key><UploadId>1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == "PUT" && query.Get("partNumber") != "":
+			parts[query.Get("partNumber")] = len(body)
+			w.Header().Set("ETag", `"`+query.Get("partNumber")+`"`)
+		case r.Method == "POST" && query.Get("uploadId") != "":
+			fmt.Fprint(w, `<CompleteMultipartUploadResult><Bucket>logs</Bucket><Key>big.log.gz</Key></CompleteMultipartUploadResult>`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	// Files larger than a part are uploaded in parts, rather than at once.
+	path := filepath.Join(dir, "big.log.gz")
+	assert.Nil(t, ioutil.WriteFile(path, bytes.Repeat([]byte("x"), int(manager.MinUploadPartSize)+1), 0644))
+
+	assert.Nil(t, newArchiver(archive{S3Bucket: "logs", Endpoint: server.URL}).upload(path))
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, map[string]int{"1": int(manager.MinUploadPartSize), "2": 1}, parts)
+}
@@ -0,0 +1,116 @@
+package console
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	// breakerDrop discards the lines of an output while its breaker is open.
+	breakerDrop = "drop"
+
+	// breakerBuffer holds the lines of an output while its breaker is open,
+	// up to breakerBufferSize of them, and writes them once it recovers.
+	breakerBuffer = "buffer"
+)
+
+// Settings of the breakers of the outputs opened afterwards. They are set from
+// the config at startup.
+var (
+	breakerThreshold  = 5
+	breakerCooldown   = 30 * time.Second
+	breakerPolicy     = breakerDrop
+	breakerBufferSize = 1000
+)
+
+// breaker stops writing to an output after threshold consecutive failures,
+// for a cooldown window, so that a broken output doesn't flood the log with an
+// error per line. Once the window is over, the next line is attempted again.
+// A breaker isn't safe for concurrent use; its output guards it.
+type breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	policy    string
+	failures  int
+	open      bool
+	openUntil time.Time
+	held      []heldLine
+	dropped   int
+	now       func() time.Time
+}
+
+// heldLine is a line held by a breaker, along with the syslog severity it is
+// sent at, or noSeverity.
+type heldLine struct {
+	text     string
+	severity int
+}
+
+// newBreaker creates a breaker for the named output from the current
+// settings.
+func newBreaker(name string) *breaker {
+	return &breaker{
+		name:      name,
+		threshold: breakerThreshold,
+		cooldown:  breakerCooldown,
+		policy:    breakerPolicy,
+		now:       time.Now,
+	}
+}
+
+// allow reports whether the output may be written to.
+func (b *breaker) allow() bool {
+	return !b.open || !b.now().Before(b.openUntil)
+}
+
+// hold keeps or drops, as the policy says, lines which couldn't be written.
+func (b *breaker) hold(lines ...heldLine) {
+	if b.policy != breakerBuffer {
+		b.dropped += len(lines)
+		return
+	}
+
+	b.held = append(b.held, lines...)
+	if extra := len(b.held) - breakerBufferSize; extra > 0 {
+		b.held = b.held[extra:]
+		b.dropped += extra
+	}
+}
+
+// take returns the lines held so far, forgetting them.
+func (b *breaker) take() []heldLine {
+	held := b.held
+	b.held = nil
+
+	return held
+}
+
+// failure records a failed write, opening the breaker once the threshold is
+// reached or extending its window when an attempt after the cooldown failed.
+func (b *breaker) failure() {
+	b.failures++
+
+	if b.open {
+		b.openUntil = b.now().Add(b.cooldown)
+		return
+	}
+
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.open = true
+		b.openUntil = b.now().Add(b.cooldown)
+		logger.Errorln("Output " + b.name + " failed " + strconv.Itoa(b.failures) +
+			" times in a row, suspending it for " + b.cooldown.String())
+	}
+}
+
+// success records a successful write, closing the breaker.
+func (b *breaker) success() {
+	if b.open {
+		logger.Infoln("Output " + b.name + " recovered, " + strconv.Itoa(b.dropped) + " lines dropped")
+	}
+
+	b.open = false
+	b.failures = 0
+	b.dropped = 0
+}
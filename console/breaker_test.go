@@ -0,0 +1,94 @@
+package console
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreakerTripsAndRecovers(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := &breaker{name: "out.log", threshold: 3, cooldown: time.Minute, policy: breakerDrop, now: func() time.Time { return now }}
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, b.allow())
+		b.failure()
+	}
+	assert.True(t, b.allow())
+
+	b.failure()
+	assert.False(t, b.allow())
+
+	b.hold(heldLine{text: "dropped", severity: noSeverity})
+	assert.Equal(t, 1, b.dropped)
+	assert.Nil(t, b.take())
+
+	// A failed attempt after the cooldown suspends the output again.
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow())
+	b.failure()
+	assert.False(t, b.allow())
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow())
+	b.success()
+	assert.True(t, b.allow())
+	assert.Equal(t, 0, b.failures)
+	assert.Equal(t, 0, b.dropped)
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := &breaker{threshold: -1, cooldown: time.Minute, now: time.Now}
+
+	for i := 0; i < 10; i++ {
+		b.failure()
+	}
+
+	assert.True(t, b.allow())
+}
+
+func TestOutputBreakerBuffers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.log")
+	file, err := os.Create(path)
+	assert.Nil(t, err)
+
+	now := time.Unix(0, 0)
+	out := &output{file: file, breaker: &breaker{
+		name:      path,
+		threshold: 2,
+		cooldown:  time.Minute,
+		policy:    breakerBuffer,
+		now:       func() time.Time { return now },
+	}}
+
+	// Writing to a closed file fails until the breaker opens.
+	file.Close()
+
+	_, err = out.WriteString("one\n")
+	assert.NotNil(t, err)
+	_, err = out.WriteString("two\n")
+	assert.NotNil(t, err)
+	_, err = out.WriteString("three\n")
+	assert.Nil(t, err)
+
+	// The output comes back once the cooldown is over.
+	out.file, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	now = now.Add(time.Minute)
+
+	_, err = out.WriteString("four\n")
+	assert.Nil(t, err)
+	assert.Nil(t, out.Close())
+
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "one\ntwo\nthree\nfour\n", string(content))
+}
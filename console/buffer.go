@@ -0,0 +1,33 @@
+package console
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity beyond which buffers aren't pooled, so
+// that a few long lines don't keep large buffers alive.
+const maxPooledBufferSize = 64 << 10
+
+// bufferPool holds the buffers lines are formatted into before being written,
+// reused across lines so that handlers don't allocate for each of them.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	return buf
+}
+
+// putBuffer returns a buffer to the pool, once nothing refers to its content.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,180 @@
+package console
+
+import (
+	"fmt"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// healthCheck is a category of checks of the check-health command, returning
+// the problems it found. The command exits with code when it finds some.
+type healthCheck struct {
+	name  string
+	code  int
+	check func(conf Config) []string
+}
+
+// healthChecks are run in order, until one of them finds problems.
+var healthChecks = []healthCheck{
+	{"daemon", 2, checkDaemon},
+	{"outputs", 3, checkOutputs},
+	{"paths", 4, checkPaths},
+}
+
+// CheckHealthAction is the action of the check-health command, checking that
+// the running Sauron is alive, that the outputs of the config are writable and
+// that its paths are accessible. It reports on every category of checks up to
+// the first one failing, and exits with the code of that category.
+func CheckHealthAction(c *cli.Context) error {
+	conf, result := setConfig(c)
+	if !result {
+		return cli.NewExitError("failed to load the config", 1)
+	}
+
+	for _, check := range healthChecks {
+		problems := check.check(conf)
+		if len(problems) == 0 {
+			fmt.Fprintln(c.App.Writer, check.name+": ok")
+			continue
+		}
+
+		for _, problem := range problems {
+			fmt.Fprintln(c.App.Writer, check.name+": "+problem)
+		}
+
+		return cli.NewExitError(check.name+" check failed", check.code)
+	}
+
+	return nil
+}
+
+// checkDaemon checks that the process of the pid file runs, unless the config
+// says no pid file is written.
+func checkDaemon(conf Config) []string {
+	if conf.NoPidFile {
+		return nil
+	}
+
+	if _, err := signalDaemon(pidFilePath(), func(int) error { return nil }); err != nil {
+		return []string{err.Error()}
+	}
+
+	return nil
+}
+
+// checkOutputs checks that the output files of every watch and rule can be
+// written to, taking dated outputs at the current date. The standard output
+// and network outputs aren't checked.
+func checkOutputs(conf Config) []string {
+	var problems []string
+
+	for _, w := range conf.Watch {
+		for _, r := range watchRules(w) {
+			path := r.Out
+			if path == "" || path == "-" || isNetOut(path) || isWebhookOut(path) || isKafkaOut(path) || isSyslogOut(path) {
+				continue
+			}
+
+			if isDatedOut(path) {
+				path = strftime(path, time.Now())
+			}
+
+			if err := checkWritable(path); err != nil {
+				problems = append(problems, "output "+path+" is not writable: "+err.Error())
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkWritable checks that a file can be opened for appending, or created in
+// its directory, or in the closest existing parent of its directory when
+// missing directories are to be created.
+func checkWritable(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+
+		return file.Close()
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil && !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		if err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+
+		dir = filepath.Dir(dir)
+	}
+
+	file, err := ioutil.TempFile(dir, ".sauron-check-")
+	if err != nil {
+		return err
+	}
+	file.Close()
+
+	return os.Remove(file.Name())
+}
+
+// checkPaths checks that the paths of every watch, including those of its
+// PathsFile, can be read. Paths missing while the watch waits for them are
+// fine.
+func checkPaths(conf Config) []string {
+	var problems []string
+
+	for _, w := range conf.Watch {
+		paths := w.Paths
+		if w.PathsFile != "" {
+			listed, err := readPathsFile(w.PathsFile)
+			if err != nil {
+				problems = append(problems, "paths file "+w.PathsFile+" is not readable: "+err.Error())
+			}
+			paths = append(append([]string(nil), paths...), listed...)
+		}
+
+		for _, path := range paths {
+			if _, err := os.Stat(path); os.IsNotExist(err) && w.WaitForPath {
+				continue
+			}
+
+			if err := checkReadable(path); err != nil {
+				problems = append(problems, "path "+path+" is not accessible: "+err.Error())
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkReadable checks that a file can be read, or a directory listed.
+func checkReadable(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		if _, err := file.Readdirnames(1); err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	return nil
+}
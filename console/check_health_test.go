@@ -0,0 +1,117 @@
+package console
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// runCheckHealth runs the check-health command with a config, returning its
+// report and exit code.
+func runCheckHealth(t *testing.T, dir, config string) (string, int) {
+	conf := filepath.Join(dir, "sauron.toml")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(config), 0644))
+
+	set := flag.NewFlagSet("test", 0)
+	set.String("conf", conf, "")
+
+	var out bytes.Buffer
+	app := cli.NewApp()
+	app.Writer = &out
+
+	err := CheckHealthAction(cli.NewContext(app, set, nil))
+	if err == nil {
+		return out.String(), 0
+	}
+
+	return out.String(), err.(cli.ExitCoder).ExitCode()
+}
+
+func TestCheckHealth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	out, code := runCheckHealth(t, dir, `
+NoPidFile = true
+
+[[Watch]]
+Paths = ["`+dir+`"]
+Out = "`+filepath.Join(dir, "out", "%Y", "out.log")+`"
+`)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "daemon: ok\noutputs: ok\npaths: ok\n", out)
+
+	// Checking outputs leaves nothing behind.
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "sauron.toml")}, files)
+}
+
+func TestCheckHealthMissingPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	missing := filepath.Join(dir, "missing")
+	out, code := runCheckHealth(t, dir, `
+NoPidFile = true
+
+[[Watch]]
+Paths = ["`+dir+`", "`+missing+`"]
+
+[[Watch]]
+Paths = ["`+filepath.Join(dir, "later")+`"]
+WaitForPath = true
+`)
+	assert.Equal(t, 4, code)
+	assert.Contains(t, out, "outputs: ok\npaths: path "+missing+" is not accessible")
+	assert.NotContains(t, out, "later")
+}
+
+func TestCheckHealthUnwritableOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// A file stands where the directory of the output should be.
+	file := filepath.Join(dir, "file")
+	assert.Nil(t, ioutil.WriteFile(file, nil, 0644))
+	output := filepath.Join(file, "logs", "out.log")
+
+	out, code := runCheckHealth(t, dir, `
+NoPidFile = true
+
+[[Watch]]
+Paths = ["`+filepath.Join(dir, "missing")+`"]
+Out = "`+output+`"
+`)
+	assert.Equal(t, 3, code)
+	assert.Equal(t, "daemon: ok\noutputs: output "+output+" is not writable: "+file+" is not a directory\n", out)
+}
+
+func TestCheckHealthDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	pidFile := pidFilePath()
+	defer os.Remove(pidFile)
+
+	assert.Nil(t, ioutil.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())), 0644))
+	out, code := runCheckHealth(t, dir, `Log = "sauron.log"`)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "daemon: ok\n")
+
+	assert.Nil(t, os.Remove(pidFile))
+	out, code = runCheckHealth(t, dir, `Log = "sauron.log"`)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out, "daemon: no pid file at "+pidFile)
+}
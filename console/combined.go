@@ -0,0 +1,185 @@
+package console
+
+import (
+	"bytes"
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// combined is the output every watch writes to when the config sets Combined,
+// or nil when watches write to their own outputs. It is set at startup.
+var combined *combiner
+
+// combiner writes the lines of every watch to a single output, once per line
+// whatever the number of rules it matched. With a window, lines are held for
+// at least that long and written in the order of their timestamps, so that
+// the lines of watches read at different paces are approximately ordered.
+// Lines without a timestamp are ordered by their arrival. It is safe for
+// concurrent use.
+type combiner struct {
+	out    *output
+	window time.Duration
+	now    func() time.Time
+
+	mutex   sync.Mutex
+	pending combinedLines
+	seq     uint64
+
+	closed chan bool
+	done   chan bool
+}
+
+// combinedLine is a line held by a combiner. Its time is the timestamp of the
+// line, or its arrival when it has none.
+type combinedLine struct {
+	time    time.Time
+	arrival time.Time
+	seq     uint64
+	text    string
+}
+
+// combinedLines is a heap of held lines, the earliest first. Lines of the same
+// time are kept in the order they arrived.
+type combinedLines []combinedLine
+
+func (l combinedLines) Len() int      { return len(l) }
+func (l combinedLines) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+func (l combinedLines) Less(i, j int) bool {
+	if l[i].time.Equal(l[j].time) {
+		return l[i].seq < l[j].seq
+	}
+
+	return l[i].time.Before(l[j].time)
+}
+
+func (l *combinedLines) Push(x interface{}) { *l = append(*l, x.(combinedLine)) }
+
+func (l *combinedLines) Pop() interface{} {
+	old := *l
+	line := old[len(old)-1]
+	*l = old[:len(old)-1]
+
+	return line
+}
+
+// openCombined opens the combined output of the config, holding lines for
+// window to order them.
+func openCombined(path string, window time.Duration) error {
+	out, err := openOut(path)
+	if err != nil {
+		return err
+	}
+
+	combined = newCombiner(out, window)
+
+	return nil
+}
+
+// newCombiner creates the combiner writing to out.
+func newCombiner(out *output, window time.Duration) *combiner {
+	c := &combiner{
+		out:    out,
+		window: window,
+		now:    time.Now,
+		closed: make(chan bool),
+		done:   make(chan bool),
+	}
+
+	if window > 0 {
+		go c.run()
+	} else {
+		close(c.done)
+	}
+
+	return c
+}
+
+// combineWatch returns a watch writing every line, whatever rule it matches,
+// to the combined output at path.
+func combineWatch(w watch, path string) watch {
+	w.Out = path
+
+	rules := make([]rule, len(w.Rules))
+	for i, r := range w.Rules {
+		r.Out = path
+		rules[i] = r
+	}
+	w.Rules = rules
+
+	return w
+}
+
+// add writes the record formatted in buf, right away without a window, or
+// once it was held for the window otherwise. The timestamp of the record is
+// zero when unknown.
+func (c *combiner) add(timestamp time.Time, buf *bytes.Buffer) {
+	if c.window <= 0 {
+		write(buf, c.out)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	if timestamp.IsZero() {
+		timestamp = now
+	}
+
+	c.seq++
+	heap.Push(&c.pending, combinedLine{time: timestamp, arrival: now, seq: c.seq, text: buf.String()})
+}
+
+// run writes the held lines as their window elapses, until the combiner is
+// closed.
+func (c *combiner) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.flush(false)
+		case <-c.closed:
+			c.flush(true)
+			return
+		}
+	}
+}
+
+// flush writes the earliest lines held for the window, or every line when
+// all is set.
+func (c *combiner) flush(all bool) {
+	var records []string
+
+	c.mutex.Lock()
+	now := c.now()
+	for c.pending.Len() > 0 && (all || !now.Before(c.pending[0].arrival.Add(c.window))) {
+		records = append(records, heap.Pop(&c.pending).(combinedLine).text)
+	}
+	c.mutex.Unlock()
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	for _, record := range records {
+		buf.Reset()
+		buf.WriteString(record)
+		write(buf, c.out)
+	}
+}
+
+// close writes the lines still held.
+func (c *combiner) close() {
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
+
+	<-c.done
+}
@@ -0,0 +1,137 @@
+package console
+
+import (
+	"../eye"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHandlerCombined(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	path := filepath.Join(dir, "combined.log")
+	assert.Nil(t, openCombined(path, 0))
+
+	handlerOf := func(w watch) eye.LineHandler {
+		w = combineWatch(w, path)
+
+		routes, err := openRoutes(w)
+		assert.Nil(t, err)
+
+		return getHandler(newTestContext(), routes, nil, w)
+	}
+
+	app := handlerOf(watch{Out: filepath.Join(dir, "app.log")})
+	db := handlerOf(watch{
+		LinePattern: "ERROR",
+		Rules: []rule{
+			{LinePattern: "ERROR", Out: filepath.Join(dir, "errors.log")},
+			{LinePattern: "db 1", Out: filepath.Join(dir, "db.log")},
+		},
+	})
+
+	assert.Nil(t, app(eye.Line{Text: "app 1"}))
+	assert.Nil(t, db(eye.Line{Text: "ERROR db 1"}))
+	assert.Nil(t, app(eye.Line{Text: "app 2"}))
+	assert.Nil(t, db(eye.Line{Text: "INFO db 2"}))
+	assert.Nil(t, db(eye.Line{Text: "ERROR db 3"}))
+
+	closeOutputs()
+
+	// Lines matching several rules are written once, and the outputs of the
+	// watches aren't opened.
+	out, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "app 1\nERROR db 1\napp 2\nERROR db 3\n", string(out))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+}
+
+func TestGetHandlerCombinedWindow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	path := filepath.Join(dir, "combined.log")
+	assert.Nil(t, openCombined(path, time.Minute))
+
+	w := combineWatch(watch{
+		TimestampPattern: `^\S+`,
+		TimestampLayout:  time.RFC3339,
+	}, path)
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	// The lines of a slower watch arrive late, but are written in time.
+	fast := getHandler(newTestContext(), routes, nil, w)
+	slow := getHandler(newTestContext(), routes, nil, w)
+
+	assert.Nil(t, fast(eye.Line{Text: "2020-01-02T03:04:02Z fast 2"}))
+	assert.Nil(t, fast(eye.Line{Text: "2020-01-02T03:04:04Z fast 4"}))
+	assert.Nil(t, slow(eye.Line{Text: "2020-01-02T03:04:01Z slow 1"}))
+	assert.Nil(t, slow(eye.Line{Text: "2020-01-02T03:04:03Z slow 3"}))
+
+	closeOutputs()
+
+	out, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "2020-01-02T03:04:01Z slow 1\n"+
+		"2020-01-02T03:04:02Z fast 2\n"+
+		"2020-01-02T03:04:03Z slow 3\n"+
+		"2020-01-02T03:04:04Z fast 4\n", string(out))
+}
+
+func TestCombinerFlush(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	path := filepath.Join(dir, "combined.log")
+	out, err := openOut(path)
+	assert.Nil(t, err)
+
+	c := newCombiner(out, time.Minute)
+	defer c.close()
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	c.add(time.Time{}, bytes.NewBufferString("no timestamp 1\n"))
+	c.add(now.Add(-time.Hour), bytes.NewBufferString("an hour ago\n"))
+	c.add(time.Time{}, bytes.NewBufferString("no timestamp 2\n"))
+
+	// Lines are held for the window.
+	now = now.Add(30 * time.Second)
+	c.flush(false)
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "", string(content))
+
+	c.add(time.Time{}, bytes.NewBufferString("no timestamp 3\n"))
+
+	// Lines without a timestamp keep the order they arrived in.
+	now = now.Add(30 * time.Second)
+	c.flush(false)
+	content, err = ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "an hour ago\nno timestamp 1\nno timestamp 2\n", string(content))
+
+	c.flush(true)
+	content, err = ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "an hour ago\nno timestamp 1\nno timestamp 2\nno timestamp 3\n", string(content))
+}
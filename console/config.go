@@ -0,0 +1,419 @@
+package console
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+type Config struct {
+	Include                   []string // glob patterns of config files to merge in
+	Watch                     []watch
+	Patterns                  map[string]string // named patterns, referenced by watches as "@name", or "@{name}" within a pattern
+	Log                       string            // sauron log
+	Pool                      bool
+	HandlerWorkers            int      // handlers running at once across every watch, 0 for unbounded
+	OutBreakerThreshold       int      // consecutive write failures suspending an output, 5 by default, negative to disable
+	OutBreakerCooldown        duration // how long a failing output is suspended, 30s by default
+	OutBreakerPolicy          string   // drop (default) or buffer the lines of a suspended output
+	OutSyncInterval           duration // how often outputs are synced to disk, 1s by default, negative to disable
+	OutSyncEveryLine          bool     // sync outputs to disk after every line
+	WebhookBatchSize          int      // lines posted at once to webhooks, 100 by default
+	WebhookFlushInterval      duration // longest wait of a line for its webhook batch, 1s by default
+	WebhookDNSRetries         int      // retries of a webhook batch after failures to resolve the endpoint, 3 by default, negative to disable
+	WebhookConnectRetries     int      // retries of a webhook batch after failures to connect, 3 by default, negative to disable
+	WebhookTimeoutRetries     int      // retries of a webhook batch after timeouts, 2 by default, negative to disable
+	WebhookServerRetries      int      // retries of a webhook batch after 5xx answers, 3 by default, negative to disable
+	WebhookRetryDelay         duration // wait before the first retry of a webhook batch, doubling for each next one, 200ms by default
+	Combined                  string   // output every watch writes to instead of its own, as a single stream
+	CombinedWindow            duration // how long lines of the Combined output are held to be written in the order of their timestamps, in arrival order when zero
+	CountInterval             duration // how often --count writes the counts of matched lines, 1m by default
+	TimeZone                  string   // time zone of the dates in output paths, such as Europe/Paris, local by default
+	LogLevel                  string
+	PrefixTime                bool     // prefix time to every output line
+	PrefixPath                bool     // prefix file path to every output line (default)
+	PrefixHost                bool     // prefix host name to every output line
+	HealthAddr                string   // address serving /healthz, /tail and /metrics, disabled when empty
+	NoPidFile                 bool     // neither write the pid file nor check for a running instance
+	Quiet                     bool     // no operational logs, config print nor summary, only the matched lines
+	AllowedUsers              []string // users allowed to stop or reload the running instance with the commands, anyone when empty
+	DefaultFileIgnoreDuration duration // FileIgnoreDuration of the watches not setting one, 7 days by default
+	DefaultFileFollowDuration duration // FileFollowDuration of the watches not setting one, 7 days by default
+}
+
+type watch struct {
+	ID                  string // identifier written in json lines and with --prefix-id, watch-<index> by default
+	Paths               []string
+	PathsFile           string   // file listing more paths, one per line, read again on reload
+	Log                 string   // file of the operational logs of this watch, the global Log by default
+	WaitForPath         bool     // follow missing paths once they get created
+	RestartDelay        duration // first delay before restarting a watcher which failed to start, doubling up to RestartMaxDelay; 1s by default, negative to give up
+	RestartMaxDelay     duration // longest delay between watcher restarts, 1m by default
+	FilePattern         string   // file extension pattern
+	FileIgnorePattern   string
+	FileIgnoreDuration  duration
+	FileModifiedAfter   string // duration, today or yesterday; files modified before are ignored, along with FileIgnoreDuration
+	FileFollowDuration  duration
+	ActiveWindow        duration     // follow only files modified within this long, following stale files again on their next write; replaces FileIgnoreDuration and FileFollowDuration
+	MinFileSize         int64        // ignore files smaller than this many bytes, 0 to disable
+	MaxFileSize         int64        // ignore files larger than this many bytes, 0 to disable
+	EnableUnfollower    *bool        // unfollow files not modified for FileFollowDuration, true by default
+	InitialOrder        string       // order of the files found at startup: path (default) or mtime
+	SeekStart           string       // where files found at startup are followed from: end (default) or start
+	LatestOnly          bool         // follow only the newest file, switching to new files as they are created
+	MaxFiles            int          // follow at most this many files, the most recently modified ones
+	Recursive           bool         // watch the subdirectories of Paths for created files too
+	MaxWatches          int          // directories watched at once with Recursive, 0 for no limit; the least recently active ones are polled instead
+	FollowSymlinks      bool         // resolve symlinked Paths to their targets, following symlinked files anew when repointed; symlinked directories are resolved again on reload only
+	PathPattern         string       // directory pattern, matched with the platform's separators and with forward slashes alike
+	PathIgnorePattern   string       // directory pattern to exclude, wins over PathPattern
+	FullPathPattern     string       // pattern the whole file path must match, with either separators
+	LinePattern         string       // pattern to match
+	LinePatterns        []string     // more patterns to match, any of them will do
+	LineIgnorePattern   string       // pattern to ignore
+	LineIgnorePatterns  []string     // more patterns to ignore, any of them will do
+	LineContains        []string     // substrings of which at least one must be present
+	LineNotContains     []string     // substrings which must not be present
+	Redactions          []redaction  // replacements applied in order to lines before they are written
+	TrimSpace           bool         // remove leading and trailing whitespace from lines before they are written
+	CollapseWhitespace  bool         // replace runs of whitespace in lines by a single space before they are written
+	TrimBeforeMatch     bool         // apply TrimSpace and CollapseWhitespace before lines are filtered and matched, not only once matched
+	OutputFields        []string     // named groups of LinePattern written instead of the whole line
+	OutputDelimiter     string       // separator of OutputFields, tab by default; one character delimiters are written as CSV
+	TimestampPattern    string       // regex finding the timestamp of a line, its "timestamp" group or whole match, for --since
+	TimestampLayout     string       // Go layout of the timestamps, such as "2006-01-02 15:04:05"
+	TimestampRequired   bool         // drop lines without a parseable timestamp with --since
+	TimePattern         string       // regex finding the event time of a line, its "timestamp" group or whole match, replacing the read time in prefix-time and json output
+	TimeLayout          string       // Go layout of the event times, such as "02/Jan/2006:15:04:05 -0700"
+	LevelPattern        string       // regex finding the level of a line, its "level" group or whole match, for LevelMin
+	LevelMin            string       // drop lines below this level, such as warn
+	LevelRequired       bool         // drop lines without a known level with LevelMin
+	Correlation         *correlation // write only the PatternB lines shortly following a PatternA line
+	Sample              int          // write only one in this many matching lines, 0 or 1 to write them all
+	SampleMode          string       // deterministic (default) to write every Sample-th line, or random
+	SkipBinary          bool         // ignore files that look binary
+	GzipStreamPattern   string       // regex of the file names followed as gzip streams, decoding the members appended to them; leave out rotated archives such as app.log.1.gz
+	ReOpen              bool         // reopen followed files when they are rotated
+	ReopenOnTruncate    bool         // follow files truncated in place again from their beginning
+	FollowAfterRemove   duration     // keep reading removed files until idle for this long
+	TailErrorRetries    int          // re-open attempts after a tail error, negative to disable
+	TailMaxLineSize     int          // split lines longer than this many bytes, 0 to disable
+	TailRateLimit       int          // burst of lines read from a file before TailRateInterval applies, 0 to disable
+	TailRateInterval    duration     // time allowing one more line to be read once TailRateLimit is reached
+	HandlerBufferSize   int          // lines per file waiting for the output, 0 to disable
+	HandlerBufferPolicy string       // block or drop-oldest when the buffer is full
+	DebounceInterval    duration     // window collapsing bursts of file events
+	NewFileGrace        duration     // wait before following created files, skipping those gone by then
+	Snapshot            bool         // deliver whole files on every change instead of new lines
+	HeartbeatInterval   duration     // write a heartbeat line after this long without lines, 0 to disable
+	HeartbeatText       string       // text of the heartbeat lines, heartbeat by default
+	EmitStartEvent      bool         // write a "[desc] sauron started watching N files" line once the watch begins following its paths
+	Remote              *remote      // file to follow on a remote host
+	Exec                string       // command run for every matched line, split into arguments at the spaces outside quotes as by a shell, though none runs it; arguments may use {{.text}}, {{.path}} and {{.desc}}
+	ExecTimeout         duration     // time limit of a command, 10s by default
+	ExecWorkers         int          // commands running at once, 2 by default
+	ExecRate            int          // commands started per minute, 60 by default, negative for unlimited
+	Rules               []rule       // additional outputs for lines matching their own pattern
+	Out                 string       // file to write, - for standard output, tcp:// or udp://host:port, kafka://broker:port/topic, syslog://host:port, or a webhook URL
+	OutMode             string       // octal permissions of the output files, such as "0640"
+	OutOwner            string       // user name or id owning the output files
+	OutGroup            string       // group name or id owning the output files
+	OutOpenRetries      int          // attempts to open the outputs again, 3 by default, negative to disable
+	Archive             *archive     // upload the files dated outputs roll away from to S3
+	Kafka               *kafka       // producer settings of kafka:// outputs
+	Syslog              *syslogOut   // severities of the lines sent to syslog:// outputs
+	LineTerminator      string       // lf (default), crlf or null written after every line
+	Format              string       // text (default) or json
+	DockerJSON          bool         // lines are Docker JSON log entries, handled as the text they logged, with their stream and time
+	JSONFields          bool         // with the json format, write the named groups of LinePattern under fields
+	TailBufferSize      int          // recent lines served on /tail, 100 by default, negative to disable
+	Desc                string
+}
+
+// rule routes the lines of a watch matching LinePattern to Out, in addition to
+// the watch's own output.
+type rule struct {
+	LinePattern string
+	Out         string
+}
+
+// correlation keeps the lines matching PatternB only when a line matching
+// PatternA came at most WithinLines lines before them in the same file.
+type correlation struct {
+	PatternA    string
+	PatternB    string
+	WithinLines int // 1 for the line right before
+}
+
+// redaction masks the parts of lines matching Pattern with Replacement, which
+// may refer to capture groups as $1 or ${name}.
+type redaction struct {
+	Pattern     string
+	Replacement string
+}
+
+// syslogOut chooses the severity of the lines a watch sends to syslog
+// outputs: that of the first entry of SeverityMap matching the line, else that
+// of the level found with LevelPattern when SeverityFromLevel is set, else
+// Severity.
+type syslogOut struct {
+	Severity          string         // severity of the other lines, info by default
+	SeverityMap       []severityRule // severities of the lines matching their pattern, in order
+	SeverityFromLevel bool           // send lines at the severity of their level, such as err for ERROR
+}
+
+// severityRule sends the lines matching Pattern at Severity, one of emerg,
+// alert, crit, err, warning, notice, info or debug.
+type severityRule struct {
+	Pattern  string
+	Severity string
+}
+
+// remote describes a file followed over SSH/SFTP.
+type remote struct {
+	Host       string // host or host:port of the SSH server
+	User       string
+	Key        string // private key file used to authenticate
+	KnownHosts string // known_hosts file, defaults to ~/.ssh/known_hosts
+	Path       string // file to follow on the remote host
+}
+
+// kafka tunes the producer of the kafka://broker:port/topic outputs of a
+// watch. Several brokers may be listed, separated by commas.
+type kafka struct {
+	Key           string   // partitioning key of the messages: path, id or desc; none, spreading them evenly, by default
+	Acks          string   // acknowledgements awaited: leader (default), all or none
+	BatchSize     int      // messages sent at once, 100 by default
+	FlushInterval duration // longest wait of a message for its batch, 1s by default
+	QueueSize     int      // messages kept while the brokers fail, 10000 by default; new ones are dropped beyond
+}
+
+// archive ships the files a dated output rolls away from to an S3 bucket,
+// compressed, deleting them once uploaded. Credentials are read from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN variables.
+type archive struct {
+	S3Bucket string
+	Prefix   string // prepended to the file names to build the object keys
+	Region   string // AWS_REGION, or us-east-1, by default
+	Endpoint string // S3 compatible endpoint, the AWS one of Region by default
+	Retries  int    // attempts to upload a file again, 3 by default, negative to disable
+}
+
+// setConfig loads the config of a command from its configSources, applying
+// the flags which override it.
+func setConfig(c *cli.Context) (Config, bool) {
+	var conf Config
+	path, dir := configSources(c)
+	if path != "" {
+		if err := loadConfig(path, &conf, nil); err != nil {
+			logger.Errorln(err)
+			return conf, false
+		}
+	}
+
+	if dir != "" {
+		if err := loadConfigDir(dir, &conf); err != nil {
+			logger.Errorln(err)
+			return conf, false
+		}
+	}
+
+	if c.IsSet("pool") {
+		conf.Pool = c.Bool("pool")
+	}
+
+	if c.Bool("quiet") {
+		conf.Quiet = true
+	}
+
+	if len(conf.LogLevel) == 0 {
+		conf.LogLevel = "info"
+	}
+
+	if err := expandPatterns(&conf); err != nil {
+		logger.Errorln(err)
+		return conf, false
+	}
+
+	setWatchIDs(conf.Watch)
+
+	return conf, true
+}
+
+// configSources returns the config file and directory loaded by a command:
+// the --conf file, or sauron.conf next to the executable when neither --conf
+// nor --config-dir is given, and the --config-dir directory. Either is empty
+// when not loaded.
+func configSources(c *cli.Context) (path, dir string) {
+	path, dir = c.String("conf"), c.String("config-dir")
+	if path == "" && dir == "" {
+		path = defaultConfigPath()
+	}
+
+	return path, dir
+}
+
+// setWatchIDs gives the watches without an ID one derived from their index,
+// watch-1 for the first one.
+func setWatchIDs(watches []watch) {
+	for i := range watches {
+		if watches[i].ID == "" {
+			watches[i].ID = "watch-" + strconv.Itoa(i+1)
+		}
+	}
+}
+
+// loadConfig decodes the config file at path and merges it into conf. Files
+// listed by its Include patterns are loaded afterwards, relative to the
+// directory of the including file, so their scalar values take precedence and
+// their watches are appended. The stack holds the files currently being loaded
+// and is used to detect cyclic includes.
+//
+// A path of "-" reads the config from the standard input, in which case
+// includes are relative to the working directory.
+func loadConfig(path string, conf *Config, stack []string) error {
+	if path == "-" {
+		var file Config
+		md, err := toml.DecodeReader(os.Stdin, &file)
+		if err != nil {
+			return err
+		}
+
+		mergeConfig(conf, file, md)
+
+		return loadIncludes(file.Include, ".", conf, stack)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	for _, loading := range stack {
+		if loading == abs {
+			return fmt.Errorf("cyclic include: %s", strings.Join(append(stack, abs), " -> "))
+		}
+	}
+
+	var file Config
+	md, err := toml.DecodeFile(path, &file)
+	if err != nil {
+		return err
+	}
+
+	mergeConfig(conf, file, md)
+
+	return loadIncludes(file.Include, filepath.Dir(abs), conf, append(stack, abs))
+}
+
+// loadIncludes loads the config files matching the include patterns, relative
+// to dir, into conf.
+func loadIncludes(patterns []string, dir string, conf *Config, stack []string) error {
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+
+		for _, match := range matches {
+			if err := loadConfig(match, conf, stack); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadConfigDir loads every *.toml file of a directory, in lexical order, and
+// merges them into conf like includes are. Files that fail to load are skipped
+// and leave conf untouched.
+func loadConfigDir(dir string, conf *Config) error {
+	if _, err := os.Stat(dir); err != nil {
+		return err
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return err
+	}
+
+	for _, match := range matches {
+		// Copy what mergeConfig modifies in place, so that a file failing
+		// after being partially merged leaves none of it behind.
+		attempt := *conf
+		attempt.Watch = append([]watch(nil), conf.Watch...)
+		if conf.Patterns != nil {
+			attempt.Patterns = make(map[string]string, len(conf.Patterns))
+			for name, pattern := range conf.Patterns {
+				attempt.Patterns[name] = pattern
+			}
+		}
+
+		if err := loadConfig(match, &attempt, nil); err != nil {
+			logger.Errorln("Skipping config " + match + ": " + err.Error())
+			continue
+		}
+
+		*conf = attempt
+	}
+
+	return nil
+}
+
+// mergeConfig copies every top-level key defined in src over dst, except for
+// Watch which is concatenated, Patterns which are merged name by name, and
+// Include which is only meaningful for the file that declares it.
+func mergeConfig(dst *Config, src Config, md toml.MetaData) {
+	dst.Watch = append(dst.Watch, src.Watch...)
+
+	for name, pattern := range src.Patterns {
+		if dst.Patterns == nil {
+			dst.Patterns = make(map[string]string)
+		}
+		dst.Patterns[name] = pattern
+	}
+
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src)
+	for i := 0; i < sv.NumField(); i++ {
+		name := sv.Type().Field(i).Name
+		if name == "Watch" || name == "Patterns" || name == "Include" || !isDefined(md, name) {
+			continue
+		}
+
+		dv.Field(i).Set(sv.Field(i))
+	}
+}
+
+// isDefined reports whether a top-level key matching name was present in the
+// decoded file. Keys are compared case-insensitively, like the decoder does.
+func isDefined(md toml.MetaData, name string) bool {
+	for _, key := range md.Keys() {
+		if len(key) == 1 && strings.EqualFold(key[0], name) {
+			return true
+		}
+	}
+
+	return false
+}
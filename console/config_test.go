@@ -0,0 +1,259 @@
+package console
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func writeConfigFile(t *testing.T, path string, content string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestLoadConfigInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeConfigFile(t, filepath.Join(dir, "main.toml"), `
+include = ["conf.d/*.toml"]
+log = "main.log"
+logLevel = "info"
+
+[[watch]]
+paths = ["/var/log/main"]
+desc = "main"
+`)
+	writeConfigFile(t, filepath.Join(dir, "conf.d", "a.toml"), `
+logLevel = "debug"
+
+[[watch]]
+paths = ["/var/log/a"]
+desc = "a"
+`)
+	writeConfigFile(t, filepath.Join(dir, "conf.d", "b.toml"), `
+logLevel = "warn"
+
+[[watch]]
+paths = ["/var/log/b"]
+desc = "b"
+`)
+
+	var conf Config
+	err = loadConfig(filepath.Join(dir, "main.toml"), &conf, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(conf.Watch))
+	assert.Equal(t, "main", conf.Watch[0].Desc)
+	assert.Equal(t, "a", conf.Watch[1].Desc)
+	assert.Equal(t, "b", conf.Watch[2].Desc)
+	assert.Equal(t, "main.log", conf.Log)
+	assert.Equal(t, "warn", conf.LogLevel)
+}
+
+func TestLoadConfigCyclicInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeConfigFile(t, filepath.Join(dir, "a.toml"), `include = ["b.toml"]`)
+	writeConfigFile(t, filepath.Join(dir, "b.toml"), `include = ["a.toml"]`)
+
+	var conf Config
+	err = loadConfig(filepath.Join(dir, "a.toml"), &conf, nil)
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "cyclic include")
+}
+
+func TestLoadConfigDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeConfigFile(t, filepath.Join(dir, "a.toml"), `
+logLevel = "debug"
+
+[patterns]
+warn = "WARN"
+
+[[watch]]
+paths = ["/var/log/a"]
+desc = "a"
+`)
+	writeConfigFile(t, filepath.Join(dir, "b.toml"), `
+[[watch]]
+paths = ["/var/log/b"
+`)
+	writeConfigFile(t, filepath.Join(dir, "bb.toml"), `
+include = ["broken/*.toml"]
+
+[patterns]
+warn = "WARNING"
+error = "ERROR"
+
+[[watch]]
+paths = ["/var/log/bb"]
+desc = "bb"
+`)
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "broken"), 0755))
+	writeConfigFile(t, filepath.Join(dir, "broken", "bad.toml"), `log = `)
+	writeConfigFile(t, filepath.Join(dir, "c.toml"), `
+log = "c.log"
+
+[[watch]]
+paths = ["/var/log/c"]
+desc = "c"
+`)
+	writeConfigFile(t, filepath.Join(dir, "ignored.conf"), `log = "ignored.log"`)
+
+	var conf Config
+	err = loadConfigDir(dir, &conf)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(conf.Watch))
+	assert.Equal(t, "a", conf.Watch[0].Desc)
+	assert.Equal(t, "c", conf.Watch[1].Desc)
+	assert.Equal(t, map[string]string{"warn": "WARN"}, conf.Patterns)
+	assert.Equal(t, "debug", conf.LogLevel)
+	assert.Equal(t, "c.log", conf.Log)
+}
+
+func TestLoadConfigDirMissing(t *testing.T) {
+	var conf Config
+
+	assert.NotNil(t, loadConfigDir("../_resources/missing", &conf))
+}
+
+func TestLoadConfigStdin(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	assert.Nil(t, err)
+
+	stdin := os.Stdin
+	os.Stdin = reader
+	defer func() { os.Stdin = stdin }()
+
+	go func() {
+		writer.Write([]byte(`
+logLevel = "debug"
+
+[[watch]]
+paths = ["/var/log/piped"]
+desc = "piped"
+`))
+		writer.Close()
+	}()
+
+	var conf Config
+	err = loadConfig("-", &conf, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "debug", conf.LogLevel)
+	assert.Equal(t, 1, len(conf.Watch))
+	assert.Equal(t, []string{"/var/log/piped"}, conf.Watch[0].Paths)
+	assert.Equal(t, "piped", conf.Watch[0].Desc)
+}
+
+func TestSetWatchIDs(t *testing.T) {
+	watches := []watch{{Desc: "nginx"}, {ID: "api", Desc: "api"}, {}}
+	setWatchIDs(watches)
+
+	assert.Equal(t, "watch-1", watches[0].ID)
+	assert.Equal(t, "api", watches[1].ID)
+	assert.Equal(t, "watch-3", watches[2].ID)
+
+	// IDs are derived from the index alone, so they stay the same across
+	// loads of the same config.
+	again := []watch{{Desc: "nginx"}, {ID: "api", Desc: "api"}, {}}
+	setWatchIDs(again)
+	assert.Equal(t, watches, again)
+}
+
+func TestExpandPatterns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	writeConfigFile(t, filepath.Join(dir, "main.toml"), `
+include = ["more.toml"]
+
+[patterns]
+status_5xx = "5\\d\\d"
+http_error = "HTTP/1\\.1\" @{status_5xx}"
+
+[[watch]]
+paths = ["/var/log/nginx"]
+linePattern = "@http_error"
+linePatterns = ["@{timeout}+$", "user@example\\.com"]
+
+[[watch.rules]]
+linePattern = "@status_5xx"
+out = "errors.log"
+`)
+	writeConfigFile(t, filepath.Join(dir, "more.toml"), `
+[patterns]
+timeout = "timed out"
+`)
+
+	var conf Config
+	assert.Nil(t, loadConfig(filepath.Join(dir, "main.toml"), &conf, nil))
+	assert.Nil(t, expandPatterns(&conf))
+
+	w := conf.Watch[0]
+	assert.Equal(t, `HTTP/1\.1" (?:5\d\d)`, w.LinePattern)
+	assert.Equal(t, []string{"(?:timed out)+$", `user@example\.com`}, w.LinePatterns)
+	assert.Equal(t, `5\d\d`, w.Rules[0].LinePattern)
+}
+
+func TestExpandPatternsErrors(t *testing.T) {
+	library := map[string]string{
+		"a":     "@{b} and a",
+		"b":     "@a",
+		"outer": "(@{missing})",
+	}
+
+	expand := func(pattern string) error {
+		conf := Config{Patterns: library, Watch: []watch{{}, {LineIgnorePattern: pattern}}}
+		return expandPatterns(&conf)
+	}
+
+	err := expand("@undefined")
+	assert.NotNil(t, err)
+	assert.Equal(t, `watch #2 LineIgnorePattern: undefined pattern "undefined"`, err.Error())
+
+	err = expand("x@{outer}")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `undefined pattern "missing"`)
+
+	err = expand("@a")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "cyclic pattern reference: a -> b -> a")
+
+	// Bare words following an @ within larger patterns are literal text.
+	assert.Nil(t, expand(`user@admin\.example`))
+}
+
+func TestSetConfigUndefinedPattern(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := filepath.Join(dir, "sauron.toml")
+	writeConfigFile(t, conf, `
+[[watch]]
+paths = ["/var/log"]
+linePattern = "@{typo}+"
+`)
+
+	set := flag.NewFlagSet("test", 0)
+	set.String("conf", conf, "")
+
+	_, ok := setConfig(cli.NewContext(nil, set, nil))
+	assert.False(t, ok)
+}
@@ -0,0 +1,84 @@
+package console
+
+import (
+	"../eye"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// correlator keeps the lines of a watch matching the PatternB of its
+// Correlation only when a line matching PatternA came at most WithinLines
+// lines before them, counting the lines of every file on their own. It drops
+// every other line. It is safe for concurrent use.
+type correlator struct {
+	a      *regexp.Regexp
+	b      *regexp.Regexp
+	within int64
+
+	mutex sync.Mutex
+	files map[string]*correlationWindow
+}
+
+// correlationWindow is where a file stands in a correlation: how many of its
+// lines were read, and which of them last matched PatternA, 0 for none.
+type correlationWindow struct {
+	lines int64
+	lastA int64
+}
+
+// newCorrelator builds the correlator of a watch, or returns nil when the
+// watch has no valid Correlation.
+func newCorrelator(w watch) *correlator {
+	if w.Correlation == nil {
+		return nil
+	}
+
+	if w.Correlation.WithinLines <= 0 {
+		logger.Errorln("Invalid correlation window " + strconv.Itoa(w.Correlation.WithinLines) + ", keeping every line")
+		return nil
+	}
+
+	a, err := regexp.Compile(w.Correlation.PatternA)
+	if err != nil {
+		logger.Errorln("Invalid correlation pattern " + w.Correlation.PatternA + ": " + err.Error())
+		return nil
+	}
+
+	b, err := regexp.Compile(w.Correlation.PatternB)
+	if err != nil {
+		logger.Errorln("Invalid correlation pattern " + w.Correlation.PatternB + ": " + err.Error())
+		return nil
+	}
+
+	return &correlator{
+		a:      a,
+		b:      b,
+		within: int64(w.Correlation.WithinLines),
+		files:  make(map[string]*correlationWindow),
+	}
+}
+
+// keep reports whether line matches PatternB within the window of a line
+// matching PatternA, counting it in the window of its file. A line matching
+// both patterns doesn't correlate with itself.
+func (c *correlator) keep(line eye.Line) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	window, ok := c.files[line.Path]
+	if !ok {
+		window = &correlationWindow{}
+		c.files[line.Path] = window
+	}
+
+	window.lines++
+
+	kept := window.lastA > 0 && window.lines-window.lastA <= c.within && c.b.MatchString(line.Text)
+
+	if c.a.MatchString(line.Text) {
+		window.lastA = window.lines
+	}
+
+	return kept
+}
@@ -0,0 +1,81 @@
+package console
+
+import (
+	"../eye"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCorrelator(within int) *correlator {
+	return newCorrelator(watch{Correlation: &correlation{
+		PatternA:    "connection opened",
+		PatternB:    "error",
+		WithinLines: within,
+	}})
+}
+
+func TestCorrelatorWindow(t *testing.T) {
+	c := newTestCorrelator(3)
+
+	keep := func(text string) bool {
+		return c.keep(eye.Line{Path: "/var/log/app.log", Text: text})
+	}
+
+	assert.False(t, keep("error before any connection"))
+	assert.False(t, keep("connection opened"))
+	assert.False(t, keep("request"))
+	assert.True(t, keep("error 2 lines after"))
+	assert.True(t, keep("error 3 lines after"))
+	assert.False(t, keep("error 4 lines after"))
+
+	// A new PatternA line opens a new window.
+	assert.False(t, keep("connection opened"))
+	assert.True(t, keep("error right after"))
+}
+
+func TestCorrelatorPerFile(t *testing.T) {
+	c := newTestCorrelator(1)
+
+	assert.False(t, c.keep(eye.Line{Path: "/var/log/a.log", Text: "connection opened"}))
+	assert.False(t, c.keep(eye.Line{Path: "/var/log/b.log", Text: "error in another file"}))
+	assert.True(t, c.keep(eye.Line{Path: "/var/log/a.log", Text: "error in the same file"}))
+
+	// A line matching both patterns doesn't correlate with itself.
+	c = newTestCorrelator(1)
+	assert.False(t, c.keep(eye.Line{Path: "/var/log/a.log", Text: "connection opened with error"}))
+	assert.True(t, c.keep(eye.Line{Path: "/var/log/a.log", Text: "error"}))
+}
+
+func TestNewCorrelatorInvalid(t *testing.T) {
+	assert.Nil(t, newCorrelator(watch{}))
+	assert.Nil(t, newTestCorrelator(0))
+	assert.Nil(t, newCorrelator(watch{Correlation: &correlation{PatternA: "(", PatternB: "error", WithinLines: 1}}))
+}
+
+func TestGetHandlerCorrelation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	w := watch{
+		Correlation: &correlation{PatternA: "opened", PatternB: "ERROR", WithinLines: 2},
+		Out:         filepath.Join(dir, "out.log"),
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, nil, w)
+	for _, text := range []string{"opened", "ERROR in window", "ok", "ERROR out of window"} {
+		assert.Nil(t, handler(eye.Line{Path: "/var/log/app.log", Text: text}))
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "out.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ERROR in window\n", string(out))
+}
@@ -0,0 +1,106 @@
+package console
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// countInterval is how often the counts of --count are written. It is set
+// from the config at startup.
+var countInterval = time.Minute
+
+// matchCounter counts the lines a watch matched on each of its routes, for
+// --count. The counts are written to the outputs of the routes in place of the
+// lines: every interval, and a last time when stopped.
+type matchCounter struct {
+	name   string
+	routes []route
+	counts []uint64
+	emit   func(r route, text string, now time.Time)
+
+	stop    chan bool
+	stopped chan bool
+}
+
+var (
+	matchCounters      []*matchCounter
+	matchCountersMutex sync.Mutex
+)
+
+// startMatchCounter starts counting the lines the watch called name matches
+// on routes, emitting their counts every interval.
+func startMatchCounter(name string, routes []route, interval time.Duration, emit func(r route, text string, now time.Time)) *matchCounter {
+	m := &matchCounter{
+		name:    name,
+		routes:  routes,
+		counts:  make([]uint64, len(routes)),
+		emit:    emit,
+		stop:    make(chan bool),
+		stopped: make(chan bool),
+	}
+
+	matchCountersMutex.Lock()
+	matchCounters = append(matchCounters, m)
+	matchCountersMutex.Unlock()
+
+	go m.run(interval)
+
+	return m
+}
+
+// add counts a line matched on routes.
+func (m *matchCounter) add(matched []route) {
+	for _, r := range matched {
+		for i := range m.routes {
+			if m.routes[i] == r {
+				atomic.AddUint64(&m.counts[i], 1)
+			}
+		}
+	}
+}
+
+// run emits the counts every interval until stopped, and once more then.
+func (m *matchCounter) run(interval time.Duration) {
+	defer close(m.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			m.emitCounts(now)
+		case <-m.stop:
+			m.emitCounts(time.Now())
+			return
+		}
+	}
+}
+
+// emitCounts emits the count of every route, such as "app matched 12 lines
+// of ERROR", the pattern being left out for routes matching any line.
+func (m *matchCounter) emitCounts(now time.Time) {
+	for i, r := range m.routes {
+		text := m.name + " matched " + strconv.FormatUint(atomic.LoadUint64(&m.counts[i]), 10) + " lines"
+		if r.lineReg != nil {
+			text += " of " + r.lineReg.String()
+		}
+
+		m.emit(r, text, now)
+	}
+}
+
+// stopMatchCounters stops every counter, once they emitted their last counts,
+// before the outputs are closed.
+func stopMatchCounters() {
+	matchCountersMutex.Lock()
+	defer matchCountersMutex.Unlock()
+
+	for _, m := range matchCounters {
+		close(m.stop)
+		<-m.stopped
+	}
+	matchCounters = nil
+}
@@ -0,0 +1,91 @@
+package console
+
+import (
+	"../eye"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func TestGetHandlerCount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+	defer stopMatchCounters()
+
+	interval := countInterval
+	countInterval = time.Hour
+	defer func() { countInterval = interval }()
+
+	set := flag.NewFlagSet("test", 0)
+	set.Bool("prefix-path", false, "")
+	set.Bool("count", true, "")
+	c := cli.NewContext(nil, set, nil)
+
+	out := filepath.Join(dir, "out.log")
+	errors := filepath.Join(dir, "errors.log")
+	w := watch{
+		ID:  "app",
+		Out: out,
+		Rules: []rule{
+			{LinePattern: "ERROR", Out: errors},
+		},
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(c, routes, nil, w)
+	for _, text := range []string{"INFO 1", "ERROR 2", "INFO 3", "ERROR 4", "ERROR 5"} {
+		assert.Nil(t, handler(eye.Line{Text: text}))
+	}
+
+	// No line is written, only the counts once stopped.
+	content, err := ioutil.ReadFile(out)
+	assert.Nil(t, err)
+	assert.Equal(t, "", string(content))
+
+	stopMatchCounters()
+
+	content, err = ioutil.ReadFile(out)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app matched 5 lines"}, strings.Split(strings.TrimSpace(string(content)), "\n"))
+
+	content, err = ioutil.ReadFile(errors)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app matched 3 lines of ERROR"}, strings.Split(strings.TrimSpace(string(content)), "\n"))
+}
+
+func TestMatchCounterInterval(t *testing.T) {
+	emitted := make(chan string, 10)
+	routes := []route{{lineReg: compilePattern("ERROR")}}
+
+	m := startMatchCounter("app", routes, 20*time.Millisecond, func(r route, text string, now time.Time) {
+		select {
+		case emitted <- text:
+		default:
+		}
+	})
+	defer stopMatchCounters()
+
+	m.add(routes)
+	m.add(routes)
+
+	// The counts are written again every interval, adding up over the run.
+	assert.Equal(t, "app matched 2 lines of ERROR", <-emitted)
+	m.add(routes)
+	for text := range emitted {
+		if text == "app matched 3 lines of ERROR" {
+			break
+		}
+		assert.Equal(t, "app matched 2 lines of ERROR", text)
+	}
+}
@@ -0,0 +1,122 @@
+package console
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// outLocation is the time zone of the dates in output paths, set from the
+// config at startup.
+var outLocation = time.Local
+
+// isDatedOut reports whether an output path is a pattern with dates, such as
+// out-%Y-%m-%d.log.
+func isDatedOut(path string) bool {
+	return strings.Contains(path, "%")
+}
+
+// strftime formats t after a pattern using the %Y (year), %y (two digit
+// year), %m (month), %d (day), %j (day of the year), %H (hour), %M (minute)
+// and %% (percent sign) directives. Other directives are left as they are.
+func strftime(pattern string, t time.Time) string {
+	var b strings.Builder
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i == len(pattern)-1 {
+			b.WriteByte(pattern[i])
+			continue
+		}
+
+		i++
+		switch pattern[i] {
+		case 'Y':
+			b.WriteString(strconv.Itoa(t.Year()))
+		case 'y':
+			b.WriteString(t.Format("06"))
+		case 'm':
+			b.WriteString(t.Format("01"))
+		case 'd':
+			b.WriteString(t.Format("02"))
+		case 'j':
+			b.WriteString(t.Format("002"))
+		case 'H':
+			b.WriteString(t.Format("15"))
+		case 'M':
+			b.WriteString(t.Format("04"))
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(pattern[i])
+		}
+	}
+
+	return b.String()
+}
+
+// newDatedOutput creates an output writing to the file its pattern gives for
+// the current time in outLocation, moving to the next file as the date
+// changes. Files ending in .gz are written as gzip streams.
+func newDatedOutput(pattern string) (*output, error) {
+	out := &output{
+		pattern:  pattern,
+		location: outLocation,
+		now:      time.Now,
+		closed:   make(chan bool),
+		breaker:  newBreaker(pattern),
+	}
+
+	if err := out.roll(); err != nil {
+		return nil, err
+	}
+
+	if syncInterval > 0 {
+		go out.flushPeriodically(syncInterval)
+	}
+
+	return out, nil
+}
+
+// roll moves a dated output to the file of the current time when it changed,
+// completing and closing the previous one.
+func (o *output) roll() error {
+	path := strftime(o.pattern, o.now().In(o.location))
+	if path == o.path && o.file != nil {
+		return nil
+	}
+
+	if o.file != nil {
+		if err := o.closeFile(); err != nil {
+			logger.Errorln(err)
+		}
+		o.file, o.gzip = nil, nil
+
+		if o.rolled != nil {
+			o.rolled(o.path)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	o.file, o.path = file, path
+	if strings.HasSuffix(path, ".gz") {
+		o.gzip = gzip.NewWriter(file)
+	}
+
+	if o.opened != nil {
+		o.opened(file)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package console
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrftime(t *testing.T) {
+	at := time.Date(2024, 6, 1, 9, 5, 0, 0, time.UTC)
+
+	assert.Equal(t, "out-2024-06-01.log", strftime("out-%Y-%m-%d.log", at))
+	assert.Equal(t, "24/153/09h05", strftime("%y/%j/%Hh%M", at))
+	assert.Equal(t, "100%-%q-%", strftime("100%%-%q-%", at))
+}
+
+func TestDatedOutputRollover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	// Dates follow the configured zone rather than UTC.
+	defer func(location *time.Location) { outLocation = location }(outLocation)
+	outLocation = time.FixedZone("UTC+2", 2*60*60)
+
+	now := time.Date(2024, 6, 1, 21, 59, 0, 0, time.UTC)
+
+	out, err := openOut(filepath.Join(dir, "%Y", "out-%Y-%m-%d.log"))
+	assert.Nil(t, err)
+	out.mutex.Lock()
+	out.now = func() time.Time { return now }
+	out.mutex.Unlock()
+
+	writeText("before midnight", out, "\n")
+
+	now = now.Add(2 * time.Minute)
+	writeText("after midnight", out, "\n")
+	writeText("still the same day", out, "\n")
+
+	for path, expected := range map[string]string{
+		"out-2024-06-01.log": "before midnight\n",
+		"out-2024-06-02.log": "after midnight\nstill the same day\n",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "2024", path))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(data))
+	}
+}
@@ -0,0 +1,81 @@
+package console
+
+import (
+	"../eye"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dockerEntry is a line of the json-file logging driver of Docker, such as
+// {"log":"started\n","stream":"stdout","time":"2020-01-02T03:04:05.123Z"}.
+type dockerEntry struct {
+	Log    string `json:"log"`
+	Stream string `json:"stream"`
+	Time   string `json:"time"`
+}
+
+// dockerDecoder unwraps the Docker JSON entries of a watch into the lines
+// logged by containers. Docker splits long lines into entries whose log
+// doesn't end with a newline, which are joined until the entry ending the
+// line, apart for every stream of every file. It is safe for concurrent use.
+type dockerDecoder struct {
+	mutex   sync.Mutex
+	partial map[dockerStream]*eye.Line
+}
+
+// dockerStream identifies a stream of the containers logging to a file.
+type dockerStream struct {
+	path   string
+	stream string
+}
+
+// newDockerDecoder returns the decoder of a watch, or nil unless it reads
+// Docker JSON logs.
+func newDockerDecoder(w watch) *dockerDecoder {
+	if !w.DockerJSON {
+		return nil
+	}
+
+	return &dockerDecoder{partial: make(map[dockerStream]*eye.Line)}
+}
+
+// decode replaces a line with the text it logged, its stream and its time,
+// reporting whether it completes a line. Lines which aren't Docker entries
+// are kept as they are. The offset of joined lines is the one of their first
+// entry.
+func (d *dockerDecoder) decode(line eye.Line) (eye.Line, bool) {
+	var entry dockerEntry
+	if err := json.Unmarshal([]byte(line.Text), &entry); err != nil {
+		return line, true
+	}
+
+	line.Text = entry.Log
+	line.Stream = entry.Stream
+	if t, err := time.Parse(time.RFC3339Nano, entry.Time); err == nil {
+		line.Time = t
+	}
+
+	key := dockerStream{path: line.Path, stream: line.Stream}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if first, ok := d.partial[key]; ok {
+		first.Text += line.Text
+		line.Text = first.Text
+		line.Time = first.Time
+		line.Offset = first.Offset
+	}
+
+	if !strings.HasSuffix(line.Text, "\n") {
+		d.partial[key] = &line
+		return line, false
+	}
+
+	delete(d.partial, key)
+	line.Text = strings.TrimSuffix(strings.TrimSuffix(line.Text, "\n"), "\r")
+
+	return line, true
+}
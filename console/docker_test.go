@@ -0,0 +1,104 @@
+package console
+
+import (
+	"../eye"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerDecode(t *testing.T) {
+	d := newDockerDecoder(watch{DockerJSON: true})
+
+	line, complete := d.decode(eye.Line{
+		Path:   "/var/lib/docker/containers/abc/abc-json.log",
+		Text:   `{"log":"GET / 200\n","stream":"stdout","time":"2020-01-02T03:04:05.123456789Z"}`,
+		Offset: 10,
+	})
+	assert.True(t, complete)
+	assert.Equal(t, "GET / 200", line.Text)
+	assert.Equal(t, "stdout", line.Stream)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC), line.Time)
+	assert.Equal(t, int64(10), line.Offset)
+
+	// Lines which aren't Docker entries are kept as they are.
+	line, complete = d.decode(eye.Line{Text: "plain text"})
+	assert.True(t, complete)
+	assert.Equal(t, eye.Line{Text: "plain text"}, line)
+
+	assert.Nil(t, newDockerDecoder(watch{}))
+}
+
+func TestDockerDecodePartial(t *testing.T) {
+	d := newDockerDecoder(watch{DockerJSON: true})
+	a, b := "/containers/a/a-json.log", "/containers/b/b-json.log"
+
+	// Docker splits long lines into entries without a trailing newline.
+	_, complete := d.decode(eye.Line{Path: a, Offset: 0, Text: `{"log":"first part, ","stream":"stderr","time":"2020-01-02T03:04:05Z"}`})
+	assert.False(t, complete)
+	_, complete = d.decode(eye.Line{Path: a, Offset: 70, Text: `{"log":"second part, ","stream":"stderr","time":"2020-01-02T03:04:06Z"}`})
+	assert.False(t, complete)
+
+	// The lines of other files go on meanwhile.
+	line, complete := d.decode(eye.Line{Path: b, Text: `{"log":"other\r\n","stream":"stdout","time":"2020-01-02T03:04:07Z"}`})
+	assert.True(t, complete)
+	assert.Equal(t, "other", line.Text)
+
+	// So do those of the other stream of the same file.
+	line, complete = d.decode(eye.Line{Path: a, Offset: 100, Text: `{"log":"out\n","stream":"stdout","time":"2020-01-02T03:04:07Z"}`})
+	assert.True(t, complete)
+	assert.Equal(t, "out", line.Text)
+	assert.Equal(t, int64(100), line.Offset)
+
+	line, complete = d.decode(eye.Line{Path: a, Offset: 141, Text: `{"log":"last part\n","stream":"stderr","time":"2020-01-02T03:04:08Z"}`})
+	assert.True(t, complete)
+	assert.Equal(t, "first part, second part, last part", line.Text)
+	assert.Equal(t, "stderr", line.Stream)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), line.Time)
+	assert.Equal(t, int64(0), line.Offset)
+
+	// The next entry starts a new line.
+	line, complete = d.decode(eye.Line{Path: a, Text: `{"log":"next\n","stream":"stdout","time":"2020-01-02T03:04:09Z"}`})
+	assert.True(t, complete)
+	assert.Equal(t, "next", line.Text)
+}
+
+func TestGetHandlerDockerJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	w := watch{
+		DockerJSON:  true,
+		LinePattern: "^ERROR",
+		Format:      "json",
+		Out:         filepath.Join(dir, "out.log"),
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, nil, w)
+	assert.Nil(t, handler(eye.Line{Text: `{"log":"INFO fine\n","stream":"stdout","time":"2020-01-02T03:04:05Z"}`}))
+	assert.Nil(t, handler(eye.Line{Text: `{"log":"ERROR bo","stream":"stderr","time":"2020-01-02T03:04:06Z"}`}))
+	assert.Nil(t, handler(eye.Line{Text: `{"log":"om\n","stream":"stderr","time":"2020-01-02T03:04:07Z"}`}))
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "out.log"))
+	assert.Nil(t, err)
+
+	records := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Equal(t, 1, len(records))
+
+	var record map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(records[0]), &record))
+	assert.Equal(t, "ERROR boom", record["text"])
+	assert.Equal(t, "stderr", record["stream"])
+	assert.Equal(t, "2020-01-02T03:04:06Z", record["time"])
+}
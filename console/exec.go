@@ -0,0 +1,314 @@
+package console
+
+import (
+	"../eye"
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Defaults of the commands run for matched lines.
+const (
+	defaultExecTimeout = 10 * time.Second
+	defaultExecWorkers = 2
+	defaultExecRate    = 60
+	execQueueSize      = 100
+)
+
+// execRunner runs the Exec command of a watch for its matched lines, on a few
+// workers, with a timeout and a limit on how many commands start per minute.
+// The command is not run through a shell: it is split into arguments by
+// splitCommand, and each argument is a template receiving the text, path and
+// desc of the line, so that {{.text}} is always passed as a single argument.
+// The text is also written to the standard input of the command.
+type execRunner struct {
+	args    []*template.Template
+	timeout time.Duration
+	jobs    chan eye.Line
+	desc    string
+
+	mutex       sync.Mutex
+	rate        int
+	window      time.Duration
+	windowStart time.Time
+	started     int
+	dropped     int
+	closed      bool
+	now         func() time.Time
+}
+
+// execRunners are the runners started, closed on shutdown.
+var (
+	execRunners      []*execRunner
+	execRunnersMutex sync.Mutex
+)
+
+// splitCommand splits an Exec command into its arguments at the spaces outside
+// quotes, as a shell would. Single quotes keep what they enclose as is, double
+// quotes too but for \" and \\, and a backslash outside quotes escapes the
+// next character. Template actions such as {{ .text }} are kept whole, with
+// their spaces and quotes.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var arg bytes.Buffer
+	inArg := false
+
+	// action copies the template action starting at i, returning where it
+	// ends.
+	action := func(i int) (int, error) {
+		end := strings.Index(command[i:], "}}")
+		if end < 0 {
+			return 0, errors.New("unterminated template action in " + command)
+		}
+		arg.WriteString(command[i : i+end+2])
+
+		return i + end + 1, nil
+	}
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case strings.HasPrefix(command[i:], "{{"):
+			end, err := action(i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			inArg = true
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated quote in " + command)
+			}
+			arg.WriteString(command[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(command) && command[i] != '"'; i++ {
+				switch {
+				case strings.HasPrefix(command[i:], "{{"):
+					end, err := action(i)
+					if err != nil {
+						return nil, err
+					}
+					i = end
+				case command[i] == '\\' && i+1 < len(command) && (command[i+1] == '"' || command[i+1] == '\\'):
+					i++
+					arg.WriteByte(command[i])
+				default:
+					arg.WriteByte(command[i])
+				}
+			}
+			if i == len(command) {
+				return nil, errors.New("unterminated quote in " + command)
+			}
+			inArg = true
+		case c == '\\' && i+1 < len(command):
+			i++
+			arg.WriteByte(command[i])
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+// newExecRunner parses the Exec command of a watch and starts its workers.
+func newExecRunner(w watch) (*execRunner, error) {
+	fields, err := splitCommand(w.Exec)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []*template.Template
+	for i, field := range fields {
+		arg, err := template.New("exec" + strconv.Itoa(i)).Option("missingkey=error").Parse(field)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+
+	r := &execRunner{
+		args:    args,
+		timeout: w.ExecTimeout.Duration,
+		jobs:    make(chan eye.Line, execQueueSize),
+		desc:    w.Desc,
+		rate:    w.ExecRate,
+		window:  time.Minute,
+		now:     time.Now,
+	}
+
+	if r.timeout <= 0 {
+		r.timeout = defaultExecTimeout
+	}
+
+	if r.rate == 0 {
+		r.rate = defaultExecRate
+	}
+
+	workers := w.ExecWorkers
+	if workers <= 0 {
+		workers = defaultExecWorkers
+	}
+
+	for i := 0; i < workers; i++ {
+		go r.work()
+	}
+
+	execRunnersMutex.Lock()
+	execRunners = append(execRunners, r)
+	execRunnersMutex.Unlock()
+
+	return r, nil
+}
+
+// submit queues the command of a line, unless the runner is closed, the rate
+// limit was reached or the queue is full, in which case the line is dropped.
+func (r *execRunner) submit(line eye.Line) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed || !r.allow() {
+		return
+	}
+
+	select {
+	case r.jobs <- line:
+	default:
+		logger.Warnln("Exec queue of " + r.desc + " is full, dropping a line")
+	}
+}
+
+// allow reports whether another command may start in the current window.
+// Negative rates disable the limit. Dropped lines are logged once per window.
+// The caller must hold the mutex.
+func (r *execRunner) allow() bool {
+	if r.rate < 0 {
+		return true
+	}
+
+	now := r.now()
+	if now.Sub(r.windowStart) >= r.window {
+		if r.dropped > 0 {
+			logger.Warnln("Exec rate limit of " + r.desc + " dropped " + strconv.Itoa(r.dropped) + " lines")
+		}
+
+		r.windowStart = now
+		r.started = 0
+		r.dropped = 0
+	}
+
+	if r.started >= r.rate {
+		r.dropped++
+		return false
+	}
+
+	r.started++
+
+	return true
+}
+
+// work runs the commands of queued lines, until the runner is closed. The
+// lines still queued then are dropped.
+func (r *execRunner) work() {
+	for line := range r.jobs {
+		if r.isClosed() {
+			continue
+		}
+		r.run(line)
+	}
+}
+
+// isClosed reports whether the runner was closed.
+func (r *execRunner) isClosed() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.closed
+}
+
+// close stops the workers, which finish the commands they are running,
+// dropping the lines still queued and those submitted afterwards.
+func (r *execRunner) close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.closed {
+		r.closed = true
+		close(r.jobs)
+	}
+}
+
+// stopExecRunners closes every runner, on shutdown.
+func stopExecRunners() {
+	execRunnersMutex.Lock()
+	defer execRunnersMutex.Unlock()
+
+	for _, r := range execRunners {
+		r.close()
+	}
+	execRunners = nil
+}
+
+// run runs the command of a line, logging its failures and whatever it wrote
+// to its standard error.
+func (r *execRunner) run(line eye.Line) {
+	data := map[string]string{
+		"text": line.Text,
+		"path": line.Path,
+		"desc": r.desc,
+	}
+
+	args := make([]string, 0, len(r.args))
+	for _, arg := range r.args {
+		var b bytes.Buffer
+		if err := arg.Execute(&b, data); err != nil {
+			logger.Errorln(err)
+			return
+		}
+		args = append(args, b.String())
+	}
+
+	if len(args) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(line.Text + "\n")
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	if stderr.Len() > 0 {
+		logger.Warnln("Exec " + args[0] + " wrote to stderr: " + strings.TrimSpace(stderr.String()))
+	}
+
+	if err != nil {
+		logger.Errorln("Exec " + args[0] + " failed: " + err.Error())
+	}
+}
@@ -0,0 +1,27 @@
+package console
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCommand(t *testing.T) {
+	for command, expected := range map[string][]string{
+		"notify-send {{.text}}":                  {"notify-send", "{{.text}}"},
+		"notify-send  {{ .text }} \t{{.path}}":   {"notify-send", "{{ .text }}", "{{.path}}"},
+		`mail -s "sauron alert" ops@example.com`: {"mail", "-s", "sauron alert", "ops@example.com"},
+		`echo 'it''s {{ .desc }}' "a \"b\" \\c"`: {"echo", "its {{ .desc }}", `a "b" \c`},
+		`echo "{{ printf "%s!" .text }}" a\ b`:   {"echo", `{{ printf "%s!" .text }}`, "a b"},
+		"":                                       nil,
+	} {
+		args, err := splitCommand(command)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, args, command)
+	}
+
+	for _, command := range []string{`echo "open`, "echo 'open", "echo {{ .text"} {
+		_, err := splitCommand(command)
+		assert.NotNil(t, err, command)
+	}
+}
@@ -0,0 +1,128 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import (
+	"../eye"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeExecScript(t *testing.T, dir string) (string, string) {
+	out := filepath.Join(dir, "out.txt")
+	script := filepath.Join(dir, "record.sh")
+	err := ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$1\" >> "+out+"\n"), 0755)
+	assert.Nil(t, err)
+
+	return script, out
+}
+
+func readExecRuns(out string) []string {
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestExecRunnerArgument(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	script, out := writeExecScript(t, dir)
+
+	r, err := newExecRunner(watch{Exec: script + " {{.text}}", Desc: "exec"})
+	assert.Nil(t, err)
+
+	r.submit(eye.Line{Path: "a.log", Text: "error; rm -rf $HOME"})
+
+	assert.Eventually(t, func() bool {
+		return len(readExecRuns(out)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"error; rm -rf $HOME"}, readExecRuns(out))
+}
+
+func TestExecRunnerQuotedArgument(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	script, out := writeExecScript(t, dir)
+
+	r, err := newExecRunner(watch{Exec: script + ` "{{ .desc }}: {{ .text }}"`, Desc: "exec"})
+	assert.Nil(t, err)
+	defer r.close()
+
+	r.submit(eye.Line{Text: "disk full"})
+
+	assert.Eventually(t, func() bool {
+		return len(readExecRuns(out)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"exec: disk full"}, readExecRuns(out))
+}
+
+func TestExecRunnerClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	script, out := writeExecScript(t, dir)
+
+	r, err := newExecRunner(watch{Exec: script + " {{.text}}", Desc: "exec"})
+	assert.Nil(t, err)
+
+	stopExecRunners()
+	assert.Empty(t, execRunners)
+
+	// Lines submitted once closed are dropped.
+	r.submit(eye.Line{Text: "late"})
+	r.close()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Nil(t, readExecRuns(out))
+}
+
+func TestExecRunnerRateLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	script, out := writeExecScript(t, dir)
+
+	r, err := newExecRunner(watch{Exec: script + " {{.text}}", ExecRate: 2, ExecWorkers: 1, Desc: "exec"})
+	assert.Nil(t, err)
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	for _, text := range []string{"a", "b", "c", "d", "e"} {
+		r.submit(eye.Line{Text: text})
+	}
+
+	now = now.Add(time.Minute)
+	r.submit(eye.Line{Text: "f"})
+
+	assert.Eventually(t, func() bool {
+		return len(readExecRuns(out)) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, []string{"a", "b", "f"}, readExecRuns(out))
+}
+
+func TestExecRunnerTimeout(t *testing.T) {
+	r, err := newExecRunner(watch{Exec: "sleep 10", ExecTimeout: duration{50 * time.Millisecond}})
+	assert.Nil(t, err)
+
+	start := time.Now()
+	r.run(eye.Line{Text: "slow"})
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
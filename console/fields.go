@@ -0,0 +1,142 @@
+package console
+
+import (
+	"bytes"
+	"encoding/csv"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// fieldFormatter rewrites lines as the OutputFields of a watch, the named
+// groups of its LinePattern, joined by its OutputDelimiter.
+type fieldFormatter struct {
+	reg       *regexp.Regexp
+	groups    [][]int
+	delimiter string
+}
+
+// newFieldFormatter builds the formatter of a watch, or returns nil when it
+// has no OutputFields. Fields naming no group of the pattern stay empty.
+func newFieldFormatter(w watch) *fieldFormatter {
+	if len(w.OutputFields) == 0 {
+		return nil
+	}
+
+	f := &fieldFormatter{delimiter: w.OutputDelimiter}
+	if f.delimiter == "" {
+		f.delimiter = "\t"
+	}
+
+	f.reg = compilePattern(anyPattern(w.LinePattern, w.LinePatterns))
+
+	// A name may be given to a group of every alternative pattern.
+	f.groups = make([][]int, len(w.OutputFields))
+	for i, field := range w.OutputFields {
+		if f.reg != nil {
+			for j, name := range f.reg.SubexpNames() {
+				if name == field {
+					f.groups[i] = append(f.groups[i], j)
+				}
+			}
+		}
+
+		if len(f.groups[i]) == 0 {
+			logger.Warnln("Output field " + field + " is no group of the line pattern, leaving it empty")
+		}
+	}
+
+	return f
+}
+
+// format returns the fields of text joined by the delimiter. Groups which
+// didn't match give empty fields. Single character delimiters are written
+// as CSV, quoting the fields which contain them.
+func (f *fieldFormatter) format(text string) string {
+	var match []string
+	if f.reg != nil {
+		match = f.reg.FindStringSubmatch(text)
+	}
+
+	values := make([]string, len(f.groups))
+	for i, groups := range f.groups {
+		for _, j := range groups {
+			if match != nil && match[j] != "" {
+				values[i] = match[j]
+				break
+			}
+		}
+	}
+
+	if utf8.RuneCountInString(f.delimiter) != 1 {
+		return strings.Join(values, f.delimiter)
+	}
+
+	var b bytes.Buffer
+	writer := csv.NewWriter(&b)
+	writer.Comma, _ = utf8.DecodeRuneInString(f.delimiter)
+
+	if err := writer.Write(values); err != nil {
+		logger.Errorln(err)
+		return strings.Join(values, f.delimiter)
+	}
+	writer.Flush()
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// groupExtractor extracts the named groups of the LinePattern of a watch,
+// written as the fields of its json lines with JSONFields.
+type groupExtractor struct {
+	reg    *regexp.Regexp
+	groups map[string][]int
+}
+
+// newGroupExtractor builds the extractor of a watch, or returns nil unless it
+// writes json lines with JSONFields and its pattern names groups.
+func newGroupExtractor(w watch) *groupExtractor {
+	if !w.JSONFields || w.Format != "json" {
+		return nil
+	}
+
+	reg := compilePattern(anyPattern(w.LinePattern, w.LinePatterns))
+	if reg == nil {
+		return nil
+	}
+
+	// A name may be given to a group of every alternative pattern.
+	g := &groupExtractor{reg: reg, groups: make(map[string][]int)}
+	for i, name := range reg.SubexpNames() {
+		if name != "" {
+			g.groups[name] = append(g.groups[name], i)
+		}
+	}
+
+	if len(g.groups) == 0 {
+		logger.Warnln("Line pattern has no named group, writing no fields")
+		return nil
+	}
+
+	return g
+}
+
+// extract returns the named groups of text which matched, or nil when text
+// doesn't match the pattern.
+func (g *groupExtractor) extract(text string) map[string]string {
+	match := g.reg.FindStringSubmatch(text)
+	if match == nil {
+		return nil
+	}
+
+	fields := make(map[string]string)
+	for name, groups := range g.groups {
+		for _, i := range groups {
+			if match[i] != "" {
+				fields[name] = match[i]
+				break
+			}
+		}
+	}
+
+	return fields
+}
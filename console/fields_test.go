@@ -0,0 +1,120 @@
+package console
+
+import (
+	"../eye"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const accessPattern = `^(?P<ip>\S+) "(?P<request>[^"]*)" (?P<status>\d+)(?: (?P<agent>.+))?$`
+
+func TestFieldFormatterTSV(t *testing.T) {
+	f := newFieldFormatter(watch{
+		LinePattern:  accessPattern,
+		OutputFields: []string{"status", "ip", "agent", "request"},
+	})
+
+	assert.Equal(t, "200\t10.0.0.1\tcurl\tGET /", f.format(`10.0.0.1 "GET /" 200 curl`))
+
+	// Missing groups give empty fields, values with the delimiter are quoted.
+	assert.Equal(t, "404\t10.0.0.2\t\t\"GET /a\tb\"", f.format("10.0.0.2 \"GET /a\tb\" 404"))
+}
+
+func TestFieldFormatterCSV(t *testing.T) {
+	f := newFieldFormatter(watch{
+		LinePattern:     accessPattern,
+		OutputFields:    []string{"ip", "request", "agent", "unknown"},
+		OutputDelimiter: ",",
+	})
+
+	assert.Equal(t, `10.0.0.1,"GET /?a=1,b=2","Mozilla ""compatible""",`, f.format(`10.0.0.1 "GET /?a=1,b=2" 200 Mozilla "compatible"`))
+	assert.Equal(t, ",,,", f.format("no match"))
+}
+
+func TestFieldFormatterDelimiter(t *testing.T) {
+	f := newFieldFormatter(watch{
+		LinePatterns:    []string{`^ERROR (?P<code>\d+)`, `^WARN (?P<code>\w+)`},
+		OutputFields:    []string{"code", "code"},
+		OutputDelimiter: " | ",
+	})
+
+	assert.Equal(t, "42 | 42", f.format("ERROR 42"))
+	assert.Equal(t, "disk | disk", f.format("WARN disk"))
+	assert.Nil(t, newFieldFormatter(watch{LinePattern: accessPattern}))
+}
+
+func TestGetHandlerOutputFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	w := watch{
+		LinePattern:     accessPattern,
+		OutputFields:    []string{"ip", "status"},
+		OutputDelimiter: ",",
+		Out:             filepath.Join(dir, "out.csv"),
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, nil, w)
+	assert.Nil(t, handler(eye.Line{Text: `10.0.0.1 "GET /" 200`}))
+	assert.Nil(t, handler(eye.Line{Text: "not an access line"}))
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "out.csv"))
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1,200\n", string(out))
+}
+
+func TestGroupExtractor(t *testing.T) {
+	g := newGroupExtractor(watch{LinePattern: accessPattern, Format: "json", JSONFields: true})
+
+	assert.Equal(t, map[string]string{"ip": "10.0.0.1", "request": "GET /", "status": "200"}, g.extract(`10.0.0.1 "GET /" 200`))
+	assert.Nil(t, g.extract("no match"))
+
+	assert.Nil(t, newGroupExtractor(watch{LinePattern: accessPattern, JSONFields: true}))
+	assert.Nil(t, newGroupExtractor(watch{LinePattern: "^ERROR", Format: "json", JSONFields: true}))
+}
+
+func TestGetHandlerJSONFields(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	w := watch{
+		LinePattern: accessPattern,
+		Format:      "json",
+		JSONFields:  true,
+		Out:         filepath.Join(dir, "out.json"),
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, nil, w)
+	assert.Nil(t, handler(eye.Line{Path: "/var/log/access.log", Text: `10.0.0.1 "GET /" 200 curl`}))
+	assert.Nil(t, handler(eye.Line{Path: "/var/log/access.log", Text: "not an access line"}))
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "out.json"))
+	assert.Nil(t, err)
+
+	var record map[string]interface{}
+	assert.Nil(t, json.Unmarshal(out, &record))
+	assert.Equal(t, "/var/log/access.log", record["path"])
+	assert.Equal(t, `10.0.0.1 "GET /" 200 curl`, record["text"])
+	assert.Contains(t, record, "time")
+	assert.Equal(t, map[string]interface{}{
+		"ip":      "10.0.0.1",
+		"request": "GET /",
+		"status":  "200",
+		"agent":   "curl",
+	}, record["fields"])
+}
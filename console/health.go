@@ -0,0 +1,65 @@
+package console
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// health reports whether Sauron is running its trails, for liveness and
+// readiness probes.
+type health struct {
+	mutex    sync.Mutex
+	trails   int
+	stopping bool
+}
+
+var status = &health{}
+
+// add records trails that started following.
+func (h *health) add(n int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.trails += n
+}
+
+// stop marks the beginning of the shutdown.
+func (h *health) stop() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.stopping = true
+}
+
+// ServeHTTP answers ok with the number of active trails, or 503 once the
+// shutdown began.
+func (h *health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.Lock()
+	trails, stopping := h.trails, h.stopping
+	h.mutex.Unlock()
+
+	if stopping {
+		http.Error(w, "stopping", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok " + strconv.Itoa(trails) + " trails\n"))
+}
+
+// serveHealth exposes the health check on /healthz, along with the recent
+// lines of every watch on /tail and the counters and latencies on /metrics, at
+// addr in the background.
+func serveHealth(addr string, h *health) {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", h)
+	mux.HandleFunc("/tail", serveRecent)
+	mux.HandleFunc("/metrics", serveMetrics)
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Errorln("Health check server failed: " + err.Error())
+		}
+	}()
+}
@@ -0,0 +1,39 @@
+package console
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	h := &health{}
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	get := func() (int, string) {
+		resp, err := http.Get(server.URL + "/healthz")
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.Nil(t, err)
+
+		return resp.StatusCode, string(body)
+	}
+
+	h.add(2)
+
+	code, body := get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok 2 trails\n", body)
+
+	h.stop()
+
+	code, body = get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "stopping\n", body)
+}
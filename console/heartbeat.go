@@ -0,0 +1,85 @@
+package console
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultHeartbeatText is the text of heartbeat lines of watches which don't
+// set HeartbeatText.
+const defaultHeartbeatText = "heartbeat"
+
+// heartbeat emits a line whenever a watch wrote nothing for its interval, for
+// monitors alerting on silence. Written lines reset it.
+type heartbeat struct {
+	interval time.Duration
+	emit     func(now time.Time)
+	stop     chan bool
+
+	mutex sync.Mutex
+	last  time.Time
+}
+
+var (
+	heartbeats      []*heartbeat
+	heartbeatsMutex sync.Mutex
+)
+
+// startHeartbeat starts emitting heartbeats every idle interval.
+func startHeartbeat(interval time.Duration, emit func(now time.Time)) *heartbeat {
+	h := &heartbeat{
+		interval: interval,
+		emit:     emit,
+		stop:     make(chan bool),
+		last:     time.Now(),
+	}
+
+	heartbeatsMutex.Lock()
+	heartbeats = append(heartbeats, h)
+	heartbeatsMutex.Unlock()
+
+	go h.run()
+
+	return h
+}
+
+// touch records activity, postponing the next heartbeat.
+func (h *heartbeat) touch() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.last = time.Now()
+}
+
+// run emits heartbeats until stopped.
+func (h *heartbeat) run() {
+	for {
+		h.mutex.Lock()
+		wait := h.interval - time.Since(h.last)
+		h.mutex.Unlock()
+
+		if wait <= 0 {
+			now := time.Now()
+			h.emit(now)
+			h.touch()
+			wait = h.interval
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-h.stop:
+			return
+		}
+	}
+}
+
+// stopHeartbeats stops every heartbeat, before the outputs are closed.
+func stopHeartbeats() {
+	heartbeatsMutex.Lock()
+	defer heartbeatsMutex.Unlock()
+
+	for _, h := range heartbeats {
+		close(h.stop)
+	}
+	heartbeats = nil
+}
@@ -0,0 +1,58 @@
+package console
+
+import (
+	"../eye"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+	defer stopHeartbeats()
+
+	out := filepath.Join(dir, "out.log")
+	w := watch{
+		LinePattern:       "ERROR",
+		HeartbeatInterval: duration{100 * time.Millisecond},
+		HeartbeatText:     "still here",
+		Out:               out,
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	count := func(text string) int {
+		data, _ := ioutil.ReadFile(out)
+		return strings.Count(string(data), text)
+	}
+
+	// Lines written more often than the interval hold heartbeats back, while
+	// lines which aren't written don't.
+	handler := getHandler(newTestContext(), routes, nil, w)
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, handler(eye.Line{Text: "ERROR busy"}))
+		time.Sleep(30 * time.Millisecond)
+	}
+	assert.Equal(t, 0, count("still here"))
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, handler(eye.Line{Text: "INFO filtered out"}))
+		time.Sleep(30 * time.Millisecond)
+	}
+	assert.True(t, count("still here") >= 1)
+
+	stopHeartbeats()
+	idle := count("still here")
+	time.Sleep(250 * time.Millisecond)
+	assert.Equal(t, idle, count("still here"))
+	assert.Equal(t, 10, count("ERROR busy"))
+}
@@ -0,0 +1,357 @@
+package console
+
+import (
+	"../eye"
+	"bytes"
+	"context"
+	"errors"
+	kafkago "github.com/segmentio/kafka-go"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Delays between attempts to produce to Kafka after a failure. They start at
+// kafkaRetryDelay and double up to kafkaMaxRetryDelay.
+var (
+	kafkaRetryDelay    = time.Second
+	kafkaMaxRetryDelay = 30 * time.Second
+)
+
+// kafkaTimeout bounds the time spent waiting for the brokers to answer.
+var kafkaTimeout = 10 * time.Second
+
+// Defaults of the producer settings of watches.
+const (
+	defaultKafkaBatchSize     = 100
+	defaultKafkaFlushInterval = time.Second
+	defaultKafkaQueueSize     = 10000
+)
+
+// Keys partitioning the messages of Kafka outputs.
+const (
+	kafkaKeyPath = "path"
+	kafkaKeyID   = "id"
+	kafkaKeyDesc = "desc"
+)
+
+// kafkaProducer produces lines to a Kafka topic, in batches of batchSize lines
+// or every interval, whichever comes first. Lines are queued until the
+// brokers acknowledge them, up to queueSize of them, beyond which new lines
+// are dropped, and batches which fail are sent again after a delay, so lines
+// are delivered at least once. What is left of the queue is sent on Close.
+// It is safe for concurrent use.
+type kafkaProducer struct {
+	brokers    []string
+	topic      string
+	retryDelay time.Duration
+
+	mutex       sync.Mutex
+	acks        int16
+	batchSize   int
+	interval    time.Duration
+	queueSize   int
+	queue       []kafkago.Message
+	overflowing bool
+	dropped     uint64
+	sent        uint64
+
+	wake   chan bool
+	closed chan bool
+	done   chan bool
+
+	// Owned by the goroutine sending the batches.
+	writer kafkaWriter
+}
+
+// kafkaWriter produces messages to the partitions of a topic, returning once
+// the brokers acknowledged them, as the Writer of kafka-go does.
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafkago.Message) error
+	Close() error
+}
+
+// newKafkaWriter creates the writer producing batches of up to batchSize
+// messages to a topic, waiting for acks acknowledgements. Messages are
+// partitioned by a hash of their key, or in turn without one. The producer
+// batches and retries on its own, so the writer neither waits for its batches
+// to fill up nor retries.
+var newKafkaWriter = func(brokers []string, topic string, acks int16, batchSize int) kafkaWriter {
+	return &kafkago.Writer{
+		Addr:         kafkago.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafkago.Hash{},
+		RequiredAcks: kafkago.RequiredAcks(acks),
+		BatchSize:    batchSize,
+		BatchTimeout: time.Millisecond,
+		MaxAttempts:  1,
+		ReadTimeout:  kafkaTimeout,
+		WriteTimeout: kafkaTimeout,
+	}
+}
+
+// isKafkaOut reports whether an output names a Kafka topic, such as
+// kafka://broker:9092/topic.
+func isKafkaOut(path string) bool {
+	return strings.HasPrefix(path, "kafka://")
+}
+
+// parseKafkaOut returns the brokers and the topic of a Kafka output. Several
+// brokers may be listed, separated by commas, to bootstrap from.
+func parseKafkaOut(path string) ([]string, string, error) {
+	rest := strings.TrimPrefix(path, "kafka://")
+
+	i := strings.Index(rest, "/")
+	if i <= 0 || i == len(rest)-1 {
+		return nil, "", errors.New("invalid kafka output " + path + ", expected kafka://broker:port/topic")
+	}
+
+	return strings.Split(rest[:i], ","), rest[i+1:], nil
+}
+
+// newKafkaProducer creates the producer of a Kafka output with the default
+// settings. Brokers are only connected to once there are lines to send.
+func newKafkaProducer(path string) (*kafkaProducer, error) {
+	brokers, topic, err := parseKafkaOut(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &kafkaProducer{
+		brokers:    brokers,
+		topic:      topic,
+		retryDelay: kafkaRetryDelay,
+		acks:       1,
+		batchSize:  defaultKafkaBatchSize,
+		interval:   defaultKafkaFlushInterval,
+		queueSize:  defaultKafkaQueueSize,
+		wake:       make(chan bool, 1),
+		closed:     make(chan bool),
+		done:       make(chan bool),
+	}
+
+	go p.run()
+
+	return p, nil
+}
+
+// setOutKafka applies the Kafka settings of a watch to one of its outputs, if
+// it produces to Kafka. Outputs are shared, so the settings of the first watch
+// opening one win.
+func setOutKafka(out *output, w watch) {
+	p, ok := out.sink.(*kafkaProducer)
+	if !ok || w.Kafka == nil {
+		return
+	}
+
+	switch w.Kafka.Key {
+	case "", kafkaKeyPath, kafkaKeyID, kafkaKeyDesc:
+	default:
+		logger.Errorln("Unknown kafka key " + w.Kafka.Key + ", spreading messages evenly instead")
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	switch w.Kafka.Acks {
+	case "", "leader":
+		p.acks = 1
+	case "all":
+		p.acks = -1
+	case "none":
+		p.acks = 0
+	default:
+		logger.Errorln("Unknown kafka acks " + w.Kafka.Acks + ", waiting for the leader instead")
+	}
+
+	if w.Kafka.BatchSize > 0 {
+		p.batchSize = w.Kafka.BatchSize
+	}
+	if w.Kafka.FlushInterval.Duration > 0 {
+		p.interval = w.Kafka.FlushInterval.Duration
+	}
+	if w.Kafka.QueueSize > 0 {
+		p.queueSize = w.Kafka.QueueSize
+	}
+}
+
+// kafkaKey returns the key partitioning a line of a watch on Kafka outputs,
+// as its Kafka Key says, or an empty key to spread lines evenly.
+func kafkaKey(w watch, line eye.Line) string {
+	if w.Kafka == nil {
+		return ""
+	}
+
+	switch w.Kafka.Key {
+	case kafkaKeyPath:
+		return line.Path
+	case kafkaKeyID:
+		return w.ID
+	case kafkaKeyDesc:
+		return w.Desc
+	}
+
+	return ""
+}
+
+// writeKeyed writes a record to an output like write, producing it with key
+// on Kafka outputs. Kafka outputs queue what they fail to send, so they don't
+// go through the breaker of the output.
+func writeKeyed(buf *bytes.Buffer, key string, out *output) {
+	if p, ok := out.sink.(*kafkaProducer); ok {
+		p.produce(key, buf.String())
+		return
+	}
+
+	write(buf, out)
+}
+
+// Write queues a line without a key, for the lines written without one, such
+// as heartbeats.
+func (p *kafkaProducer) Write(b []byte) (int, error) {
+	p.produce("", string(b))
+
+	return len(b), nil
+}
+
+// produce queues a message, dropping it when the queue is full.
+func (p *kafkaProducer) produce(key, value string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.queue) >= p.queueSize {
+		if !p.overflowing {
+			logger.Warnln("Kafka queue of " + p.topic + " is full, dropping lines until it drains")
+			p.overflowing = true
+		}
+		atomic.AddUint64(&p.dropped, 1)
+		return
+	}
+
+	m := kafkago.Message{
+		Value: []byte(strings.TrimRight(value, "\r\n\x00")),
+		Time:  time.Now(),
+	}
+	if key != "" {
+		m.Key = []byte(key)
+	}
+
+	p.overflowing = false
+	p.queue = append(p.queue, m)
+
+	if len(p.queue) >= p.batchSize {
+		select {
+		case p.wake <- true:
+		default:
+		}
+	}
+}
+
+// run sends the queued messages as batches fill up or every interval, until
+// the producer is closed, retrying with a growing delay when sending fails.
+func (p *kafkaProducer) run() {
+	defer close(p.done)
+
+	delay := p.retryDelay
+	for {
+		p.mutex.Lock()
+		interval := p.interval
+		p.mutex.Unlock()
+
+		select {
+		case <-p.wake:
+		case <-time.After(interval):
+		case <-p.closed:
+			if err := p.send(); err != nil {
+				logger.Errorln("Failed to produce the last lines to " + p.topic + ": " + err.Error())
+			}
+			p.disconnect()
+			return
+		}
+
+		if err := p.send(); err != nil {
+			logger.Errorln("Failed to produce to " + p.topic + ": " + err.Error() + ". Retrying in " + delay.String())
+			p.disconnect()
+
+			select {
+			case <-time.After(delay):
+			case <-p.closed:
+			}
+
+			if delay *= 2; delay > kafkaMaxRetryDelay {
+				delay = kafkaMaxRetryDelay
+			}
+			continue
+		}
+
+		delay = p.retryDelay
+	}
+}
+
+// send sends the queued messages in batches, removing them from the queue as
+// they are sent.
+func (p *kafkaProducer) send() error {
+	for {
+		p.mutex.Lock()
+		n := len(p.queue)
+		if n > p.batchSize {
+			n = p.batchSize
+		}
+		batch := append([]kafkago.Message(nil), p.queue[:n]...)
+		acks := p.acks
+		batchSize := p.batchSize
+		p.mutex.Unlock()
+
+		if n == 0 {
+			return nil
+		}
+
+		if p.writer == nil {
+			p.writer = newKafkaWriter(p.brokers, p.topic, acks, batchSize)
+		}
+
+		if err := p.writer.WriteMessages(context.Background(), batch...); err != nil {
+			return err
+		}
+
+		p.mutex.Lock()
+		p.queue = p.queue[n:]
+		p.mutex.Unlock()
+
+		atomic.AddUint64(&p.sent, 1)
+	}
+}
+
+// disconnect closes the writer, so that the next attempt connects to the
+// brokers and looks the partitions of the topic up again.
+func (p *kafkaProducer) disconnect() {
+	if p.writer != nil {
+		p.writer.Close()
+		p.writer = nil
+	}
+}
+
+// Close sends what is left of the queue and stops the producer. Lines still
+// queued once the brokers fail are lost.
+func (p *kafkaProducer) Close() error {
+	p.mutex.Lock()
+	select {
+	case <-p.closed:
+	default:
+		close(p.closed)
+	}
+	p.mutex.Unlock()
+
+	<-p.done
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.queue) > 0 {
+		return errors.New("kafka topic " + p.topic + ": " + strconv.Itoa(len(p.queue)) + " lines lost")
+	}
+
+	return nil
+}
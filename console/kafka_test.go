@@ -0,0 +1,245 @@
+package console
+
+import (
+	"../eye"
+	"context"
+	"errors"
+	kafkago "github.com/segmentio/kafka-go"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKafka stands in for the brokers of the Kafka producers, whose writers
+// pass the messages they write to messages. Writes fail as long as failures
+// is positive, decreasing it, or always when it is negative.
+type fakeKafka struct {
+	writers  chan *fakeKafkaWriter
+	messages chan kafkago.Message
+
+	mutex    sync.Mutex
+	failures int
+}
+
+// fakeKafkaWriter is a kafkaWriter writing to a fakeKafka.
+type fakeKafkaWriter struct {
+	kafka     *fakeKafka
+	brokers   []string
+	topic     string
+	acks      int16
+	batchSize int
+	messages  chan kafkago.Message
+
+	closed int32
+}
+
+func (w *fakeKafkaWriter) WriteMessages(ctx context.Context, messages ...kafkago.Message) error {
+	w.kafka.mutex.Lock()
+	if w.kafka.failures != 0 {
+		w.kafka.failures--
+		w.kafka.mutex.Unlock()
+		return errors.New("leader not available")
+	}
+	w.kafka.mutex.Unlock()
+
+	for _, m := range messages {
+		w.messages <- m
+	}
+
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	atomic.AddInt32(&w.closed, 1)
+
+	return nil
+}
+
+// withFakeKafka makes the Kafka producers write to a fakeKafka failing
+// failures times, returning its writers as they are created.
+func withFakeKafka(failures int) (chan *fakeKafkaWriter, func()) {
+	fake := &fakeKafka{
+		writers:  make(chan *fakeKafkaWriter, 16),
+		messages: make(chan kafkago.Message, 16),
+		failures: failures,
+	}
+
+	create := newKafkaWriter
+	newKafkaWriter = func(brokers []string, topic string, acks int16, batchSize int) kafkaWriter {
+		w := &fakeKafkaWriter{
+			kafka:     fake,
+			brokers:   brokers,
+			topic:     topic,
+			acks:      acks,
+			batchSize: batchSize,
+			messages:  fake.messages,
+		}
+		fake.writers <- w
+
+		return w
+	}
+
+	return fake.writers, func() { newKafkaWriter = create }
+}
+
+func receiveWriter(t *testing.T, writers chan *fakeKafkaWriter) *fakeKafkaWriter {
+	select {
+	case w := <-writers:
+		return w
+	case <-time.After(5 * time.Second):
+		t.Fatal("kafka writer not created")
+		return nil
+	}
+}
+
+func receiveMessage(t *testing.T, w *fakeKafkaWriter) kafkago.Message {
+	select {
+	case m := <-w.messages:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not produced")
+		return kafkago.Message{}
+	}
+}
+
+func TestParseKafkaOut(t *testing.T) {
+	brokers, topic, err := parseKafkaOut("kafka://a:9092,b:9092/logs")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a:9092", "b:9092"}, brokers)
+	assert.Equal(t, "logs", topic)
+
+	for _, invalid := range []string{"kafka://a:9092", "kafka://a:9092/", "kafka:///logs"} {
+		_, _, err := parseKafkaOut(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestNewKafkaWriter(t *testing.T) {
+	w, ok := newKafkaWriter([]string{"a:9092", "b:9092"}, "logs", -1, 50).(*kafkago.Writer)
+	assert.True(t, ok)
+	assert.Equal(t, "logs", w.Topic)
+	assert.Equal(t, kafkago.RequireAll, w.RequiredAcks)
+	assert.Equal(t, 50, w.BatchSize)
+	assert.Equal(t, 1, w.MaxAttempts)
+	assert.Equal(t, "a:9092,b:9092", w.Addr.String())
+
+	// Keys are hashed, as the partitioning of other clients does.
+	assert.IsType(t, &kafkago.Hash{}, w.Balancer)
+}
+
+func TestGetHandlerKafka(t *testing.T) {
+	defer closeOutputs()
+
+	writers, restore := withFakeKafka(0)
+	defer restore()
+
+	w := watch{
+		Out:   "kafka://localhost:9092/logs",
+		Kafka: &kafka{Key: "path", FlushInterval: duration{10 * time.Millisecond}},
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, nil, w)
+	assert.Nil(t, handler(eye.Line{Path: "/var/log/a.log", Text: "first"}))
+	assert.Nil(t, handler(eye.Line{Path: "/var/log/a.log", Text: "second"}))
+
+	writer := receiveWriter(t, writers)
+	assert.Equal(t, []string{"localhost:9092"}, writer.brokers)
+	assert.Equal(t, "logs", writer.topic)
+	assert.Equal(t, int16(1), writer.acks)
+
+	// Lines of a file share their key, and so their partition.
+	first := receiveMessage(t, writer)
+	second := receiveMessage(t, writer)
+	assert.Equal(t, "/var/log/a.log", string(first.Key))
+	assert.Equal(t, "first", string(first.Value))
+	assert.Equal(t, "/var/log/a.log", string(second.Key))
+	assert.Equal(t, "second", string(second.Value))
+
+	recorder := httptest.NewRecorder()
+	serveMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), `sauron_kafka_lines_dropped_total{topic="logs"} 0`)
+}
+
+func TestKafkaProducerWithoutAcks(t *testing.T) {
+	writers, restore := withFakeKafka(0)
+	defer restore()
+
+	p, err := newKafkaProducer("kafka://localhost:9092/logs")
+	assert.Nil(t, err)
+	setOutKafka(&output{sink: p}, watch{Kafka: &kafka{Acks: "none", BatchSize: 2}})
+
+	p.produce("", "first\n")
+	p.produce("", "second\n")
+
+	writer := receiveWriter(t, writers)
+	assert.Equal(t, int16(0), writer.acks)
+	assert.Equal(t, 2, writer.batchSize)
+
+	// Lines without a key are spread over the partitions by the writer.
+	first := receiveMessage(t, writer)
+	assert.Nil(t, first.Key)
+	assert.Equal(t, "first", string(first.Value))
+	assert.Equal(t, "second", string(receiveMessage(t, writer).Value))
+
+	assert.Nil(t, p.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&writer.closed))
+}
+
+func TestKafkaProducerRetries(t *testing.T) {
+	defer func(delay time.Duration) { kafkaRetryDelay = delay }(kafkaRetryDelay)
+	kafkaRetryDelay = time.Millisecond
+
+	// The first attempt fails as the leader moved.
+	writers, restore := withFakeKafka(1)
+	defer restore()
+
+	p, err := newKafkaProducer("kafka://localhost:9092/logs")
+	assert.Nil(t, err)
+	setOutKafka(&output{sink: p}, watch{Kafka: &kafka{FlushInterval: duration{time.Millisecond}}})
+
+	p.produce("key", "line\n")
+
+	// The failed writer is closed, and the line produced by a new one.
+	failed := receiveWriter(t, writers)
+	writer := receiveWriter(t, writers)
+
+	m := receiveMessage(t, writer)
+	assert.Equal(t, "key", string(m.Key))
+	assert.Equal(t, "line", string(m.Value))
+
+	// The line is produced once the retry succeeds, and only then.
+	assert.Nil(t, p.Close())
+	assert.Equal(t, 0, len(writer.messages))
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&failed.closed))
+}
+
+func TestKafkaProducerQueueBounded(t *testing.T) {
+	_, restore := withFakeKafka(-1)
+	defer restore()
+
+	p, err := newKafkaProducer("kafka://localhost:9092/logs")
+	assert.Nil(t, err)
+	setOutKafka(&output{sink: p}, watch{Kafka: &kafka{QueueSize: 2}})
+
+	for i := 0; i < 5; i++ {
+		p.produce("", "line "+strconv.Itoa(i))
+	}
+
+	p.mutex.Lock()
+	assert.Equal(t, []string{"line 0", "line 1"}, []string{string(p.queue[0].Value), string(p.queue[1].Value)})
+	p.mutex.Unlock()
+	assert.Equal(t, uint64(3), atomic.LoadUint64(&p.dropped))
+
+	err = p.Close()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "2 lines lost")
+}
@@ -0,0 +1,84 @@
+package console
+
+import (
+	"../eye"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyTrails are the trails whose handler latency is served on /metrics,
+// along with the description of their watch.
+var (
+	latencyTrails      = map[*eye.Trail]string{}
+	latencyTrailsMutex sync.Mutex
+)
+
+// addLatencyTrail serves the handler latency of a trail on /metrics, summed
+// with those of the other trails of its watch.
+func addLatencyTrail(trail *eye.Trail, desc string) {
+	latencyTrailsMutex.Lock()
+	defer latencyTrailsMutex.Unlock()
+
+	latencyTrails[trail] = desc
+}
+
+// removeLatencyTrail stops serving the handler latency of a trail.
+func removeLatencyTrail(trail *eye.Trail) {
+	latencyTrailsMutex.Lock()
+	defer latencyTrailsMutex.Unlock()
+
+	delete(latencyTrails, trail)
+}
+
+// latencySums is the handler latency of the trails of a watch, summed.
+type latencySums struct {
+	bounds  []time.Duration
+	buckets []uint64
+	count   uint64
+	sum     time.Duration
+}
+
+// writeLatencyMetrics writes the handler latency of the trails of every
+// watch as a Prometheus histogram labeled with their description.
+func writeLatencyMetrics(w io.Writer) {
+	latencyTrailsMutex.Lock()
+	sums := make(map[string]*latencySums)
+	for trail, desc := range latencyTrails {
+		bounds, buckets, count, sum := trail.HandlerLatency().Buckets()
+
+		s, ok := sums[desc]
+		if !ok {
+			s = &latencySums{bounds: bounds, buckets: make([]uint64, len(buckets))}
+			sums[desc] = s
+		}
+
+		for i := range buckets {
+			s.buckets[i] += buckets[i]
+		}
+		s.count += count
+		s.sum += sum
+	}
+	latencyTrailsMutex.Unlock()
+
+	descs := make([]string, 0, len(sums))
+	for desc := range sums {
+		descs = append(descs, desc)
+	}
+	sort.Strings(descs)
+
+	fmt.Fprintln(w, "# TYPE sauron_handler_latency_seconds histogram")
+	for _, desc := range descs {
+		s := sums[desc]
+		for i, bound := range s.bounds {
+			le := strconv.FormatFloat(bound.Seconds(), 'g', -1, 64)
+			fmt.Fprintf(w, "sauron_handler_latency_seconds_bucket{desc=%q,le=%q} %d\n", desc, le, s.buckets[i])
+		}
+		fmt.Fprintf(w, "sauron_handler_latency_seconds_bucket{desc=%q,le=\"+Inf\"} %d\n", desc, s.count)
+		fmt.Fprintf(w, "sauron_handler_latency_seconds_sum{desc=%q} %s\n", desc, strconv.FormatFloat(s.sum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(w, "sauron_handler_latency_seconds_count{desc=%q} %d\n", desc, s.count)
+	}
+}
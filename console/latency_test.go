@@ -0,0 +1,46 @@
+package console
+
+import (
+	"../eye"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeMetricsLatency(t *testing.T) {
+	app := eye.NewTrailWithOptions(nil, &eye.TrailOptions{})
+	app.HandlerLatency().Observe(500 * time.Microsecond)
+	app.HandlerLatency().Observe(2 * time.Second)
+
+	rotated := eye.NewTrailWithOptions(nil, &eye.TrailOptions{})
+	rotated.HandlerLatency().Observe(500 * time.Microsecond)
+
+	addLatencyTrail(app, "app")
+	addLatencyTrail(rotated, "app")
+	defer removeLatencyTrail(app)
+	defer removeLatencyTrail(rotated)
+
+	recorder := httptest.NewRecorder()
+	serveMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := ioutil.ReadAll(recorder.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), "# TYPE sauron_handler_latency_seconds histogram\n")
+	assert.Contains(t, string(body), `sauron_handler_latency_seconds_bucket{desc="app",le="0.001"} 2`+"\n")
+	assert.Contains(t, string(body), `sauron_handler_latency_seconds_bucket{desc="app",le="1"} 2`+"\n")
+	assert.Contains(t, string(body), `sauron_handler_latency_seconds_bucket{desc="app",le="+Inf"} 3`+"\n")
+	assert.Contains(t, string(body), `sauron_handler_latency_seconds_sum{desc="app"} 2.001`+"\n")
+	assert.Contains(t, string(body), `sauron_handler_latency_seconds_count{desc="app"} 3`+"\n")
+
+	removeLatencyTrail(rotated)
+
+	recorder = httptest.NewRecorder()
+	serveMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err = ioutil.ReadAll(recorder.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), `sauron_handler_latency_seconds_count{desc="app"} 2`+"\n")
+}
@@ -0,0 +1,93 @@
+package console
+
+import (
+	"regexp"
+	"strings"
+)
+
+// severities orders the level names found in logs, with their common aliases
+// and abbreviations.
+var severities = map[string]int{
+	"trace":     0,
+	"trc":       0,
+	"debug":     1,
+	"dbg":       1,
+	"info":      2,
+	"inf":       2,
+	"notice":    3,
+	"warn":      4,
+	"warning":   4,
+	"wrn":       4,
+	"error":     5,
+	"err":       5,
+	"critical":  6,
+	"crit":      6,
+	"fatal":     6,
+	"ftl":       6,
+	"alert":     7,
+	"panic":     8,
+	"emerg":     8,
+	"emergency": 8,
+}
+
+// levelFilter suppresses the lines of a watch whose level is below LevelMin.
+// The level is the "level" group of the LevelPattern of the watch, or its
+// whole match, and is compared ignoring case.
+type levelFilter struct {
+	reg      *regexp.Regexp
+	min      int
+	required bool
+}
+
+// newLevelFilter builds the filter of a watch, or returns nil when the watch
+// doesn't set a known LevelMin along with a LevelPattern.
+func newLevelFilter(w watch) *levelFilter {
+	if w.LevelPattern == "" || w.LevelMin == "" {
+		return nil
+	}
+
+	min, ok := severities[strings.ToLower(w.LevelMin)]
+	if !ok {
+		logger.Errorln("Unknown minimum level " + w.LevelMin + ", keeping every line")
+		return nil
+	}
+
+	reg, err := regexp.Compile(w.LevelPattern)
+	if err != nil {
+		logger.Errorln("Invalid level pattern " + w.LevelPattern + ": " + err.Error())
+		return nil
+	}
+
+	return &levelFilter{reg: reg, min: min, required: w.LevelRequired}
+}
+
+// keep reports whether a line is severe enough. Lines without a known level
+// are kept unless levels are required.
+func (f *levelFilter) keep(text string) bool {
+	severity, ok := findLevel(f.reg, text)
+	if !ok {
+		return !f.required
+	}
+
+	return severity >= f.min
+}
+
+// findLevel returns the severity of the level of a line, the "level" group of
+// reg or its whole match, reporting whether a known level was found.
+func findLevel(reg *regexp.Regexp, text string) (int, bool) {
+	match := reg.FindStringSubmatch(text)
+	if match == nil {
+		return 0, false
+	}
+
+	level := match[0]
+	for i, name := range reg.SubexpNames() {
+		if name == "level" {
+			level = match[i]
+		}
+	}
+
+	severity, ok := severities[strings.ToLower(level)]
+
+	return severity, ok
+}
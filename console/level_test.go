@@ -0,0 +1,80 @@
+package console
+
+import (
+	"../eye"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelFilter(t *testing.T) {
+	levels := []string{"TRACE", "DEBUG", "INFO", "NOTICE", "WARN", "ERROR", "CRITICAL", "ALERT", "EMERGENCY"}
+
+	for i, min := range levels {
+		f := newLevelFilter(watch{
+			LevelPattern: `level=(?P<level>\w+)`,
+			LevelMin:     min,
+		})
+		assert.NotNil(t, f, min)
+
+		for j, level := range levels {
+			assert.Equal(t, j >= i, f.keep("level="+level+" msg=x"), min+" "+level)
+		}
+	}
+}
+
+func TestLevelFilterAliases(t *testing.T) {
+	f := newLevelFilter(watch{LevelPattern: `^\w+`, LevelMin: "warn"})
+
+	assert.True(t, f.keep("warning disk almost full"))
+	assert.True(t, f.keep("err disk full"))
+	assert.True(t, f.keep("Fatal out of memory"))
+	assert.True(t, f.keep("panic: nil map"))
+	assert.False(t, f.keep("dbg cache hit"))
+	assert.False(t, f.keep("Info started"))
+}
+
+func TestLevelFilterUnknown(t *testing.T) {
+	w := watch{LevelPattern: `\[(?P<level>\w+)\]`, LevelMin: "info"}
+
+	f := newLevelFilter(w)
+	assert.True(t, f.keep("no level at all"))
+	assert.True(t, f.keep("[verbose] unknown level"))
+
+	w.LevelRequired = true
+	f = newLevelFilter(w)
+	assert.False(t, f.keep("no level at all"))
+	assert.False(t, f.keep("[verbose] unknown level"))
+	assert.True(t, f.keep("[info] known level"))
+
+	assert.Nil(t, newLevelFilter(watch{LevelPattern: `\w+`, LevelMin: "loud"}))
+	assert.Nil(t, newLevelFilter(watch{LevelMin: "info"}))
+}
+
+func TestGetHandlerLevelMin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	w := watch{
+		LevelPattern: `^(?P<level>[A-Z]+) `,
+		LevelMin:     "warn",
+		Out:          filepath.Join(dir, "out.log"),
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, nil, w)
+	for _, text := range []string{"DEBUG one", "INFO two", "WARN three", "ERROR four", "plain five"} {
+		assert.Nil(t, handler(eye.Line{Text: text}))
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "out.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "WARN three\nERROR four\nplain five\n", string(out))
+}
@@ -0,0 +1,82 @@
+package console
+
+import (
+	"../eye"
+	"fmt"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// ListFilesAction is the action of the list-files command, printing every
+// file found under the paths of each watch, along with whether it would be
+// followed and, if not, why. Nothing is followed.
+func ListFilesAction(c *cli.Context) error {
+	conf, result := setConfig(c)
+	if !result {
+		return cli.NewExitError("failed to load the config", 1)
+	}
+
+	listFiles(conf, c.App.Writer)
+
+	return nil
+}
+
+// listFiles writes the table of the files of every watch to out.
+func listFiles(conf Config, out io.Writer) {
+	table := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "WATCH\tFILE\tSTATUS")
+
+	for _, w := range conf.Watch {
+		options := &eye.TrailOptions{Logger: logger}
+		setTrailOptions(options, conf, w)
+		dirOptions := &eye.DirectoryWatcherOptions{FollowSymlinks: w.FollowSymlinks}
+
+		paths := w.Paths
+		if w.PathsFile != "" {
+			listed, err := readPathsFile(w.PathsFile)
+			if err != nil {
+				fmt.Fprintln(table, w.ID+"\t"+w.PathsFile+"\terror: "+err.Error())
+			}
+			paths = append(append([]string(nil), paths...), listed...)
+		}
+
+		for _, path := range paths {
+			for _, status := range pathFiles(path, options, dirOptions) {
+				fmt.Fprintln(table, w.ID+"\t"+status.Path+"\t"+status.Reason)
+			}
+		}
+	}
+
+	table.Flush()
+}
+
+// pathFiles returns the status of the files under a path, as it would be
+// printed: followed, ignored with the reason, or what prevents listing them.
+func pathFiles(path string, options *eye.TrailOptions, dirOptions *eye.DirectoryWatcherOptions) []eye.FileStatus {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return []eye.FileStatus{{Path: path, Reason: "missing"}}
+	}
+
+	// Files are listed by walking the path, however it is watched.
+	watcher, trailOptions, err := newWatcher(path, options, dirOptions)
+	if err != nil {
+		return []eye.FileStatus{{Path: path, Reason: "error: " + err.Error()}}
+	}
+
+	statuses, err := eye.NewTrailWithOptions(watcher, trailOptions).ListFiles()
+	if err != nil {
+		return []eye.FileStatus{{Path: path, Reason: "error: " + err.Error()}}
+	}
+
+	for i := range statuses {
+		if statuses[i].Followed {
+			statuses[i].Reason = "followed"
+		} else {
+			statuses[i].Reason = "ignored: " + statuses[i].Reason
+		}
+	}
+
+	return statuses
+}
@@ -0,0 +1,51 @@
+package console
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func TestListFilesAction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	logs := filepath.Join(dir, "logs")
+	assert.Nil(t, os.MkdirAll(logs, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(logs, "app.log"), []byte("line\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(logs, "app.txt"), []byte("line\n"), 0644))
+	missing := filepath.Join(dir, "missing")
+
+	conf := filepath.Join(dir, "sauron.toml")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`
+[[watch]]
+id = "app"
+paths = [`+strconv.Quote(logs)+`, `+strconv.Quote(missing)+`]
+filePattern = "\\.log$"
+`), 0644))
+
+	set := flag.NewFlagSet("test", 0)
+	set.String("conf", conf, "")
+
+	var out bytes.Buffer
+	app := cli.NewApp()
+	app.Writer = &out
+
+	assert.Nil(t, ListFilesAction(cli.NewContext(app, set, nil)))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.Equal(t, []string{"WATCH", "FILE", "STATUS"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"app", filepath.Join(logs, "app.log"), "followed"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"app", filepath.Join(logs, "app.txt"), "ignored:", "file", "pattern"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"app", missing, "missing"}, strings.Fields(lines[3]))
+}
@@ -0,0 +1,97 @@
+package console
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// netDialTimeout bounds the time spent connecting to a network output.
+var netDialTimeout = 5 * time.Second
+
+// netSink writes lines to a TCP or UDP collector. TCP connections are dialed
+// again when a write fails, and the write is retried once on the new
+// connection. It is safe for concurrent use.
+type netSink struct {
+	mutex   sync.Mutex
+	network string
+	address string
+	conn    net.Conn
+}
+
+// isNetOut reports whether an output names a network collector, such as
+// tcp://host:port or udp://host:port.
+func isNetOut(path string) bool {
+	return strings.HasPrefix(path, "tcp://") || strings.HasPrefix(path, "udp://")
+}
+
+// dialNetSink connects to the collector of a tcp:// or udp:// output.
+func dialNetSink(path string) (*netSink, error) {
+	i := strings.Index(path, "://")
+	s := &netSink{network: path[:i], address: path[i+3:]}
+
+	if err := s.dial(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// dial replaces the connection of the sink.
+func (s *netSink) dial() error {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	conn, err := net.DialTimeout(s.network, s.address, netDialTimeout)
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+
+	return nil
+}
+
+// Write sends p to the collector, reconnecting first when the previous
+// connection was lost.
+func (s *netSink) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.conn == nil {
+		if err := s.dial(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := s.conn.Write(p)
+	if err == nil || s.network != "tcp" {
+		return n, err
+	}
+
+	logger.Warnln("Lost connection to " + s.address + ": " + err.Error() + ". Reconnecting")
+
+	if err := s.dial(); err != nil {
+		return 0, err
+	}
+
+	return s.conn.Write(p)
+}
+
+// Close closes the connection to the collector.
+func (s *netSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+
+	return err
+}
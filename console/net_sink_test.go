@@ -0,0 +1,135 @@
+package console
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lineServer is a TCP collector sending every line it receives to lines.
+type lineServer struct {
+	listener net.Listener
+	lines    chan string
+	mutex    sync.Mutex
+	conns    []net.Conn
+}
+
+func newLineServer(t *testing.T, address string, lines chan string) *lineServer {
+	l, err := net.Listen("tcp", address)
+	assert.Nil(t, err)
+
+	s := &lineServer{listener: l, lines: lines}
+	go s.accept()
+
+	return s
+}
+
+func (s *lineServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.conns = append(s.conns, conn)
+		s.mutex.Unlock()
+
+		go func() {
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				s.lines <- scanner.Text()
+			}
+		}()
+	}
+}
+
+// close stops the collector and drops its connections.
+func (s *lineServer) close() {
+	s.listener.Close()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func TestNetOutTCP(t *testing.T) {
+	lines := make(chan string, 10)
+	server := newLineServer(t, "127.0.0.1:0", lines)
+	defer server.close()
+
+	out, err := openOut("tcp://" + server.listener.Addr().String())
+	assert.Nil(t, err)
+	defer closeOutputs()
+
+	writeText("first", out, "\n")
+	writeText("second", out, "\n")
+
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case line := <-lines:
+			assert.Equal(t, expected, line)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no line received")
+		}
+	}
+}
+
+func TestNetOutUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	out, err := openOut("udp://" + conn.LocalAddr().String())
+	assert.Nil(t, err)
+	defer closeOutputs()
+
+	writeText("datagram", out, "\n")
+
+	buf := make([]byte, 100)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "datagram\n", string(buf[:n]))
+}
+
+func TestNetSinkReconnect(t *testing.T) {
+	lines := make(chan string, 10)
+	server := newLineServer(t, "127.0.0.1:0", lines)
+	address := server.listener.Addr().String()
+
+	sink, err := dialNetSink("tcp://" + address)
+	assert.Nil(t, err)
+	defer sink.Close()
+
+	_, err = sink.Write([]byte("before\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "before", <-lines)
+
+	server.close()
+
+	server = newLineServer(t, address, lines)
+	defer server.close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		sink.Write([]byte("after\n"))
+
+		select {
+		case line := <-lines:
+			assert.True(t, strings.HasPrefix(line, "after"))
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	t.Fatal("no line received after the listener restarted")
+}
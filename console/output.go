@@ -0,0 +1,228 @@
+package console
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Settings of the outputs opened afterwards, set from the config at startup.
+// Outputs are flushed and synced to disk every syncInterval, so that their
+// content can be read and survives a crash. Negative intervals disable this.
+// They are also synced after every line with syncEveryLine.
+var (
+	syncInterval  = time.Second
+	syncEveryLine bool
+)
+
+// syncFile commits the content of a file to disk.
+var syncFile = (*os.File).Sync
+
+// output is a destination of lines, written either as is or as a gzip stream
+// to a file, or to a network sink. Dated outputs move to another file as the
+// date changes, opened calling opened, then calling rolled with the path of
+// the previous file. Writes go through a breaker, which suspends them when the
+// output keeps failing. It is safe for concurrent use.
+type output struct {
+	mutex   sync.Mutex
+	file    *os.File
+	sink    io.WriteCloser
+	gzip    *gzip.Writer
+	closed  chan bool
+	dirty   bool
+	breaker *breaker
+
+	pattern  string
+	path     string
+	location *time.Location
+	now      func() time.Time
+	opened   func(file *os.File)
+	rolled   func(path string)
+}
+
+// newOutput wraps a file, compressing what is written to it when compress is
+// set.
+func newOutput(file *os.File, compress bool) *output {
+	out := &output{
+		file:    file,
+		closed:  make(chan bool),
+		breaker: newBreaker(file.Name()),
+	}
+
+	if compress {
+		out.gzip = gzip.NewWriter(file)
+	}
+
+	if syncInterval > 0 {
+		go out.flushPeriodically(syncInterval)
+	}
+
+	return out
+}
+
+// newSinkOutput wraps a network sink, which is neither compressed nor synced.
+func newSinkOutput(name string, sink io.WriteCloser) *output {
+	return &output{
+		sink:    sink,
+		breaker: newBreaker(name),
+	}
+}
+
+// WriteString writes s to the output, like Write.
+func (o *output) WriteString(s string) (int, error) {
+	return o.Write([]byte(s))
+}
+
+// Write writes b to the output, along with the lines held while its breaker
+// was open. Lines are held or dropped without error while the breaker is
+// open. The output doesn't keep b, which may be reused once Write returns.
+func (o *output) Write(b []byte) (int, error) {
+	return o.WriteSeverity(b, noSeverity)
+}
+
+// WriteSeverity writes b like Write does, sending it at a syslog severity to
+// syslog outputs, while the others ignore it.
+func (o *output) WriteSeverity(b []byte, severity int) (int, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.breaker == nil {
+		return o.write(b, severity)
+	}
+
+	line := heldLine{text: string(b), severity: severity}
+	if !o.breaker.allow() {
+		o.breaker.hold(line)
+		return len(b), nil
+	}
+
+	held := o.breaker.take()
+	for i, h := range held {
+		if _, err := o.write([]byte(h.text), h.severity); err != nil {
+			o.breaker.failure()
+			o.breaker.hold(append(held[i:], line)...)
+			return 0, err
+		}
+	}
+
+	if _, err := o.write(b, severity); err != nil {
+		o.breaker.failure()
+		o.breaker.hold(line)
+		return 0, err
+	}
+
+	o.breaker.success()
+	o.dirty = true
+
+	if syncEveryLine {
+		if err := o.flush(); err != nil {
+			return len(b), err
+		}
+	}
+
+	return len(b), nil
+}
+
+// write writes b to the sink or the file, compressing it if needed. Syslog
+// sinks send it at severity, unless it is noSeverity.
+func (o *output) write(b []byte, severity int) (int, error) {
+	if s, ok := o.sink.(*syslogSink); ok && severity != noSeverity {
+		// The daemon ends the messages itself.
+		if err := s.send(severity, strings.TrimRight(string(b), "\r\n\x00")); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if o.sink != nil {
+		return o.sink.Write(b)
+	}
+
+	if o.pattern != "" {
+		if err := o.roll(); err != nil {
+			return 0, err
+		}
+	}
+
+	if o.gzip != nil {
+		return o.gzip.Write(b)
+	}
+
+	return o.file.Write(b)
+}
+
+// flush flushes the gzip stream, if any, and syncs the file to disk when
+// something was written since the last time.
+func (o *output) flush() error {
+	if !o.dirty {
+		return nil
+	}
+
+	if o.gzip != nil {
+		if err := o.gzip.Flush(); err != nil {
+			return err
+		}
+	}
+
+	o.dirty = false
+
+	if o.sink != nil || o.file == nil {
+		return nil
+	}
+
+	return syncFile(o.file)
+}
+
+// flushPeriodically flushes the output every interval until it is closed.
+func (o *output) flushPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			o.mutex.Lock()
+			if err := o.flush(); err != nil {
+				logger.Errorln(err)
+			}
+			o.mutex.Unlock()
+		case <-o.closed:
+			return
+		}
+	}
+}
+
+// Close completes the gzip stream, if any, and closes the file or the sink.
+func (o *output) Close() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.closed != nil {
+		close(o.closed)
+	}
+
+	if o.sink != nil {
+		return o.sink.Close()
+	}
+
+	if o.file == nil {
+		return nil
+	}
+
+	return o.closeFile()
+}
+
+// closeFile completes the gzip stream, if any, and closes the file.
+func (o *output) closeFile() error {
+	if o.gzip != nil {
+		if err := o.gzip.Close(); err != nil {
+			o.file.Close()
+			return err
+		}
+	}
+
+	return o.file.Close()
+}
@@ -0,0 +1,123 @@
+package console
+
+import (
+	"../eye"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeText writes a record and its terminator to an output, as the handlers
+// do.
+func writeText(record string, out *output, terminator string) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.WriteString(record + terminator)
+	write(buf, out)
+}
+
+func TestGzipOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "matched.log.gz")
+	w := watch{Out: path}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, nil, w)
+	for _, text := range []string{"one", "two", "three"} {
+		assert.Nil(t, handler(eye.Line{Text: text}))
+	}
+
+	closeOutputs()
+
+	file, err := os.Open(path)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	assert.Nil(t, err)
+
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "one\ntwo\nthree\n", string(content))
+}
+
+// countSyncs counts the syncs of files until the returned function is called.
+func countSyncs() (*int32, func()) {
+	var syncs int32
+	syncFile = func(file *os.File) error {
+		atomic.AddInt32(&syncs, 1)
+		return file.Sync()
+	}
+
+	return &syncs, func() { syncFile = (*os.File).Sync }
+}
+
+func TestOutputSyncsPeriodically(t *testing.T) {
+	syncInterval = 10 * time.Millisecond
+	defer func() { syncInterval = time.Second }()
+
+	syncs, restore := countSyncs()
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file, err := os.Create(filepath.Join(dir, "out.log"))
+	assert.Nil(t, err)
+
+	out := newOutput(file, false)
+	defer out.Close()
+
+	// Nothing is synced until something is written.
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(syncs))
+
+	_, err = out.WriteString("one\n")
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(syncs) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestOutputSyncsEveryLine(t *testing.T) {
+	syncInterval = -1
+	syncEveryLine = true
+	defer func() {
+		syncInterval = time.Second
+		syncEveryLine = false
+	}()
+
+	syncs, restore := countSyncs()
+	defer restore()
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file, err := os.Create(filepath.Join(dir, "out.log"))
+	assert.Nil(t, err)
+
+	out := newOutput(file, false)
+	defer out.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err = out.WriteString("line\n")
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(syncs))
+}
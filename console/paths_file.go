@@ -0,0 +1,110 @@
+package console
+
+import (
+	"../eye"
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+)
+
+// readPathsFile returns the paths listed in a PathsFile, one per line.
+// Blank lines and lines starting with # are skipped.
+func readPathsFile(name string) ([]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		paths = append(paths, line)
+	}
+
+	return paths, scanner.Err()
+}
+
+// pathsFileTrails keeps a trail following every path listed in a PathsFile,
+// starting and ending trails as paths are added to and removed from it. It is
+// safe for concurrent use.
+type pathsFileTrails struct {
+	name     string
+	follow   func(path string) (*eye.Trail, error)
+	unfollow func(trail *eye.Trail)
+
+	mutex  sync.Mutex
+	trails map[string]*eye.Trail
+}
+
+// newPathsFileTrails creates the trails of a PathsFile, started and ended
+// with follow and unfollow, without reading it yet.
+func newPathsFileTrails(name string, follow func(path string) (*eye.Trail, error), unfollow func(trail *eye.Trail)) *pathsFileTrails {
+	return &pathsFileTrails{
+		name:     name,
+		follow:   follow,
+		unfollow: unfollow,
+		trails:   make(map[string]*eye.Trail),
+	}
+}
+
+// sync reads the file again, following the paths newly listed and ending the
+// trails of the paths no longer listed. Paths failing to be followed are
+// tried again on the next sync. Nothing changes when the file can't be read.
+func (p *pathsFileTrails) sync() {
+	paths, err := readPathsFile(p.name)
+	if err != nil {
+		logger.Errorln("Failed to read paths file " + p.name + ": " + err.Error())
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	listed := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		listed[path] = true
+
+		if _, ok := p.trails[path]; ok {
+			continue
+		}
+
+		trail, err := p.follow(path)
+		if err != nil {
+			logger.Errorln(err)
+			continue
+		}
+
+		if trail != nil {
+			logger.Infoln("Following " + path + " listed in " + p.name)
+			p.trails[path] = trail
+		}
+	}
+
+	for path, trail := range p.trails {
+		if !listed[path] {
+			logger.Infoln("Unfollowing " + path + " no longer listed in " + p.name)
+			p.unfollow(trail)
+			delete(p.trails, path)
+		}
+	}
+}
+
+// paths returns the paths currently followed.
+func (p *pathsFileTrails) paths() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	paths := make([]string, 0, len(p.trails))
+	for path := range p.trails {
+		paths = append(paths, path)
+	}
+
+	return paths
+}
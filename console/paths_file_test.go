@@ -0,0 +1,84 @@
+package console
+
+import (
+	"../eye"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadPathsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "sauron.paths")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("# web servers\n/var/log/nginx\n\n  /var/log/apache  \n#/var/log/old\n"), 0644))
+
+	paths, err := readPathsFile(name)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/var/log/nginx", "/var/log/apache"}, paths)
+
+	_, err = readPathsFile(filepath.Join(dir, "missing.paths"))
+	assert.NotNil(t, err)
+}
+
+func TestPathsFileTrailsSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"nginx", "apache", "app"} {
+		assert.Nil(t, os.Mkdir(filepath.Join(dir, name), 0755))
+	}
+
+	list := func(names ...string) {
+		var lines []string
+		for _, name := range names {
+			lines = append(lines, filepath.Join(dir, name))
+		}
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "sauron.paths"), []byte(strings.Join(lines, "\n")), 0644))
+	}
+
+	watched := make(map[*eye.Trail]string)
+	var unfollowed []string
+	trails := newPathsFileTrails(filepath.Join(dir, "sauron.paths"), func(path string) (*eye.Trail, error) {
+		watcher, _, err := newWatcher(path, &eye.TrailOptions{}, &eye.DirectoryWatcherOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		trail := eye.NewTrail(watcher)
+		watched[trail] = path
+		return trail, nil
+	}, func(trail *eye.Trail) {
+		unfollowed = append(unfollowed, watched[trail])
+	})
+
+	paths := func() []string {
+		paths := trails.paths()
+		sort.Strings(paths)
+		return paths
+	}
+
+	// Missing directories are skipped until a later sync.
+	list("nginx", "apache", "missing")
+	trails.sync()
+	assert.Equal(t, []string{filepath.Join(dir, "apache"), filepath.Join(dir, "nginx")}, paths())
+
+	list("nginx", "app", "missing")
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "missing"), 0755))
+	trails.sync()
+	assert.Equal(t, []string{filepath.Join(dir, "app"), filepath.Join(dir, "missing"), filepath.Join(dir, "nginx")}, paths())
+	assert.Equal(t, []string{filepath.Join(dir, "apache")}, unfollowed)
+
+	// An unreadable file changes nothing.
+	assert.Nil(t, os.Remove(filepath.Join(dir, "sauron.paths")))
+	trails.sync()
+	assert.Equal(t, 3, len(paths()))
+}
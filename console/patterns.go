@@ -0,0 +1,126 @@
+package console
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Named patterns of the Patterns table are referenced as "@name" by patterns
+// made of the reference alone, and as "@{name}" within larger patterns, where
+// they are grouped so that they repeat or alternate as a whole. A bare @word
+// within a larger pattern, as in user@example\.com, stays literal text.
+var (
+	patternReference       = regexp.MustCompile(`^@(\w+)$`)
+	patternInlineReference = regexp.MustCompile(`@\{(\w+)\}`)
+)
+
+// patternField is a pattern of a watch, along with the field setting it.
+type patternField struct {
+	field   string
+	pattern *string
+}
+
+// patternFields lists the pattern fields of a watch, so that they can be
+// expanded or validated alike.
+func patternFields(w *watch) []patternField {
+	fields := []patternField{
+		{"LinePattern", &w.LinePattern},
+		{"LineIgnorePattern", &w.LineIgnorePattern},
+		{"FilePattern", &w.FilePattern},
+		{"FileIgnorePattern", &w.FileIgnorePattern},
+		{"PathPattern", &w.PathPattern},
+		{"PathIgnorePattern", &w.PathIgnorePattern},
+		{"FullPathPattern", &w.FullPathPattern},
+		{"GzipStreamPattern", &w.GzipStreamPattern},
+		{"LevelPattern", &w.LevelPattern},
+		{"TimestampPattern", &w.TimestampPattern},
+		{"TimePattern", &w.TimePattern},
+	}
+
+	for i := range w.LinePatterns {
+		fields = append(fields, patternField{"LinePatterns", &w.LinePatterns[i]})
+	}
+	for i := range w.LineIgnorePatterns {
+		fields = append(fields, patternField{"LineIgnorePatterns", &w.LineIgnorePatterns[i]})
+	}
+	for i := range w.Rules {
+		fields = append(fields, patternField{"Rules.LinePattern", &w.Rules[i].LinePattern})
+	}
+	for i := range w.Redactions {
+		fields = append(fields, patternField{"Redactions.Pattern", &w.Redactions[i].Pattern})
+	}
+	if w.Syslog != nil {
+		for i := range w.Syslog.SeverityMap {
+			fields = append(fields, patternField{"Syslog.SeverityMap.Pattern", &w.Syslog.SeverityMap[i].Pattern})
+		}
+	}
+	if w.Correlation != nil {
+		fields = append(fields,
+			patternField{"Correlation.PatternA", &w.Correlation.PatternA},
+			patternField{"Correlation.PatternB", &w.Correlation.PatternB})
+	}
+
+	return fields
+}
+
+// expandPatterns replaces the references to named patterns in the patterns of
+// every watch with the patterns they name.
+func expandPatterns(conf *Config) error {
+	for i := range conf.Watch {
+		for _, f := range patternFields(&conf.Watch[i]) {
+			expanded, err := expandPattern(*f.pattern, conf.Patterns, nil)
+			if err != nil {
+				return fmt.Errorf("watch #%d %s: %v", i+1, f.field, err)
+			}
+
+			*f.pattern = expanded
+		}
+	}
+
+	return nil
+}
+
+// expandPattern replaces the references to named patterns in pattern, named
+// patterns referencing others in turn. The stack holds the names being
+// expanded and is used to detect cyclic references.
+func expandPattern(pattern string, library map[string]string, stack []string) (string, error) {
+	var err error
+	lookup := func(name string) string {
+		for _, expanding := range stack {
+			if expanding == name {
+				err = fmt.Errorf("cyclic pattern reference: %s", strings.Join(append(stack, name), " -> "))
+				return ""
+			}
+		}
+
+		named, ok := library[name]
+		if !ok {
+			err = fmt.Errorf("undefined pattern %s", strconv.Quote(name))
+			return ""
+		}
+
+		expanded, e := expandPattern(named, library, append(stack, name))
+		if e != nil {
+			err = e
+		}
+
+		return expanded
+	}
+
+	if m := patternReference.FindStringSubmatch(pattern); m != nil {
+		expanded := lookup(m[1])
+		return expanded, err
+	}
+
+	expanded := patternInlineReference.ReplaceAllStringFunc(pattern, func(reference string) string {
+		if err != nil {
+			return reference
+		}
+
+		return "(?:" + lookup(patternInlineReference.FindStringSubmatch(reference)[1]) + ")"
+	})
+
+	return expanded, err
+}
@@ -0,0 +1,116 @@
+package console
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// defaultRecentSize is the number of recent lines kept per watch when
+// TailBufferSize isn't set.
+const defaultRecentSize = 100
+
+// recentLines is a fixed-size ring buffer of the last lines written by a
+// watch.
+type recentLines struct {
+	mutex sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// newRecentLines creates a ring buffer holding up to size lines.
+func newRecentLines(size int) *recentLines {
+	return &recentLines{lines: make([]string, size)}
+}
+
+// add stores a line, overwriting the oldest one when the buffer is full.
+func (r *recentLines) add(line string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// last returns up to n of the most recent lines, oldest first.
+func (r *recentLines) last(n int) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.lines)
+	}
+
+	if n < 0 || n > count {
+		n = count
+	}
+
+	lines := make([]string, 0, n)
+	for i := r.next - n; i < r.next; i++ {
+		lines = append(lines, r.lines[(i+len(r.lines))%len(r.lines)])
+	}
+
+	return lines
+}
+
+var (
+	recent      = make(map[string]*recentLines)
+	recentMutex sync.Mutex
+)
+
+// recentFor returns the buffer of recent lines of a watch, keyed by its
+// description, or nil when TailBufferSize disables it.
+func recentFor(w watch) *recentLines {
+	size := w.TailBufferSize
+	if size < 0 {
+		return nil
+	}
+
+	if size == 0 {
+		size = defaultRecentSize
+	}
+
+	recentMutex.Lock()
+	defer recentMutex.Unlock()
+
+	if r, ok := recent[w.Desc]; ok {
+		return r
+	}
+
+	r := newRecentLines(size)
+	recent[w.Desc] = r
+
+	return r
+}
+
+// serveRecent answers /tail?watch=desc&n=100 with the most recent lines of a
+// watch, one per line. Every line kept is returned when n is omitted.
+func serveRecent(w http.ResponseWriter, req *http.Request) {
+	recentMutex.Lock()
+	r, ok := recent[req.URL.Query().Get("watch")]
+	recentMutex.Unlock()
+
+	if !ok {
+		http.Error(w, "unknown watch", http.StatusNotFound)
+		return
+	}
+
+	n := -1
+	if value := req.URL.Query().Get("n"); value != "" {
+		var err error
+		if n, err = strconv.Atoi(value); err != nil || n < 0 {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, line := range r.last(n) {
+		w.Write([]byte(line + "\n"))
+	}
+}
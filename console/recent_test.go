@@ -0,0 +1,73 @@
+package console
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentLines(t *testing.T) {
+	r := newRecentLines(3)
+
+	assert.Equal(t, []string{}, r.last(-1))
+
+	r.add("one")
+	r.add("two")
+	assert.Equal(t, []string{"one", "two"}, r.last(-1))
+	assert.Equal(t, []string{"two"}, r.last(1))
+
+	r.add("three")
+	r.add("four")
+	assert.Equal(t, []string{"two", "three", "four"}, r.last(-1))
+	assert.Equal(t, []string{"three", "four"}, r.last(2))
+	assert.Equal(t, []string{"two", "three", "four"}, r.last(10))
+}
+
+func TestServeRecent(t *testing.T) {
+	defer func() {
+		recentMutex.Lock()
+		delete(recent, "recent-test")
+		recentMutex.Unlock()
+	}()
+
+	r := recentFor(watch{Desc: "recent-test", TailBufferSize: 5})
+	for i := 1; i <= 7; i++ {
+		r.add("line " + strconv.Itoa(i))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(serveRecent))
+	defer server.Close()
+
+	get := func(query string) (int, string) {
+		resp, err := http.Get(server.URL + "/tail?" + query)
+		assert.Nil(t, err)
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.Nil(t, err)
+
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("watch=recent-test&n=2")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "line 6\nline 7\n", body)
+
+	code, body = get("watch=recent-test")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "line 3\nline 4\nline 5\nline 6\nline 7\n", body)
+
+	code, _ = get("watch=recent-test&n=many")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = get("watch=missing")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestRecentForDisabled(t *testing.T) {
+	assert.Nil(t, recentFor(watch{Desc: "disabled", TailBufferSize: -1}))
+}
@@ -0,0 +1,43 @@
+package console
+
+import (
+	"regexp"
+)
+
+// redactor applies the redactions of a watch.
+type redactor struct {
+	regs         []*regexp.Regexp
+	replacements []string
+}
+
+// newRedactor compiles the redactions of a watch, or returns nil when it has
+// none. Invalid patterns are logged and skipped.
+func newRedactor(w watch) *redactor {
+	if len(w.Redactions) == 0 {
+		return nil
+	}
+
+	r := &redactor{}
+	for _, redaction := range w.Redactions {
+		reg, err := regexp.Compile(redaction.Pattern)
+		if err != nil {
+			logger.Errorln("Invalid redaction pattern " + redaction.Pattern + ": " + err.Error())
+			continue
+		}
+
+		r.regs = append(r.regs, reg)
+		r.replacements = append(r.replacements, redaction.Replacement)
+	}
+
+	return r
+}
+
+// redact applies the redactions to text in order, each one seeing the result
+// of the previous ones.
+func (r *redactor) redact(text string) string {
+	for i, reg := range r.regs {
+		text = reg.ReplaceAllString(text, r.replacements[i])
+	}
+
+	return text
+}
@@ -0,0 +1,69 @@
+package console
+
+import (
+	"../eye"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	r := newRedactor(watch{Redactions: []redaction{
+		{Pattern: `([\w.+-]+)@([\w-]+\.[\w.-]+)`, Replacement: "***@$2"},
+		{Pattern: `(?i)(Authorization: Bearer) [\w.~+/-]+=*`, Replacement: "$1 [redacted]"},
+	}})
+
+	assert.Equal(t, "login ***@example.com ok", r.redact("login jane.doe@example.com ok"))
+	assert.Equal(t, "GET / Authorization: Bearer [redacted] 200", r.redact("GET / Authorization: Bearer eyJhbGciOi.J9.x-y_z 200"))
+	assert.Equal(t, "nothing secret", r.redact("nothing secret"))
+}
+
+func TestRedactOverlapping(t *testing.T) {
+	// Each redaction sees the output of the previous ones: the token masked
+	// by the first isn't taken for an email by the second, while the third
+	// rewrites what both of them produced.
+	r := newRedactor(watch{Redactions: []redaction{
+		{Pattern: `token=\S+`, Replacement: "token=[redacted]"},
+		{Pattern: `\S+@\S+`, Replacement: "[email]"},
+		{Pattern: `\[(\w+)\]`, Replacement: "<$1>"},
+	}})
+
+	assert.Equal(t, "user <email> <email> token=<redacted>", r.redact("user bob@example.com [email] token=bob@secret"))
+}
+
+func TestRedactInvalidPattern(t *testing.T) {
+	r := newRedactor(watch{Redactions: []redaction{
+		{Pattern: `(`, Replacement: "x"},
+		{Pattern: `secret`, Replacement: "***"},
+	}})
+
+	assert.Equal(t, "a *** b", r.redact("a secret b"))
+	assert.Nil(t, newRedactor(watch{}))
+}
+
+func TestGetHandlerRedactions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	w := watch{
+		LinePattern: "alice@example.com",
+		Redactions:  []redaction{{Pattern: `\w+@example\.com`, Replacement: "[email]"}},
+		Out:         filepath.Join(dir, "out.log"),
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, nil, w)
+	assert.Nil(t, handler(eye.Line{Text: "login alice@example.com"}))
+	assert.Nil(t, handler(eye.Line{Text: "login bob@example.com"}))
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "out.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "login [email]\n", string(out))
+}
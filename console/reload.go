@@ -0,0 +1,276 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// reloadHooks are called on SIGHUP once the logging is reloaded.
+var (
+	reloadHooks      []func()
+	reloadHooksMutex sync.Mutex
+)
+
+// onReload registers a function called on every SIGHUP.
+func onReload(hook func()) {
+	reloadHooksMutex.Lock()
+	defer reloadHooksMutex.Unlock()
+
+	reloadHooks = append(reloadHooks, hook)
+}
+
+// runReloadHooks calls the registered reload hooks, in order.
+func runReloadHooks() {
+	reloadHooksMutex.Lock()
+	hooks := append([]func(){}, reloadHooks...)
+	reloadHooksMutex.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// ReloadAction is the action of the reload command, asking the running Sauron
+// to reload its config by sending it SIGHUP.
+func ReloadAction(c *cli.Context) error {
+	if err := checkRecordedUser(configRecordPath()); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	pid, err := reloadDaemon(pidFilePath())
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Fprintf(c.App.Writer, "Sent reload to sauron (pid %d)\n", pid)
+
+	return nil
+}
+
+// StopAction is the action of the stop command, asking the running Sauron to
+// shut down gracefully by sending it SIGTERM.
+func StopAction(c *cli.Context) error {
+	if err := checkRecordedUser(configRecordPath()); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	pid, err := stopDaemon(pidFilePath())
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Fprintf(c.App.Writer, "Sent stop to sauron (pid %d)\n", pid)
+
+	return nil
+}
+
+// writeConfigRecord records the configSources of the running Sauron in the
+// file at record, one per line and made absolute, so that the reload and stop
+// commands check its own config wherever they run from.
+func writeConfigRecord(record string, c *cli.Context) error {
+	path, dir := configSources(c)
+	for _, source := range []*string{&path, &dir} {
+		if *source != "" && *source != "-" {
+			if abs, err := filepath.Abs(*source); err == nil {
+				*source = abs
+			}
+		}
+	}
+
+	return ioutil.WriteFile(record, []byte(path+"\n"+dir+"\n"), 0664)
+}
+
+// readConfigRecord returns the config file and directory recorded by the
+// running Sauron. Without a record, as for a Sauron started before it was
+// written, the default config is assumed.
+func readConfigRecord(record string) (path, dir string, err error) {
+	data, err := ioutil.ReadFile(record)
+	if os.IsNotExist(err) {
+		return defaultConfigPath(), "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if len(lines) < 2 {
+		return "", "", fmt.Errorf("invalid config record %s", record)
+	}
+
+	return lines[0], lines[1], nil
+}
+
+// checkRecordedUser checks the invoking user against the config recorded by
+// the running Sauron in the file at record.
+func checkRecordedUser(record string) error {
+	path, dir, err := readConfigRecord(record)
+	if err != nil {
+		return err
+	}
+
+	return checkInvokingUser(path, dir)
+}
+
+// checkInvokingUser fails unless the user running a command is one of the
+// AllowedUsers of the daemon's config, loaded from the file at path and the
+// directory dir like setConfig does, whatever config the user points at. It
+// fails as well when that config can't be read, as when the daemon read it
+// from the standard input, while anyone may signal the running Sauron when it
+// has no AllowedUsers.
+func checkInvokingUser(path, dir string) error {
+	if path == "-" {
+		return errors.New("the config of the running sauron was read from the standard input, its AllowedUsers can't be checked")
+	}
+
+	var conf Config
+	if path != "" {
+		if err := loadConfig(path, &conf, nil); err != nil {
+			return fmt.Errorf("failed to load the config %s: %v", path, err)
+		}
+	}
+
+	if dir != "" {
+		if err := loadConfigDir(dir, &conf); err != nil {
+			return fmt.Errorf("failed to load the config directory %s: %v", dir, err)
+		}
+	}
+
+	if len(conf.AllowedUsers) == 0 {
+		return nil
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("failed to look up the invoking user: %v", err)
+	}
+
+	if !userAllowed(current, conf.AllowedUsers) {
+		return fmt.Errorf("user %s is not allowed to signal sauron", current.Username)
+	}
+
+	return nil
+}
+
+// userAllowed reports whether u is listed in allowed, by name or by id.
+func userAllowed(u *user.User, allowed []string) bool {
+	for _, name := range allowed {
+		if name == u.Username || name == u.Uid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reloadDaemon signals the process of a pid file to reload, returning its pid.
+func reloadDaemon(pidFile string) (int, error) {
+	return signalDaemon(pidFile, signalReload)
+}
+
+// stopDaemon signals the process of a pid file to stop, returning its pid.
+func stopDaemon(pidFile string) (int, error) {
+	return signalDaemon(pidFile, signalStop)
+}
+
+// signalDaemon signals the process of a pid file with send, returning its pid.
+// Missing pid files and pids of processes which are gone are reported as
+// errors rather than signaled.
+func signalDaemon(pidFile string, send func(pid int) error) (int, error) {
+	data, err := ioutil.ReadFile(pidFile)
+	if os.IsNotExist(err) {
+		return 0, fmt.Errorf("no pid file at %s, is sauron running?", pidFile)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid file %s: %v", pidFile, err)
+	}
+
+	if !processRunning(pid) {
+		return 0, fmt.Errorf("stale pid file %s, sauron (pid %d) is not running", pidFile, pid)
+	}
+
+	return pid, send(pid)
+}
+
+// reloadLogging reloads the config on SIGHUP and applies its LogLevel and Log,
+// re-opening the log file so that it can be rotated, and closing the previous
+// one. The loggers of the watches with their own Log get the new level and
+// re-open their files too. Other changes, such as those of watches, take
+// effect on restart, while the PathsFile of watches are read again by their
+// reload hooks. Quiet instances stay quiet, and configs read from the standard
+// input, which can't be read again, are kept.
+func reloadLogging(c *cli.Context) {
+	if path, _ := configSources(c); path == "-" {
+		logger.Errorln("The config was read from the standard input, keeping the current one")
+		return
+	}
+
+	conf, ok := setConfig(c)
+	if !ok {
+		logger.Errorln("Failed to reload the config, keeping the current one")
+		return
+	}
+
+	if quiet {
+		return
+	}
+
+	var level logrus.Level
+	if err := level.UnmarshalText([]byte(conf.LogLevel)); err == nil {
+		logger.SetLevel(level)
+	}
+
+	if len(conf.Log) > 0 {
+		if f, err := os.OpenFile(conf.Log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+			swapLogOutput(logger, f)
+		} else {
+			logger.Errorln(err)
+		}
+	}
+
+	reopenWatchLoggers(logger.GetLevel())
+
+	logger.Infoln("Reloaded the config, changes to watches take effect on restart")
+}
+
+// reopenWatchLoggers sets the level of the loggers of the watches with their
+// own Log, and re-opens their files.
+func reopenWatchLoggers(level logrus.Level) {
+	watchLoggersMutex.Lock()
+	defer watchLoggersMutex.Unlock()
+
+	for path, l := range watchLoggers {
+		l.SetLevel(level)
+
+		if f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+			swapLogOutput(l, f)
+		} else {
+			logger.Errorln(err)
+		}
+	}
+}
+
+// swapLogOutput makes l write to out, closing the file it wrote to before.
+// The standard streams are left open.
+func swapLogOutput(l *logrus.Logger, out io.Writer) {
+	previous := l.Out
+	l.SetOutput(out)
+
+	if f, ok := previous.(*os.File); ok && f != os.Stdout && f != os.Stderr && f != out {
+		f.Close()
+	}
+}
@@ -0,0 +1,177 @@
+package console
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/urfave/cli.v1"
+)
+
+func TestUserAllowed(t *testing.T) {
+	u := &user.User{Username: "alice", Uid: "1001"}
+
+	assert.True(t, userAllowed(u, []string{"bob", "alice"}))
+	assert.True(t, userAllowed(u, []string{"1001"}))
+	assert.False(t, userAllowed(u, []string{"bob", "1002"}))
+	assert.False(t, userAllowed(u, nil))
+}
+
+func TestCheckInvokingUser(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	current, err := user.Current()
+	assert.Nil(t, err)
+
+	conf := filepath.Join(dir, "sauron.toml")
+	check := func(config string) error {
+		assert.Nil(t, ioutil.WriteFile(conf, []byte(config), 0644))
+
+		return checkInvokingUser(conf, "")
+	}
+
+	// Without AllowedUsers, anyone may signal.
+	assert.Nil(t, check(""))
+	assert.Nil(t, check(`Log = "sauron.log"`))
+
+	assert.Nil(t, check(`AllowedUsers = ["nobody-else", "`+current.Username+`"]`))
+	assert.Nil(t, check(`AllowedUsers = ["`+current.Uid+`"]`))
+
+	err = check(`AllowedUsers = ["nobody-else"]`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "user "+current.Username+" is not allowed")
+
+	// A config which can't be read allows no one.
+	err = checkInvokingUser(filepath.Join(dir, "missing.toml"), "")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to load the config")
+
+	err = check(`AllowedUsers = [`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to load the config")
+
+	err = checkInvokingUser("-", "")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "standard input")
+}
+
+func TestCheckRecordedUser(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	configDir := filepath.Join(dir, "conf.d")
+	assert.Nil(t, os.Mkdir(configDir, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(configDir, "users.toml"), []byte(`AllowedUsers = ["nobody-else"]`), 0644))
+
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	// The daemon records its config dir, relative to where it was started,
+	// made absolute.
+	set := flag.NewFlagSet("test", 0)
+	set.String("conf", "", "")
+	set.String("config-dir", "conf.d", "")
+	record := filepath.Join(dir, "sauron.pid.config")
+	assert.Nil(t, writeConfigRecord(record, cli.NewContext(nil, set, nil)))
+
+	path, recorded, err := readConfigRecord(record)
+	assert.Nil(t, err)
+	assert.Equal(t, "", path)
+	assert.Equal(t, configDir, recorded)
+
+	// The AllowedUsers of the recorded config apply, though the default one
+	// is missing.
+	err = checkRecordedUser(record)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "is not allowed")
+
+	path, recorded, err = readConfigRecord(filepath.Join(dir, "missing"))
+	assert.Nil(t, err)
+	assert.Equal(t, defaultConfigPath(), path)
+	assert.Equal(t, "", recorded)
+}
+
+func TestStopActionConfigIgnored(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := filepath.Join(dir, "sauron.toml")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`Log = "sauron.log"`), 0644))
+
+	// The config given by the invoking user grants nothing: without a config
+	// recorded by a running Sauron, the default one, missing next to the test
+	// binary, is checked before the pid file is read.
+	os.Remove(configRecordPath())
+
+	set := flag.NewFlagSet("test", 0)
+	set.String("conf", conf, "")
+	c := cli.NewContext(cli.NewApp(), set, nil)
+
+	err = StopAction(c)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to load the config "+defaultConfigPath())
+
+	err = ReloadAction(c)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to load the config "+defaultConfigPath())
+}
+
+func TestReloadLogging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func() {
+		logger = logrus.New()
+		watchLoggers = map[string]*logrus.Logger{}
+	}()
+
+	setLogger(Config{Log: filepath.Join(dir, "sauron.log"), LogLevel: "info"})
+	own := watchLogger(watch{Log: filepath.Join(dir, "watch.log")})
+	previous := logger.Out.(*os.File)
+	previousOwn := own.Out.(*os.File)
+
+	reloaded := filepath.Join(dir, "reloaded.log")
+	conf := filepath.Join(dir, "sauron.toml")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`
+LogLevel = "warn"
+Log = "`+filepath.ToSlash(reloaded)+`"
+`), 0644))
+
+	set := flag.NewFlagSet("test", 0)
+	set.String("conf", conf, "")
+	reloadLogging(cli.NewContext(nil, set, nil))
+
+	assert.Equal(t, logrus.WarnLevel, logger.GetLevel())
+	assert.Equal(t, logrus.WarnLevel, own.GetLevel())
+
+	// The files written before are closed, the watch's one re-opened.
+	_, err = previous.WriteString("late\n")
+	assert.NotNil(t, err)
+	_, err = previousOwn.WriteString("late\n")
+	assert.NotNil(t, err)
+
+	logger.Warnln("after reload")
+	written, err := ioutil.ReadFile(reloaded)
+	assert.Nil(t, err)
+	assert.Contains(t, string(written), "after reload")
+
+	// A config read from the standard input is kept.
+	set = flag.NewFlagSet("test", 0)
+	set.String("conf", "-", "")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`LogLevel = "debug"`), 0644))
+	reloadLogging(cli.NewContext(nil, set, nil))
+
+	assert.Equal(t, logrus.WarnLevel, logger.GetLevel())
+}
@@ -0,0 +1,59 @@
+package console
+
+import (
+	"strconv"
+	"time"
+)
+
+// Delays between the attempts to restart a watcher which failed to start,
+// unless set by the watch. They double after every failed attempt, up to the
+// maximum.
+const (
+	defaultRestartDelay    = time.Second
+	defaultRestartMaxDelay = time.Minute
+)
+
+// restartDelays returns the first and longest delays between the restarts of
+// the watchers of a watch. A negative first delay disables restarts.
+func restartDelays(w watch) (time.Duration, time.Duration) {
+	delay, maxDelay := w.RestartDelay.Duration, w.RestartMaxDelay.Duration
+
+	if delay == 0 {
+		delay = defaultRestartDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultRestartMaxDelay
+	}
+	if maxDelay < delay {
+		maxDelay = delay
+	}
+
+	return delay, maxDelay
+}
+
+// restartWatch calls start until it succeeds, waiting delay before the first
+// attempt and twice as long after every failure, up to maxDelay. It gives up
+// and returns false once stop is closed.
+func restartWatch(path string, delay, maxDelay time.Duration, start func() error, stop <-chan bool) bool {
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(delay):
+		case <-stop:
+			return false
+		}
+
+		logger.Infoln("Restarting the watch of " + path + ", attempt " + strconv.Itoa(attempt))
+
+		err := start()
+		if err == nil {
+			logger.Infoln("Restarted the watch of " + path)
+			return true
+		}
+
+		logger.Errorln(err)
+
+		if delay *= 2; delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package console
+
+import (
+	"../eye"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartDelays(t *testing.T) {
+	delay, maxDelay := restartDelays(watch{})
+	assert.Equal(t, defaultRestartDelay, delay)
+	assert.Equal(t, defaultRestartMaxDelay, maxDelay)
+
+	delay, maxDelay = restartDelays(watch{RestartDelay: duration{2 * time.Minute}})
+	assert.Equal(t, 2*time.Minute, delay)
+	assert.Equal(t, 2*time.Minute, maxDelay)
+
+	delay, _ = restartDelays(watch{RestartDelay: duration{-1}})
+	assert.True(t, delay < 0)
+}
+
+func TestRestartWatchBacksOff(t *testing.T) {
+	var attempts []time.Time
+	start := func() error {
+		attempts = append(attempts, time.Now())
+		if len(attempts) < 4 {
+			return errors.New("mount unavailable")
+		}
+
+		return nil
+	}
+
+	begin := time.Now()
+	assert.True(t, restartWatch("/mnt/logs", 10*time.Millisecond, 30*time.Millisecond, start, make(chan bool)))
+	assert.Equal(t, 4, len(attempts))
+
+	// Waits of 10, 20, then 30 and 30 milliseconds.
+	assert.True(t, attempts[3].Sub(begin) >= 90*time.Millisecond)
+	assert.True(t, attempts[1].Sub(attempts[0]) >= 20*time.Millisecond)
+}
+
+func TestRestartWatchStops(t *testing.T) {
+	stop := make(chan bool)
+	close(stop)
+
+	assert.False(t, restartWatch("/mnt/logs", time.Hour, time.Hour, func() error {
+		t.Fatal("restarted after being stopped")
+		return nil
+	}, stop))
+}
+
+func TestRestartWatchDirectoryWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// The directory shows up after the first attempt failed, as a mount would.
+	path := filepath.Join(dir, "logs")
+	_, err = eye.NewDirectoryWatcher(path)
+	assert.NotNil(t, err)
+
+	var trail *eye.Trail
+	attempts := 0
+	start := func() error {
+		attempts++
+		if attempts == 2 {
+			assert.Nil(t, os.Mkdir(path, 0755))
+		}
+
+		watcher, err := eye.NewDirectoryWatcher(path)
+		if err != nil {
+			return err
+		}
+
+		trail = eye.NewTrailWithOptions(watcher, &eye.TrailOptions{FileIgnoreDuration: time.Hour})
+		return trail.Follow(func(line eye.Line) error { return nil })
+	}
+
+	assert.True(t, restartWatch(path, time.Millisecond, time.Millisecond, start, make(chan bool)))
+	assert.Equal(t, 2, attempts)
+	assert.NotNil(t, trail)
+	trail.End()
+}
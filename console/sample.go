@@ -0,0 +1,88 @@
+package console
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sampleNoteInterval is the shortest time between two notes of how many lines
+// were skipped by sampling.
+var sampleNoteInterval = time.Minute
+
+// sampler keeps one in every Sample matching lines of a watch: every Sample-th
+// one in the deterministic mode, or each with a probability of 1/Sample in the
+// random mode. It counts the lines it skips for the notes written along with
+// the kept ones. It is safe for concurrent use.
+type sampler struct {
+	every  uint64
+	random *rand.Rand
+
+	mutex    sync.Mutex
+	seen     uint64
+	skipped  uint64
+	lastNote time.Time
+}
+
+// newSampler builds the sampler of a watch, or returns nil when the watch
+// keeps every line.
+func newSampler(w watch) *sampler {
+	if w.Sample <= 1 {
+		return nil
+	}
+
+	s := &sampler{every: uint64(w.Sample), lastNote: time.Now()}
+
+	switch w.SampleMode {
+	case "", "deterministic":
+	case "random":
+		s.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	default:
+		logger.Errorln("Unknown sample mode " + w.SampleMode + ", sampling deterministically instead")
+	}
+
+	return s
+}
+
+// keep reports whether a matching line is sampled, counting it as skipped
+// otherwise.
+func (s *sampler) keep() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.seen++
+
+	var kept bool
+	if s.random != nil {
+		kept = s.random.Int63n(int64(s.every)) == 0
+	} else {
+		kept = s.seen%s.every == 0
+	}
+
+	if !kept {
+		s.skipped++
+	}
+
+	return kept
+}
+
+// note returns the note of how many lines were skipped since the previous one,
+// once sampleNoteInterval has passed since it and some lines were skipped, and
+// an empty string otherwise.
+func (s *sampler) note(now time.Time) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.skipped == 0 || now.Sub(s.lastNote) < sampleNoteInterval {
+		return ""
+	}
+
+	note := "sampled 1 in " + strconv.FormatUint(s.every, 10) + " lines, skipped " +
+		strconv.FormatUint(s.skipped, 10) + " since " + s.lastNote.Format(time.RFC3339)
+
+	s.skipped = 0
+	s.lastNote = now
+
+	return note
+}
@@ -0,0 +1,99 @@
+package console
+
+import (
+	"../eye"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func countKept(s *sampler, lines int) int {
+	kept := 0
+	for i := 0; i < lines; i++ {
+		if s.keep() {
+			kept++
+		}
+	}
+
+	return kept
+}
+
+func TestSamplerDeterministic(t *testing.T) {
+	s := newSampler(watch{Sample: 10})
+	assert.Equal(t, 100, countKept(s, 1000))
+
+	// Every 10th line is kept.
+	s = newSampler(watch{Sample: 10, SampleMode: "deterministic"})
+	for i := 1; i <= 20; i++ {
+		assert.Equal(t, i%10 == 0, s.keep())
+	}
+}
+
+func TestSamplerRandom(t *testing.T) {
+	s := newSampler(watch{Sample: 10, SampleMode: "random"})
+
+	kept := countKept(s, 10000)
+	assert.True(t, kept > 800 && kept < 1200, "kept %d lines", kept)
+}
+
+func TestNewSamplerDisabled(t *testing.T) {
+	assert.Nil(t, newSampler(watch{}))
+	assert.Nil(t, newSampler(watch{Sample: 1}))
+	assert.NotNil(t, newSampler(watch{Sample: 2, SampleMode: "unknown"}))
+}
+
+func TestSamplerNote(t *testing.T) {
+	s := newSampler(watch{Sample: 4})
+	start := s.lastNote
+
+	countKept(s, 8)
+	assert.Equal(t, "", s.note(start.Add(sampleNoteInterval/2)))
+
+	note := s.note(start.Add(sampleNoteInterval))
+	assert.True(t, strings.HasPrefix(note, "sampled 1 in 4 lines, skipped 6 since "), note)
+
+	// Nothing was skipped since the last note.
+	assert.Equal(t, "", s.note(start.Add(3*sampleNoteInterval)))
+}
+
+func TestGetHandlerSample(t *testing.T) {
+	defer func(interval time.Duration) { sampleNoteInterval = interval }(sampleNoteInterval)
+	sampleNoteInterval = 0
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	w := watch{
+		LinePattern: "DEBUG",
+		Sample:      3,
+		Out:         filepath.Join(dir, "out.log"),
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, nil, w)
+	for i := 1; i <= 6; i++ {
+		assert.Nil(t, handler(eye.Line{Path: "/var/log/app.log", Text: "DEBUG " + strconv.Itoa(i)}))
+		// Lines which don't match aren't counted by the sampler.
+		assert.Nil(t, handler(eye.Line{Path: "/var/log/app.log", Text: "INFO"}))
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "out.log"))
+	assert.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], "sampled 1 in 3 lines, skipped 2 since "), lines[0])
+	assert.Equal(t, "DEBUG 3", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "sampled 1 in 3 lines, skipped 2 since "), lines[2])
+	assert.Equal(t, "DEBUG 6", lines[3])
+}
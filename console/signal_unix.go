@@ -0,0 +1,70 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// shutdownSignals are the signals stopping Sauron.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// watchLevelSignal toggles the log level between info and debug every time the
+// process receives SIGUSR1.
+func watchLevelSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+
+	go func() {
+		for range signals {
+			toggleLogLevel()
+		}
+	}()
+}
+
+// watchReloadSignal calls reload every time the process receives SIGHUP.
+func watchReloadSignal(reload func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			reload()
+		}
+	}()
+}
+
+// signalReload sends SIGHUP to the process with the given pid.
+func signalReload(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return process.Signal(syscall.SIGHUP)
+}
+
+// signalStop sends SIGTERM to the process with the given pid.
+func signalStop(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	return process.Signal(syscall.SIGTERM)
+}
+
+// processRunning reports whether a process of the current user runs with the
+// given pid, by sending it the null signal.
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// We only get an error if the pid isn't running, or it's not ours.
+	return process.Signal(syscall.Signal(0)) == nil
+}
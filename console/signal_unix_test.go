@@ -0,0 +1,122 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchLevelSignal(t *testing.T) {
+	logger.SetLevel(logrus.InfoLevel)
+	defer logger.SetLevel(logrus.InfoLevel)
+
+	watchLevelSignal()
+
+	assert.Nil(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return logger.GetLevel() == logrus.DebugLevel
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool {
+		return logger.GetLevel() == logrus.InfoLevel
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestProcessRunning(t *testing.T) {
+	assert.True(t, processRunning(os.Getpid()))
+
+	exited := exec.Command("true")
+	assert.Nil(t, exited.Run())
+	assert.False(t, processRunning(exited.Process.Pid))
+}
+
+func TestReloadDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	pidFile := filepath.Join(dir, "sauron.pid")
+
+	_, err = reloadDaemon(pidFile)
+	assert.Contains(t, err.Error(), "no pid file")
+
+	// The child reports SIGHUP on its standard output.
+	cmd := exec.Command("sh", "-c", `trap 'echo reloaded' HUP; echo ready; while :; do sleep 0.05; done`)
+	stdout, err := cmd.StdoutPipe()
+	assert.Nil(t, err)
+	assert.Nil(t, cmd.Start())
+	defer cmd.Process.Kill()
+
+	lines := bufio.NewScanner(stdout)
+	assert.True(t, lines.Scan())
+	assert.Equal(t, "ready", lines.Text())
+
+	assert.Nil(t, ioutil.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0644))
+
+	pid, err := reloadDaemon(pidFile)
+	assert.Nil(t, err)
+	assert.Equal(t, cmd.Process.Pid, pid)
+
+	assert.True(t, lines.Scan())
+	assert.Equal(t, "reloaded", lines.Text())
+}
+
+func TestReloadDaemonStalePidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	exited := exec.Command("true")
+	assert.Nil(t, exited.Run())
+
+	pidFile := filepath.Join(dir, "sauron.pid")
+	assert.Nil(t, ioutil.WriteFile(pidFile, []byte(strconv.Itoa(exited.Process.Pid)), 0644))
+
+	_, err = reloadDaemon(pidFile)
+	assert.Contains(t, err.Error(), "stale pid file")
+
+	assert.Nil(t, ioutil.WriteFile(pidFile, []byte("garbage"), 0644))
+
+	_, err = reloadDaemon(pidFile)
+	assert.Contains(t, err.Error(), "invalid pid file")
+}
+
+func TestStopDaemon(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// The child reports SIGTERM on its standard output.
+	cmd := exec.Command("sh", "-c", `trap 'echo stopped; exit' TERM; echo ready; while :; do sleep 0.05; done`)
+	stdout, err := cmd.StdoutPipe()
+	assert.Nil(t, err)
+	assert.Nil(t, cmd.Start())
+	defer cmd.Process.Kill()
+
+	lines := bufio.NewScanner(stdout)
+	assert.True(t, lines.Scan())
+	assert.Equal(t, "ready", lines.Text())
+
+	pidFile := filepath.Join(dir, "sauron.pid")
+	assert.Nil(t, ioutil.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0644))
+
+	pid, err := stopDaemon(pidFile)
+	assert.Nil(t, err)
+	assert.Equal(t, cmd.Process.Pid, pid)
+
+	assert.True(t, lines.Scan())
+	assert.Equal(t, "stopped", lines.Text())
+}
@@ -0,0 +1,53 @@
+package console
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// shutdownSignals are the signals stopping Sauron. Ctrl+C and Ctrl+Break are
+// delivered as an interrupt, while closing the console, logging off and
+// shutting down are delivered as SIGTERM.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// stillActive is the exit code reported for processes which haven't exited.
+const stillActive = 259
+
+// processQueryLimitedInformation is the access right needed to read the exit
+// code of a process.
+const processQueryLimitedInformation = 0x1000
+
+// watchLevelSignal does nothing, since there is no SIGUSR1 on Windows.
+func watchLevelSignal() {}
+
+// watchReloadSignal does nothing, since there is no SIGHUP on Windows.
+func watchReloadSignal(reload func()) {}
+
+// signalReload fails, since signals can't be sent to processes on Windows.
+func signalReload(pid int) error {
+	return errors.New("reload is not supported on Windows")
+}
+
+// signalStop fails, since signals can't be sent to processes on Windows.
+func signalStop(pid int) error {
+	return errors.New("stop is not supported on Windows")
+}
+
+// processRunning reports whether a process runs with the given pid. Signals
+// can't be sent to processes on Windows, so the process is opened and its exit
+// code checked instead.
+func processRunning(pid int) bool {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return false
+	}
+
+	return code == stillActive
+}
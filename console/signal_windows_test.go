@@ -0,0 +1,21 @@
+package console
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessRunning(t *testing.T) {
+	assert.True(t, processRunning(os.Getpid()))
+
+	running := exec.Command("ping", "-n", "30", "127.0.0.1")
+	assert.Nil(t, running.Start())
+	assert.True(t, processRunning(running.Process.Pid))
+
+	assert.Nil(t, running.Process.Kill())
+	running.Wait()
+	assert.False(t, processRunning(running.Process.Pid))
+}
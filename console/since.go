@@ -0,0 +1,111 @@
+package console
+
+import (
+	"regexp"
+	"time"
+)
+
+// since is the time before which lines are suppressed, set from the --since
+// flag at startup. Zero keeps every line.
+var since time.Time
+
+// parseSince parses the value of the --since flag: either a time in RFC 3339,
+// or a duration standing for that long before now.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// sinceFilter suppresses the lines of a watch whose embedded timestamp is
+// older than a cut-off.
+type sinceFilter struct {
+	*timestampParser
+	cutoff   time.Time
+	required bool
+}
+
+// newSinceFilter builds the filter of a watch, or returns nil when no cut-off
+// is set or the watch doesn't say how to find timestamps.
+func newSinceFilter(cutoff time.Time, w watch) *sinceFilter {
+	if cutoff.IsZero() {
+		return nil
+	}
+
+	parser := newTimestampParser(w.TimestampPattern, w.TimestampLayout)
+	if parser == nil {
+		return nil
+	}
+
+	return &sinceFilter{
+		timestampParser: parser,
+		cutoff:          cutoff,
+		required:        w.TimestampRequired,
+	}
+}
+
+// keep reports whether a line is recent enough. Lines without a parseable
+// timestamp are kept unless timestamps are required.
+func (f *sinceFilter) keep(text string) bool {
+	ts, ok := f.timestamp(text)
+	if !ok {
+		return !f.required
+	}
+
+	return !ts.Before(f.cutoff)
+}
+
+// timestampParser finds the timestamps embedded in the lines of a watch. The
+// timestamp is the "timestamp" group of a pattern, such as the
+// TimestampPattern of the watch, or its whole match, and is parsed with a
+// layout in local time. Layouts without a year, as in syslog, are given the
+// current one.
+type timestampParser struct {
+	reg    *regexp.Regexp
+	layout string
+	now    func() time.Time
+}
+
+// newTimestampParser builds the parser of the timestamps found by pattern and
+// written in layout, or returns nil when either is missing.
+func newTimestampParser(pattern, layout string) *timestampParser {
+	if pattern == "" || layout == "" {
+		return nil
+	}
+
+	reg, err := regexp.Compile(pattern)
+	if err != nil {
+		logger.Errorln("Invalid timestamp pattern " + pattern + ": " + err.Error())
+		return nil
+	}
+
+	return &timestampParser{reg: reg, layout: layout, now: time.Now}
+}
+
+// timestamp extracts and parses the timestamp of a line.
+func (p *timestampParser) timestamp(text string) (time.Time, bool) {
+	match := p.reg.FindStringSubmatch(text)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	value := match[0]
+	for i, name := range p.reg.SubexpNames() {
+		if name == "timestamp" {
+			value = match[i]
+		}
+	}
+
+	ts, err := time.ParseInLocation(p.layout, value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if ts.Year() == 0 {
+		ts = ts.AddDate(p.now().Year(), 0, 0)
+	}
+
+	return ts, true
+}
@@ -0,0 +1,114 @@
+package console
+
+import (
+	"../eye"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	cutoff, err := parseSince("90m", now)
+	assert.Nil(t, err)
+	assert.Equal(t, now.Add(-90*time.Minute), cutoff)
+
+	cutoff, err = parseSince("2024-03-10T08:00:00Z", now)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC), cutoff)
+
+	_, err = parseSince("yesterday-ish", now)
+	assert.NotNil(t, err)
+}
+
+func TestSinceFilter(t *testing.T) {
+	cutoff := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	w := watch{
+		TimestampPattern: `^\[(?P<timestamp>[^\]]+)\]`,
+		TimestampLayout:  "2006-01-02 15:04:05",
+	}
+
+	f := newSinceFilter(cutoff, w)
+	assert.NotNil(t, f)
+
+	assert.False(t, f.keep("[2024-03-10 11:59:59] before"))
+	assert.True(t, f.keep("[2024-03-10 12:00:00] at"))
+	assert.True(t, f.keep("[2024-03-10 12:00:01] after"))
+	assert.True(t, f.keep("no timestamp"))
+	assert.True(t, f.keep("[not a time] unparseable"))
+
+	w.TimestampRequired = true
+	f = newSinceFilter(cutoff, w)
+	assert.False(t, f.keep("no timestamp"))
+	assert.True(t, f.keep("[2024-03-10 12:00:01] after"))
+}
+
+func TestSinceFilterWithoutYear(t *testing.T) {
+	cutoff := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	f := newSinceFilter(cutoff, watch{
+		TimestampPattern: `^[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d`,
+		TimestampLayout:  time.Stamp,
+	})
+	f.now = func() time.Time { return cutoff }
+
+	assert.False(t, f.keep("Mar 10 11:00:00 host app: before"))
+	assert.True(t, f.keep("Mar 10 13:00:00 host app: after"))
+}
+
+func TestSinceFilterDisabled(t *testing.T) {
+	w := watch{TimestampPattern: `\d+`, TimestampLayout: "2006"}
+
+	assert.Nil(t, newSinceFilter(time.Time{}, w))
+	assert.Nil(t, newSinceFilter(time.Now(), watch{}))
+}
+
+func TestGetHandlerTimePattern(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	read := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	thisYear := time.Now().Year()
+
+	for i, test := range []struct {
+		pattern string
+		layout  string
+		line    string
+		time    time.Time
+	}{
+		{`^\S+`, time.RFC3339Nano, "2024-03-10T12:34:56.789+02:00 GET /", time.Date(2024, 3, 10, 10, 34, 56, 789000000, time.UTC)},
+		{`\[(?P<timestamp>[^\]]+)\]`, "02/Jan/2006:15:04:05 -0700", `127.0.0.1 - - [10/Mar/2024:12:34:56 -0500] "GET /" 200`, time.Date(2024, 3, 10, 17, 34, 56, 0, time.UTC)},
+		{`^\d{4}-\d\d-\d\d \d\d:\d\d:\d\d`, "2006-01-02 15:04:05", "2024-03-10 12:34:56 INFO started", time.Date(2024, 3, 10, 12, 34, 56, 0, time.Local)},
+		{`^[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d`, time.Stamp, "Mar 10 12:34:56 host app: started", time.Date(thisYear, 3, 10, 12, 34, 56, 0, time.Local)},
+		// Lines without a parseable time keep the time they were read.
+		{`^\S+`, time.RFC3339, "started without a time", read},
+	} {
+		w := watch{
+			TimePattern: test.pattern,
+			TimeLayout:  test.layout,
+			Format:      "json",
+			Out:         filepath.Join(dir, strconv.Itoa(i)+".log"),
+		}
+
+		routes, err := openRoutes(w)
+		assert.Nil(t, err)
+
+		handler := getHandler(newTestContext(), routes, nil, w)
+		assert.Nil(t, handler(eye.Line{Text: test.line, Time: read}))
+
+		out, err := ioutil.ReadFile(w.Out)
+		assert.Nil(t, err)
+
+		var record jsonLine
+		assert.Nil(t, json.Unmarshal(out, &record), test.line)
+		assert.True(t, test.time.Equal(record.Time), test.line+": "+record.Time.String())
+	}
+}
@@ -0,0 +1,76 @@
+package console
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// watchCounts counts the lines read and matched by a watch.
+type watchCounts struct {
+	name    string
+	read    uint64
+	matched uint64
+}
+
+// summary accumulates the activity of a session, reported on shutdown.
+type summary struct {
+	started time.Time
+	mutex   sync.Mutex
+	watches []*watchCounts
+}
+
+var report = newSummary()
+
+// newSummary starts a summary at the current time.
+func newSummary() *summary {
+	return &summary{started: time.Now()}
+}
+
+// countsFor returns the counters of a watch, keyed by its ID, so that watches
+// sharing a description are counted apart.
+func (s *summary) countsFor(w watch) *watchCounts {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	name := w.ID
+	if name == "" {
+		name = "watch"
+	}
+
+	for _, counts := range s.watches {
+		if counts.name == name {
+			return counts
+		}
+	}
+
+	counts := &watchCounts{name: name}
+	s.watches = append(s.watches, counts)
+
+	return counts
+}
+
+// String formats the summary on a single line, such as "Ran for 1m0s, read 10
+// lines, matched 4 (app: 8/3, db: 2/1)".
+func (s *summary) String() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var read, matched uint64
+	perWatch := ""
+	for i, counts := range s.watches {
+		r, m := atomic.LoadUint64(&counts.read), atomic.LoadUint64(&counts.matched)
+		read += r
+		matched += m
+
+		if i > 0 {
+			perWatch += ", "
+		}
+		perWatch += counts.name + ": " + strconv.FormatUint(r, 10) + "/" + strconv.FormatUint(m, 10)
+	}
+
+	return "Ran for " + time.Since(s.started).Round(time.Second).String() +
+		", read " + strconv.FormatUint(read, 10) + " lines, matched " + strconv.FormatUint(matched, 10) +
+		" (" + perWatch + ")"
+}
@@ -0,0 +1,37 @@
+package console
+
+import (
+	"../eye"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummary(t *testing.T) {
+	report = newSummary()
+	defer func() { report = newSummary() }()
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	// Watches are counted apart, though they share a description.
+	app := watch{ID: "app", Desc: "prod", LinePattern: "ERROR", Out: filepath.Join(dir, "app.log")}
+	db := watch{ID: "db", Desc: "prod", Out: filepath.Join(dir, "db.log")}
+
+	for _, w := range []watch{app, db} {
+		routes, err := openRoutes(w)
+		assert.Nil(t, err)
+
+		handler := getHandler(newTestContext(), routes, nil, w)
+		for _, text := range []string{"ERROR one", "INFO two", "ERROR three"} {
+			assert.Nil(t, handler(eye.Line{Text: text}))
+		}
+	}
+
+	assert.Equal(t, "Ran for 0s, read 6 lines, matched 5 (app: 3/2, db: 3/3)", report.String())
+}
@@ -0,0 +1,196 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Syslog severities, from RFC 5424.
+const (
+	syslogEmerg = iota
+	syslogAlert
+	syslogCrit
+	syslogErr
+	syslogWarning
+	syslogNotice
+	syslogInfo
+	syslogDebug
+)
+
+// noSeverity writes records to syslog outputs as they are, at info.
+const noSeverity = -1
+
+// levelSyslogSeverities are the syslog severities of the levels ordered by
+// severities, from trace to emergency.
+var levelSyslogSeverities = []int{
+	syslogDebug,
+	syslogDebug,
+	syslogInfo,
+	syslogNotice,
+	syslogWarning,
+	syslogErr,
+	syslogCrit,
+	syslogAlert,
+	syslogEmerg,
+}
+
+// syslogFacilities are the facilities lines can be sent with.
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// isSyslogOut reports whether an output names a syslog daemon, such as
+// syslog://host:514 over UDP, syslog+tcp://host:514, or syslog:// for the
+// local one.
+func isSyslogOut(path string) bool {
+	return strings.HasPrefix(path, "syslog://") || strings.HasPrefix(path, "syslog+tcp://")
+}
+
+// parseSyslogOut returns the network and the address of the daemon of a
+// syslog output, both empty for the local one, along with the facility and
+// the tag of its lines, given as the facility and tag parameters, such as
+// syslog://host:514?facility=local0&tag=app. They default to user and sauron.
+func parseSyslogOut(path string) (network, address string, facility int, tag string, err error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", "", 0, "", err
+	}
+
+	if u.Host != "" {
+		network, address = "udp", u.Host
+		if u.Scheme == "syslog+tcp" {
+			network = "tcp"
+		}
+	} else if u.Scheme == "syslog+tcp" {
+		return "", "", 0, "", errors.New("invalid syslog output " + path + ", expected syslog+tcp://host:port")
+	}
+
+	facility = syslogFacilities["user"]
+	if name := u.Query().Get("facility"); name != "" {
+		var ok bool
+		if facility, ok = syslogFacilities[strings.ToLower(name)]; !ok {
+			return "", "", 0, "", errors.New("unknown syslog facility " + name)
+		}
+	}
+
+	tag = u.Query().Get("tag")
+	if tag == "" {
+		tag = "sauron"
+	}
+
+	return network, address, facility, tag, nil
+}
+
+// parseSyslogSeverity returns the syslog severity of a level name, accepting
+// the level names found in logs as well, such as error or warn.
+func parseSyslogSeverity(name string) (int, bool) {
+	level, ok := severities[strings.ToLower(name)]
+	if !ok {
+		return 0, false
+	}
+
+	return levelSyslogSeverities[level], true
+}
+
+// severityMapper chooses the severity of the lines a watch sends to syslog.
+type severityMapper struct {
+	regs       []*regexp.Regexp
+	severities []int
+	levelReg   *regexp.Regexp
+	fallback   int
+}
+
+// newSeverityMapper builds the mapper of a watch, or returns nil when the
+// watch doesn't set Syslog, its lines being sent at info. Invalid patterns and
+// unknown severities are logged and skipped.
+func newSeverityMapper(w watch) *severityMapper {
+	if w.Syslog == nil {
+		return nil
+	}
+
+	m := &severityMapper{fallback: syslogInfo}
+	if w.Syslog.Severity != "" {
+		if severity, ok := parseSyslogSeverity(w.Syslog.Severity); ok {
+			m.fallback = severity
+		} else {
+			logger.Errorln("Unknown syslog severity " + w.Syslog.Severity + ", using info instead")
+		}
+	}
+
+	for _, r := range w.Syslog.SeverityMap {
+		severity, ok := parseSyslogSeverity(r.Severity)
+		if !ok {
+			logger.Errorln("Unknown syslog severity " + r.Severity + " of pattern " + r.Pattern)
+			continue
+		}
+
+		reg, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			logger.Errorln("Invalid severity pattern " + r.Pattern + ": " + err.Error())
+			continue
+		}
+
+		m.regs = append(m.regs, reg)
+		m.severities = append(m.severities, severity)
+	}
+
+	if w.Syslog.SeverityFromLevel {
+		if w.LevelPattern == "" {
+			logger.Errorln("SeverityFromLevel needs a LevelPattern")
+		} else if reg, err := regexp.Compile(w.LevelPattern); err != nil {
+			logger.Errorln("Invalid level pattern " + w.LevelPattern + ": " + err.Error())
+		} else {
+			m.levelReg = reg
+		}
+	}
+
+	return m
+}
+
+// severity returns the severity of a line: that of the first pattern it
+// matches, else that of its level, else the default one.
+func (m *severityMapper) severity(text string) int {
+	for i, reg := range m.regs {
+		if reg.MatchString(text) {
+			return m.severities[i]
+		}
+	}
+
+	if m.levelReg != nil {
+		if level, ok := findLevel(m.levelReg, text); ok {
+			return levelSyslogSeverities[level]
+		}
+	}
+
+	return m.fallback
+}
+
+// writeSeverity writes a record formatted in buf to an output at severity,
+// logging failures, as write does.
+func writeSeverity(buf *bytes.Buffer, severity int, out *output) {
+	if _, err := out.WriteSeverity(buf.Bytes(), severity); err != nil {
+		logger.Errorln(err)
+	}
+}
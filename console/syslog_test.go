@@ -0,0 +1,71 @@
+package console
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSyslogOut(t *testing.T) {
+	network, address, facility, tag, err := parseSyslogOut("syslog://")
+	assert.Nil(t, err)
+	assert.Equal(t, "", network)
+	assert.Equal(t, "", address)
+	assert.Equal(t, 1, facility)
+	assert.Equal(t, "sauron", tag)
+
+	network, address, facility, tag, err = parseSyslogOut("syslog://logs:514?facility=local0&tag=app")
+	assert.Nil(t, err)
+	assert.Equal(t, "udp", network)
+	assert.Equal(t, "logs:514", address)
+	assert.Equal(t, 16, facility)
+	assert.Equal(t, "app", tag)
+
+	network, address, _, _, err = parseSyslogOut("syslog+tcp://logs:601")
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "logs:601", address)
+
+	_, _, _, _, err = parseSyslogOut("syslog://logs:514?facility=nope")
+	assert.NotNil(t, err)
+	_, _, _, _, err = parseSyslogOut("syslog+tcp://")
+	assert.NotNil(t, err)
+}
+
+func TestSeverityMapper(t *testing.T) {
+	assert.Nil(t, newSeverityMapper(watch{}))
+
+	m := newSeverityMapper(watch{
+		LevelPattern: `level=(?P<level>\w+)`,
+		Syslog: &syslogOut{
+			Severity: "notice",
+			SeverityMap: []severityRule{
+				{Pattern: `\bERROR\b`, Severity: "err"},
+				{Pattern: `\bINFO\b`, Severity: "info"},
+				{Pattern: `(`, Severity: "crit"},
+				{Pattern: `PANIC`, Severity: "nope"},
+				// Level names found in logs are accepted too.
+				{Pattern: `\bWARN\b`, Severity: "warn"},
+			},
+			SeverityFromLevel: true,
+		},
+	})
+
+	for text, severity := range map[string]int{
+		"ERROR disk full":          syslogErr,
+		"INFO started":             syslogInfo,
+		"WARN slow":                syslogWarning,
+		"ERROR level=debug":        syslogErr,
+		"level=critical down":      syslogCrit,
+		"level=trace":              syslogDebug,
+		"level=unknown":            syslogNotice,
+		"PANIC without a severity": syslogNotice,
+		"nothing special":          syslogNotice,
+	} {
+		assert.Equal(t, severity, m.severity(text), text)
+	}
+
+	// Lines are sent at info by default.
+	m = newSeverityMapper(watch{Syslog: &syslogOut{Severity: "nope"}})
+	assert.Equal(t, syslogInfo, m.severity("ERROR disk full"))
+}
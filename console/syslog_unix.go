@@ -0,0 +1,61 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import (
+	"log/syslog"
+)
+
+// syslogSink sends lines to a syslog daemon. Lines written without a severity,
+// such as heartbeats, are sent at info. It is safe for concurrent use.
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+// dialSyslogSink connects to the daemon of a syslog output.
+func dialSyslogSink(path string) (*syslogSink, error) {
+	network, address, facility, tag, err := parseSyslogOut(path)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := syslog.Dial(network, address, syslog.Priority(facility<<3)|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &syslogSink{writer: writer}, nil
+}
+
+// send sends a message at severity.
+func (s *syslogSink) send(severity int, message string) error {
+	switch severity {
+	case syslogEmerg:
+		return s.writer.Emerg(message)
+	case syslogAlert:
+		return s.writer.Alert(message)
+	case syslogCrit:
+		return s.writer.Crit(message)
+	case syslogErr:
+		return s.writer.Err(message)
+	case syslogWarning:
+		return s.writer.Warning(message)
+	case syslogNotice:
+		return s.writer.Notice(message)
+	case syslogDebug:
+		return s.writer.Debug(message)
+	}
+
+	return s.writer.Info(message)
+}
+
+// Write sends p at info.
+func (s *syslogSink) Write(p []byte) (int, error) {
+	return s.writer.Write(p)
+}
+
+// Close closes the connection to the daemon.
+func (s *syslogSink) Close() error {
+	return s.writer.Close()
+}
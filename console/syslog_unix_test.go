@@ -0,0 +1,121 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import (
+	"../eye"
+	"net"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHandlerSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+	defer closeOutputs()
+
+	w := watch{
+		Out:          "syslog://" + conn.LocalAddr().String() + "?facility=local0&tag=app",
+		LevelPattern: `^(?P<level>[A-Z]+) `,
+		Syslog: &syslogOut{
+			SeverityMap:       []severityRule{{Pattern: `disk full`, Severity: "crit"}},
+			SeverityFromLevel: true,
+		},
+	}
+
+	routes, err := openRoutes(w)
+	assert.Nil(t, err)
+
+	handler := getHandler(newTestContext(), routes, nil, w)
+	for _, text := range []string{
+		"ERROR connection refused",
+		"INFO started",
+		"WARN slow query",
+		"ERROR disk full",
+		"no level",
+	} {
+		assert.Nil(t, handler(eye.Line{Text: text}))
+	}
+
+	// Priorities are the facility, local0 or 16, times 8 plus the severity.
+	message := regexp.MustCompile(`^<(\d+)>\S+ \S+ app\[\d+\]: (.*)\n$`)
+	received := make(map[string]int)
+	buf := make([]byte, 1024)
+	for len(received) < 5 {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if !assert.Nil(t, err) {
+			break
+		}
+
+		match := message.FindStringSubmatch(string(buf[:n]))
+		if !assert.NotNil(t, match, string(buf[:n])) {
+			break
+		}
+
+		priority, _ := strconv.Atoi(match[1])
+		received[match[2]] = priority - 16*8
+	}
+
+	assert.Equal(t, map[string]int{
+		"ERROR connection refused": syslogErr,
+		"INFO started":             syslogInfo,
+		"WARN slow query":          syslogWarning,
+		"ERROR disk full":          syslogCrit,
+		"no level":                 syslogInfo,
+	}, received)
+}
+
+func TestSyslogSeverityHeldByBreaker(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	sink, err := dialSyslogSink("syslog://" + conn.LocalAddr().String())
+	assert.Nil(t, err)
+
+	now := time.Unix(0, 0)
+	out := newSinkOutput("syslog", sink)
+	out.breaker = &breaker{name: "syslog", threshold: 1, cooldown: time.Minute, policy: breakerBuffer, now: func() time.Time { return now }}
+	defer out.Close()
+
+	// Lines held while the breaker is open keep their severity.
+	out.breaker.failure()
+	_, err = out.WriteSeverity([]byte("ERROR disk full\n"), syslogCrit)
+	assert.Nil(t, err)
+
+	now = now.Add(time.Minute)
+	_, err = out.WriteSeverity([]byte("INFO recovered\n"), syslogInfo)
+	assert.Nil(t, err)
+
+	// Priorities are the facility, user or 1, times 8 plus the severity.
+	message := regexp.MustCompile(`^<(\d+)>\S+ \S+ sauron\[\d+\]: (.*)\n$`)
+	var received []string
+	buf := make([]byte, 1024)
+	for len(received) < 2 {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if !assert.Nil(t, err) {
+			break
+		}
+
+		match := message.FindStringSubmatch(string(buf[:n]))
+		if !assert.NotNil(t, match, string(buf[:n])) {
+			break
+		}
+
+		priority, _ := strconv.Atoi(match[1])
+		received = append(received, strconv.Itoa(priority-8)+" "+match[2])
+	}
+
+	assert.Equal(t, []string{
+		strconv.Itoa(syslogCrit) + " ERROR disk full",
+		strconv.Itoa(syslogInfo) + " INFO recovered",
+	}, received)
+}
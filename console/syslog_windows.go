@@ -0,0 +1,25 @@
+package console
+
+import (
+	"errors"
+)
+
+// syslogSink stands for the syslog outputs, which Windows doesn't support.
+type syslogSink struct{}
+
+// dialSyslogSink fails, syslog outputs not being supported on Windows.
+func dialSyslogSink(path string) (*syslogSink, error) {
+	return nil, errors.New("syslog output " + path + " is not supported on Windows")
+}
+
+func (s *syslogSink) send(severity int, message string) error {
+	return errors.New("syslog is not supported on Windows")
+}
+
+func (s *syslogSink) Write(p []byte) (int, error) {
+	return 0, errors.New("syslog is not supported on Windows")
+}
+
+func (s *syslogSink) Close() error {
+	return nil
+}
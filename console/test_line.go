@@ -0,0 +1,82 @@
+package console
+
+import (
+	"../eye"
+	"fmt"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// TestLineAction runs a sample line given as argument through every watch of
+// the config and prints, for each of their outputs, whether the line would be
+// written and how.
+func TestLineAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.NewExitError("usage: sauron test-line [--conf=file] \"sample line\"", 1)
+	}
+
+	conf, result := setConfig(c)
+	if !result {
+		return cli.NewExitError("failed to load the config", 1)
+	}
+
+	testLine(c, conf, c.Args().First(), c.App.Writer)
+
+	return nil
+}
+
+// testLine writes the verdict of every output of every watch for text to out.
+func testLine(c *cli.Context, conf Config, text string, out io.Writer) {
+	for i, w := range conf.Watch {
+		name := "watch #" + strconv.Itoa(i+1)
+		if w.Desc != "" {
+			name += " (" + w.Desc + ")"
+		}
+
+		line := eye.Line{Text: text, Time: time.Now(), Offset: -1}
+		if len(w.Paths) > 0 {
+			line.Path = w.Paths[0]
+		}
+
+		ignoreReg := compilePattern(anyPattern(w.LineIgnorePattern, w.LineIgnorePatterns))
+
+		for _, r := range watchRules(w) {
+			destination := r.Out
+			if destination == "" || destination == "-" {
+				destination = "stdout"
+			}
+
+			switch lineVerdict(text, compilePattern(r.LinePattern), ignoreReg, w) {
+			case verdictMatch:
+				fmt.Fprintf(out, "%s: match -> %s\n\t%s\n", name, destination, formatLine(c, line, w))
+			case verdictIgnored:
+				fmt.Fprintf(out, "%s: ignored -> %s\n", name, destination)
+			default:
+				fmt.Fprintf(out, "%s: no match -> %s\n", name, destination)
+			}
+		}
+	}
+}
+
+const (
+	verdictMatch   = "match"
+	verdictIgnored = "ignored"
+	verdictNoMatch = "no match"
+)
+
+// lineVerdict tells apart lines that would be written from lines rejected by
+// the ignore pattern or LineNotContains, and from lines failing to match.
+func lineVerdict(text string, lineReg *regexp.Regexp, ignoreReg *regexp.Regexp, w watch) string {
+	if matchLine(text, lineReg, ignoreReg, w) {
+		return verdictMatch
+	}
+
+	if (ignoreReg != nil && ignoreReg.MatchString(text)) || containsAny(text, w.LineNotContains) {
+		return verdictIgnored
+	}
+
+	return verdictNoMatch
+}
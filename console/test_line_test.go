@@ -0,0 +1,40 @@
+package console
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestLine(t *testing.T) {
+	conf := Config{Watch: []watch{
+		{Desc: "errors", LinePattern: "ERROR", LineIgnorePattern: "healthcheck", Out: "errors.log"},
+		{LineNotContains: []string{"disk"}, Out: "-"},
+		{Desc: "routed", Rules: []rule{{LinePattern: "WARN", Out: "warnings.log"}}},
+	}}
+
+	var out bytes.Buffer
+	testLine(newTestContext(), conf, "ERROR disk full", &out)
+
+	assert.Equal(t, "watch #1 (errors): match -> errors.log\n"+
+		"\t[errors] ERROR disk full\n"+
+		"watch #2: ignored -> stdout\n"+
+		"watch #3 (routed): no match -> warnings.log\n", out.String())
+
+	out.Reset()
+	testLine(newTestContext(), conf, "ERROR healthcheck", &out)
+
+	assert.Equal(t, "watch #1 (errors): ignored -> errors.log\n"+
+		"watch #2: match -> stdout\n"+
+		"\tERROR healthcheck\n"+
+		"watch #3 (routed): no match -> warnings.log\n", out.String())
+
+	out.Reset()
+	testLine(newTestContext(), conf, "INFO all good", &out)
+
+	assert.Equal(t, "watch #1 (errors): no match -> errors.log\n"+
+		"watch #2: match -> stdout\n"+
+		"\tINFO all good\n"+
+		"watch #3 (routed): no match -> warnings.log\n", out.String())
+}
@@ -0,0 +1,173 @@
+package console
+
+import (
+	"fmt"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slowPatternThreshold is how long a pattern may take to match a synthetic
+// line before it is reported as slow.
+var slowPatternThreshold = 10 * time.Millisecond
+
+// syntheticLines are the lines patterns are benchmarked against: long runs of
+// the characters patterns usually repeat, which fail to match at the very end.
+var syntheticLines = []string{
+	strings.Repeat("a", 4096) + "!",
+	strings.Repeat("0", 4096) + "!",
+	strings.Repeat("a ", 2048) + "!",
+	strings.Repeat("ab1 ", 1024) + "\x00",
+}
+
+// patternIssue is a problem found with a pattern of the config.
+type patternIssue struct {
+	watch   string
+	field   string
+	pattern string
+	problem string
+}
+
+func (i patternIssue) String() string {
+	return i.watch + ": " + i.field + " " + strconv.Quote(i.pattern) + ": " + i.problem
+}
+
+// ValidateAction compiles every pattern of the config and benchmarks it
+// against synthetic lines, reporting the invalid and slow ones. Issues only
+// fail the command with --strict.
+func ValidateAction(c *cli.Context) error {
+	conf, result := setConfig(c)
+	if !result {
+		return cli.NewExitError("failed to load the config", 1)
+	}
+
+	issues := validatePatterns(conf, slowPatternThreshold)
+	reportIssues(issues, c.App.Writer)
+
+	if len(issues) > 0 && c.Bool("strict") {
+		return cli.NewExitError("the config has "+strconv.Itoa(len(issues))+" pattern issues", 1)
+	}
+
+	return nil
+}
+
+// reportIssues writes the issues found with the patterns of the config to out.
+func reportIssues(issues []patternIssue, out io.Writer) {
+	if len(issues) == 0 {
+		fmt.Fprintln(out, "Every pattern is valid")
+		return
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintln(out, issue)
+	}
+}
+
+// validatePatterns returns the issues found with the patterns of every watch:
+// those which don't compile, and those taking longer than threshold to match
+// one of the synthetic lines.
+func validatePatterns(conf Config, threshold time.Duration) []patternIssue {
+	var issues []patternIssue
+
+	for i, w := range conf.Watch {
+		name := "watch #" + strconv.Itoa(i+1)
+		if w.Desc != "" {
+			name += " (" + w.Desc + ")"
+		}
+
+		for _, p := range watchPatterns(w) {
+			issue := patternIssue{watch: name, field: p.field, pattern: p.pattern}
+
+			reg, err := regexp.Compile(p.pattern)
+			if err != nil {
+				issue.problem = "invalid: " + err.Error()
+				issues = append(issues, issue)
+				continue
+			}
+
+			if elapsed := benchmarkPattern(reg); elapsed > threshold {
+				issue.problem = "slow, " + elapsed.String() + " to match a line of " +
+					strconv.Itoa(len(syntheticLines[0])) + " characters; " + patternSuggestion(p.pattern)
+				issues = append(issues, issue)
+			}
+		}
+	}
+
+	return issues
+}
+
+// namedPattern is a pattern of a watch, along with the field setting it.
+type namedPattern struct {
+	field   string
+	pattern string
+}
+
+// watchPatterns lists the non-empty patterns of a watch.
+func watchPatterns(w watch) []namedPattern {
+	var patterns []namedPattern
+	for _, f := range patternFields(&w) {
+		if *f.pattern != "" {
+			patterns = append(patterns, namedPattern{f.field, *f.pattern})
+		}
+	}
+
+	return patterns
+}
+
+// benchmarkPattern returns the longest time reg takes to match one of the
+// synthetic lines.
+func benchmarkPattern(reg *regexp.Regexp) time.Duration {
+	var longest time.Duration
+	for _, line := range syntheticLines {
+		start := time.Now()
+		reg.MatchString(line)
+
+		if elapsed := time.Since(start); elapsed > longest {
+			longest = elapsed
+		}
+	}
+
+	return longest
+}
+
+// patternSuggestion tells how a slow pattern could be rewritten. Patterns
+// always run in linear time, but nested or large bounded repetitions multiply
+// the work done for every character.
+func patternSuggestion(pattern string) string {
+	if hasNestedRepeat(pattern) {
+		return "avoid nesting repetitions such as (a+)+ or (?:a{1,30}){1,30}, repeat the inner expression once instead, as in a+"
+	}
+
+	return "prefer anchors and literal prefixes to large bounded repetitions and alternations"
+}
+
+// hasNestedRepeat reports whether pattern repeats an expression which is
+// itself repeated.
+func hasNestedRepeat(pattern string) bool {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return false
+	}
+
+	var walk func(re *syntax.Regexp, repeated bool) bool
+	walk = func(re *syntax.Regexp, repeated bool) bool {
+		isRepeat := re.Op == syntax.OpStar || re.Op == syntax.OpPlus || re.Op == syntax.OpRepeat
+		if isRepeat && repeated {
+			return true
+		}
+
+		for _, sub := range re.Sub {
+			if walk(sub, repeated || isRepeat) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return walk(re, false)
+}
@@ -0,0 +1,99 @@
+package console
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// slowPattern nests bounded repetitions, which multiplies the states tried
+// for every character.
+const slowPattern = `(?:a{1,30}){1,30}b`
+
+func TestValidatePatterns(t *testing.T) {
+	conf := Config{Watch: []watch{
+		{Desc: "app", LinePattern: "^ERROR", LineIgnorePattern: "(", FilePattern: `\.log$`},
+		{LinePatterns: []string{"WARN", slowPattern}},
+	}}
+
+	issues := validatePatterns(conf, slowPatternThreshold)
+	assert.Equal(t, 2, len(issues))
+
+	assert.Equal(t, "watch #1 (app)", issues[0].watch)
+	assert.Equal(t, "LineIgnorePattern", issues[0].field)
+	assert.True(t, strings.HasPrefix(issues[0].problem, "invalid: "))
+
+	assert.Equal(t, "watch #2", issues[1].watch)
+	assert.Equal(t, "LinePatterns", issues[1].field)
+	assert.Equal(t, slowPattern, issues[1].pattern)
+	assert.True(t, strings.HasPrefix(issues[1].problem, "slow, "))
+	assert.Contains(t, issues[1].problem, "avoid nesting repetitions")
+}
+
+func TestBenchmarkPattern(t *testing.T) {
+	slow := benchmarkPattern(regexp.MustCompile(slowPattern))
+	fast := benchmarkPattern(regexp.MustCompile("^ERROR"))
+
+	assert.True(t, slow > slowPatternThreshold)
+	assert.True(t, fast < slowPatternThreshold)
+}
+
+func TestHasNestedRepeat(t *testing.T) {
+	assert.True(t, hasNestedRepeat(`(a+)+b`))
+	assert.True(t, hasNestedRepeat(slowPattern))
+	assert.True(t, hasNestedRepeat(`(\w+\s?)*$`))
+	assert.False(t, hasNestedRepeat(`^ERROR \d+ (\w+)`))
+	assert.False(t, hasNestedRepeat(`(`))
+}
+
+func TestValidateActionStrict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := filepath.Join(dir, "sauron.toml")
+	assert.Nil(t, ioutil.WriteFile(conf, []byte(`
+[[Watch]]
+Paths = ["/var/log"]
+LinePattern = "`+slowPattern+`"
+`), 0644))
+
+	run := func(strict bool) (string, error) {
+		set := flag.NewFlagSet("test", 0)
+		set.String("conf", conf, "")
+		set.Bool("strict", strict, "")
+
+		var out bytes.Buffer
+		app := cli.NewApp()
+		app.Writer = &out
+
+		err := ValidateAction(cli.NewContext(app, set, nil))
+
+		return out.String(), err
+	}
+
+	// Issues are reported, but only fail the command with --strict.
+	out, err := run(false)
+	assert.Nil(t, err)
+	assert.Contains(t, out, "watch #1: LinePattern")
+
+	out, err = run(true)
+	assert.NotNil(t, err)
+	assert.Contains(t, out, "slow, ")
+
+	defer func(threshold time.Duration) { slowPatternThreshold = threshold }(slowPatternThreshold)
+	slowPatternThreshold = time.Hour
+
+	out, err = run(true)
+	assert.Nil(t, err)
+	assert.Equal(t, "Every pattern is valid\n", out)
+}
@@ -0,0 +1,403 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Settings of the webhooks opened afterwards, set from the config at startup.
+// Lines are posted in batches of webhookBatchSize lines, or once the oldest
+// line of a batch waited for webhookFlushInterval, whichever comes first. Up
+// to webhookQueueSize batches wait for their post, beyond which new batches
+// are dropped.
+var (
+	webhookBatchSize     = 100
+	webhookFlushInterval = time.Second
+	webhookTimeout       = 10 * time.Second
+	webhookQueueSize     = 10
+)
+
+// errWebhookClosed is returned by the writes to a closed webhook.
+var errWebhookClosed = errors.New("the webhook is closed")
+
+// webhookFailure is the class of a failed post, each retried up to its own
+// limit.
+type webhookFailure int
+
+const (
+	dnsFailure     webhookFailure = iota // the endpoint failed to resolve
+	connectFailure                       // the connection was refused or failed
+	timeoutFailure                       // the endpoint didn't answer in time
+	serverFailure                        // the endpoint answered with a 5xx status
+	otherFailure                         // anything else, such as a 4xx status
+
+	webhookFailureClasses
+)
+
+// webhookFailureNames name the classes of failures in the metrics.
+var webhookFailureNames = [webhookFailureClasses]string{"dns", "connect", "timeout", "http_5xx", "other"}
+
+// Retries of the webhooks opened afterwards, set from the config at startup:
+// a batch is posted again as long as the failures of each class stay within
+// its retries, waiting webhookRetryDelay before the first retry and twice as
+// long before each next one. Other failures aren't retried.
+var (
+	webhookRetries    = [webhookFailureClasses]int{3, 3, 2, 3, 0}
+	webhookRetryDelay = 200 * time.Millisecond
+)
+
+// webhookStatusError is the failure of a post the endpoint answered with a
+// status other than 2xx.
+type webhookStatusError struct {
+	url    string
+	status string
+	code   int
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook %s answered %s", e.url, e.status)
+}
+
+// webhook posts lines to an HTTP endpoint as JSON, in batches of the form
+// {"lines": ["...", "..."]}. Batches are posted by a goroutine of their own,
+// so that writing lines never waits for the endpoint. What is left of the
+// batch is posted on Close. It is safe for concurrent use.
+type webhook struct {
+	url      string
+	client   *http.Client
+	size     int
+	interval time.Duration
+
+	retries    [webhookFailureClasses]int
+	retryDelay time.Duration
+
+	mutex      sync.Mutex
+	batch      []string
+	generation int
+	timer      *time.Timer
+	closed     bool
+
+	queue chan []string
+	done  chan bool
+
+	sent     uint64
+	dropped  uint64
+	failures [webhookFailureClasses]uint64
+}
+
+// isWebhookOut reports whether an output names a webhook.
+func isWebhookOut(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// newWebhook creates the webhook of an endpoint with the current settings.
+func newWebhook(url string) *webhook {
+	size := webhookBatchSize
+	if size <= 0 {
+		size = 1
+	}
+
+	queueSize := webhookQueueSize
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+
+	h := &webhook{
+		url:        url,
+		client:     &http.Client{Timeout: webhookTimeout},
+		size:       size,
+		interval:   webhookFlushInterval,
+		retries:    webhookRetries,
+		retryDelay: webhookRetryDelay,
+		queue:      make(chan []string, queueSize),
+		done:       make(chan bool),
+	}
+
+	go h.run()
+
+	return h
+}
+
+// Write adds a line to the batch, queueing it for its post when full. When
+// the queue is full, the other lines of the batch are dropped while an error
+// is returned for the line written, so that the output may hold it.
+func (h *webhook) Write(p []byte) (int, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		return 0, errWebhookClosed
+	}
+
+	h.batch = append(h.batch, strings.TrimRight(string(p), "\r\n\x00"))
+
+	if len(h.batch) < h.size {
+		if len(h.batch) == 1 && h.interval > 0 {
+			generation := h.generation
+			h.timer = time.AfterFunc(h.interval, func() { h.flushAfterInterval(generation) })
+		}
+
+		return len(p), nil
+	}
+
+	if err := h.flush(true); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// flushAfterInterval queues the batch of the given generation, unless it was
+// already queued for being full.
+func (h *webhook) flushAfterInterval(generation int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if generation != h.generation {
+		return
+	}
+
+	if err := h.flush(false); err != nil {
+		logger.Errorln(err)
+	}
+}
+
+// flush queues the batch for its post and starts a new one. When the queue is
+// full, the lines of the batch are counted as dropped, except for the last
+// one when keepLast is set.
+func (h *webhook) flush(keepLast bool) error {
+	batch := h.takeBatch()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	select {
+	case h.queue <- batch:
+		return nil
+	default:
+	}
+
+	dropped := len(batch)
+	if keepLast {
+		dropped--
+	}
+	atomic.AddUint64(&h.dropped, uint64(dropped))
+
+	return errors.New("webhook " + h.url + " is falling behind, dropping a batch")
+}
+
+// takeBatch returns the batch and starts a new one.
+func (h *webhook) takeBatch() []string {
+	batch := h.batch
+	h.batch = nil
+	h.generation++
+
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+
+	return batch
+}
+
+// run posts the queued batches until the queue is closed. The lines of the
+// batches which fail are counted as dropped.
+func (h *webhook) run() {
+	defer close(h.done)
+
+	for batch := range h.queue {
+		if err := h.send(batch); err != nil {
+			atomic.AddUint64(&h.dropped, uint64(len(batch)))
+			logger.WithField("url", h.url).Errorln("Dropping " + strconv.Itoa(len(batch)) + " lines: " + err.Error())
+			continue
+		}
+
+		atomic.AddUint64(&h.sent, 1)
+	}
+}
+
+// send posts a batch, retrying it while the failures of each class stay
+// within their retries. It runs on the sender goroutine, so that only the
+// batches queued behind wait meanwhile, not the lines written.
+func (h *webhook) send(lines []string) error {
+	var failures [webhookFailureClasses]int
+	delay := h.retryDelay
+
+	for {
+		err := h.post(lines)
+		if err == nil {
+			return nil
+		}
+
+		class := classifyWebhookError(err)
+		atomic.AddUint64(&h.failures[class], 1)
+
+		if failures[class] >= h.retries[class] {
+			return err
+		}
+		failures[class]++
+
+		logger.WithField("url", h.url).Warnln("Retrying after a " + webhookFailureNames[class] + " failure: " + err.Error())
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// classifyWebhookError returns the class of the failure of a post. Failures
+// to resolve the endpoint are told apart from the other failures to dial it,
+// be it by timing out or by being refused.
+func classifyWebhookError(err error) webhookFailure {
+	if e, ok := err.(*webhookStatusError); ok {
+		if e.code >= 500 {
+			return serverFailure
+		}
+		return otherFailure
+	}
+
+	cause := err
+	if e, ok := cause.(*url.Error); ok {
+		cause = e.Err
+	}
+
+	op, _ := cause.(*net.OpError)
+	if op != nil {
+		cause = op.Err
+	}
+
+	if _, ok := cause.(*net.DNSError); ok {
+		return dnsFailure
+	}
+
+	if e, ok := err.(interface {
+		Timeout() bool
+	}); ok && e.Timeout() {
+		return timeoutFailure
+	}
+
+	if op != nil && op.Op == "dial" {
+		return connectFailure
+	}
+
+	return otherFailure
+}
+
+// post sends a batch of lines to the endpoint.
+func (h *webhook) post(lines []string) error {
+	body, err := json.Marshal(struct {
+		Lines []string `json:"lines"`
+	}{lines})
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &webhookStatusError{url: h.url, status: resp.Status, code: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// Close posts what is left of the batch, returning once every queued batch
+// was posted. Closing it again does nothing.
+func (h *webhook) Close() error {
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		return nil
+	}
+	h.closed = true
+	batch := h.takeBatch()
+	h.mutex.Unlock()
+
+	if len(batch) > 0 {
+		h.queue <- batch
+	}
+	close(h.queue)
+	<-h.done
+
+	return nil
+}
+
+// openWebhooks returns the webhooks among the open outputs.
+func openWebhooks() []*webhook {
+	outputsMutex.Lock()
+	defer outputsMutex.Unlock()
+
+	var webhooks []*webhook
+	for _, out := range outputs {
+		if h, ok := out.sink.(*webhook); ok {
+			webhooks = append(webhooks, h)
+		}
+	}
+
+	return webhooks
+}
+
+// openKafkaProducers returns the Kafka producers among the open outputs.
+func openKafkaProducers() []*kafkaProducer {
+	outputsMutex.Lock()
+	defer outputsMutex.Unlock()
+
+	var producers []*kafkaProducer
+	for _, out := range outputs {
+		if p, ok := out.sink.(*kafkaProducer); ok {
+			producers = append(producers, p)
+		}
+	}
+
+	return producers
+}
+
+// serveMetrics writes the counters of the webhooks and the Kafka producers,
+// and the handler latency of the watches, in the Prometheus text format.
+func serveMetrics(w http.ResponseWriter, req *http.Request) {
+	webhooks := openWebhooks()
+	producers := openKafkaProducers()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	fmt.Fprintln(w, "# TYPE sauron_webhook_batches_sent_total counter")
+	for _, h := range webhooks {
+		fmt.Fprintf(w, "sauron_webhook_batches_sent_total{url=%q} %d\n", h.url, atomic.LoadUint64(&h.sent))
+	}
+
+	fmt.Fprintln(w, "# TYPE sauron_webhook_lines_dropped_total counter")
+	for _, h := range webhooks {
+		fmt.Fprintf(w, "sauron_webhook_lines_dropped_total{url=%q} %d\n", h.url, atomic.LoadUint64(&h.dropped))
+	}
+
+	fmt.Fprintln(w, "# TYPE sauron_webhook_failures_total counter")
+	for _, h := range webhooks {
+		for class, name := range webhookFailureNames {
+			fmt.Fprintf(w, "sauron_webhook_failures_total{url=%q,class=%q} %d\n", h.url, name, atomic.LoadUint64(&h.failures[class]))
+		}
+	}
+
+	fmt.Fprintln(w, "# TYPE sauron_kafka_batches_sent_total counter")
+	for _, p := range producers {
+		fmt.Fprintf(w, "sauron_kafka_batches_sent_total{topic=%q} %d\n", p.topic, atomic.LoadUint64(&p.sent))
+	}
+
+	fmt.Fprintln(w, "# TYPE sauron_kafka_lines_dropped_total counter")
+	for _, p := range producers {
+		fmt.Fprintf(w, "sauron_kafka_lines_dropped_total{topic=%q} %d\n", p.topic, atomic.LoadUint64(&p.dropped))
+	}
+
+	writeLatencyMetrics(w)
+}
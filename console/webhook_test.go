@@ -0,0 +1,326 @@
+package console
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newBatchServer starts an endpoint sending the batches it receives to
+// batches, answering with status.
+func newBatchServer(t *testing.T, status int) (*httptest.Server, chan []string) {
+	batches := make(chan []string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Lines []string `json:"lines"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		batches <- body.Lines
+		w.WriteHeader(status)
+	}))
+
+	return server, batches
+}
+
+func withWebhookSettings(size int, interval time.Duration) func() {
+	batchSize, flushInterval := webhookBatchSize, webhookFlushInterval
+	webhookBatchSize, webhookFlushInterval = size, interval
+
+	return func() {
+		webhookBatchSize, webhookFlushInterval = batchSize, flushInterval
+	}
+}
+
+func withWebhookQueueSize(size int) func() {
+	previous := webhookQueueSize
+	webhookQueueSize = size
+
+	return func() {
+		webhookQueueSize = previous
+	}
+}
+
+func withWebhookRetries(retries [webhookFailureClasses]int, delay time.Duration) func() {
+	previous, previousDelay := webhookRetries, webhookRetryDelay
+	webhookRetries, webhookRetryDelay = retries, delay
+
+	return func() {
+		webhookRetries, webhookRetryDelay = previous, previousDelay
+	}
+}
+
+// webhookTransport answers the posts of a webhook in place of an endpoint.
+type webhookTransport func(*http.Request) (*http.Response, error)
+
+func (f webhookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// timeoutError is a failure to get an answer in time.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// webhookAnswer answers a post with a status.
+func webhookAnswer(req *http.Request, code int) *http.Response {
+	return &http.Response{
+		Status:     http.StatusText(code),
+		StatusCode: code,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+}
+
+func TestWebhookFlushOnBatchSize(t *testing.T) {
+	defer withWebhookSettings(2, time.Hour)()
+
+	server, batches := newBatchServer(t, http.StatusOK)
+	defer server.Close()
+
+	h := newWebhook(server.URL)
+	h.Write([]byte("one\n"))
+	h.Write([]byte("two\n"))
+	h.Write([]byte("three\n"))
+
+	assert.Equal(t, []string{"one", "two"}, <-batches)
+	assert.Equal(t, 0, len(batches))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadUint64(&h.sent) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestWebhookFlushOnInterval(t *testing.T) {
+	defer withWebhookSettings(100, 50*time.Millisecond)()
+
+	server, batches := newBatchServer(t, http.StatusOK)
+	defer server.Close()
+
+	h := newWebhook(server.URL)
+	start := time.Now()
+	h.Write([]byte("one\n"))
+	h.Write([]byte("two\n"))
+
+	select {
+	case batch := <-batches:
+		assert.Equal(t, []string{"one", "two"}, batch)
+		assert.True(t, time.Since(start) >= 50*time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch not flushed after the interval")
+	}
+}
+
+func TestWebhookFlushOnClose(t *testing.T) {
+	defer withWebhookSettings(100, time.Hour)()
+
+	server, batches := newBatchServer(t, http.StatusOK)
+	defer server.Close()
+
+	h := newWebhook(server.URL)
+	h.Write([]byte("last\n"))
+	assert.Equal(t, 0, len(batches))
+
+	assert.Nil(t, h.Close())
+	assert.Equal(t, []string{"last"}, <-batches)
+}
+
+func TestWebhookDropped(t *testing.T) {
+	defer withWebhookSettings(3, time.Hour)()
+	defer withWebhookRetries([webhookFailureClasses]int{}, 0)()
+
+	server, batches := newBatchServer(t, http.StatusInternalServerError)
+	defer server.Close()
+
+	h := newWebhook(server.URL)
+	h.Write([]byte("one\n"))
+	h.Write([]byte("two\n"))
+	_, err := h.Write([]byte("three\n"))
+	assert.Nil(t, err)
+	<-batches
+	assert.Nil(t, h.Close())
+
+	assert.Equal(t, uint64(0), h.sent)
+	assert.Equal(t, uint64(3), h.dropped)
+}
+
+func TestWebhookSlowEndpoint(t *testing.T) {
+	defer withWebhookSettings(1, time.Hour)()
+	defer withWebhookQueueSize(1)()
+
+	received := make(chan string, 4)
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Lines []string `json:"lines"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body.Lines[0]
+		<-release
+	}))
+	defer server.Close()
+
+	h := newWebhook(server.URL)
+
+	// Writes don't wait for the endpoint while it handles the first batch.
+	_, err := h.Write([]byte("one\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "one", <-received)
+
+	start := time.Now()
+	_, err = h.Write([]byte("two\n"))
+	assert.Nil(t, err)
+
+	// The line written when the queue is full is left to the output to hold.
+	_, err = h.Write([]byte("three\n"))
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	close(release)
+	assert.Nil(t, h.Close())
+	assert.Equal(t, "two", <-received)
+	assert.Equal(t, uint64(2), h.sent)
+	assert.Equal(t, uint64(0), h.dropped)
+
+	_, err = h.Write([]byte("four\n"))
+	assert.Equal(t, errWebhookClosed, err)
+}
+
+func TestClassifyWebhookError(t *testing.T) {
+	dial := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: err}
+	}
+
+	for err, class := range map[error]webhookFailure{
+		dial(&net.DNSError{Err: "no such host", Name: "hooks.example.com"}):                 dnsFailure,
+		dial(&net.DNSError{Err: "i/o timeout", Name: "hooks.example.com", IsTimeout: true}): dnsFailure,
+		dial(&os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}):               connectFailure,
+		dial(timeoutError{}): timeoutFailure,
+		timeoutError{}:       timeoutFailure,
+		&webhookStatusError{code: http.StatusServiceUnavailable}: serverFailure,
+		&webhookStatusError{code: http.StatusNotFound}:           otherFailure,
+		errors.New("unexpected EOF"):                             otherFailure,
+	} {
+		assert.Equal(t, webhookFailureNames[class], webhookFailureNames[classifyWebhookError(err)], err.Error())
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	defer withWebhookSettings(1, time.Hour)()
+	defer withWebhookRetries([webhookFailureClasses]int{2, 1, 1, 1, 0}, 0)()
+
+	dnsError := &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "hooks.example.com"}}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+
+	for _, test := range []struct {
+		name     string
+		failures []interface{} // errors, or statuses, of the posts failing before the endpoint accepts
+		sent     bool
+		class    webhookFailure
+	}{
+		{"dns blip", []interface{}{dnsError, dnsError}, true, dnsFailure},
+		{"dns down", []interface{}{dnsError, dnsError, dnsError}, false, dnsFailure},
+		{"refused", []interface{}{refused}, true, connectFailure},
+		{"refused twice", []interface{}{refused, refused}, false, connectFailure},
+		{"timeout", []interface{}{timeoutError{}, timeoutError{}}, false, timeoutFailure},
+		{"5xx", []interface{}{http.StatusBadGateway}, true, serverFailure},
+		{"5xx twice", []interface{}{http.StatusBadGateway, http.StatusServiceUnavailable}, false, serverFailure},
+		{"4xx", []interface{}{http.StatusNotFound}, false, otherFailure},
+		// Each class is retried within its own limit.
+		{"mixed", []interface{}{dnsError, refused, dnsError, http.StatusBadGateway, timeoutError{}}, true, dnsFailure},
+	} {
+		posts := 0
+		h := newWebhook("http://hooks.example.com/lines")
+		h.client.Transport = webhookTransport(func(req *http.Request) (*http.Response, error) {
+			posts++
+			if posts > len(test.failures) {
+				return webhookAnswer(req, http.StatusOK), nil
+			}
+
+			switch failure := test.failures[posts-1].(type) {
+			case int:
+				return webhookAnswer(req, failure), nil
+			default:
+				return nil, failure.(error)
+			}
+		})
+
+		_, err := h.Write([]byte("line\n"))
+		assert.Nil(t, err, test.name)
+		assert.Nil(t, h.Close(), test.name)
+
+		if test.sent {
+			assert.Equal(t, len(test.failures)+1, posts, test.name)
+			assert.Equal(t, uint64(1), h.sent, test.name)
+		} else {
+			assert.Equal(t, len(test.failures), posts, test.name)
+			assert.Equal(t, uint64(0), h.sent, test.name)
+			assert.Equal(t, uint64(1), h.dropped, test.name)
+		}
+
+		assert.True(t, h.failures[test.class] > 0, test.name)
+	}
+}
+
+func TestWebhookRetriesDontBlockWrites(t *testing.T) {
+	defer withWebhookSettings(1, time.Hour)()
+	defer withWebhookRetries([webhookFailureClasses]int{0, 0, 2, 0, 0}, 100*time.Millisecond)()
+
+	var posts int32
+	h := newWebhook("http://hooks.example.com/lines")
+	h.client.Transport = webhookTransport(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&posts, 1)
+		return nil, timeoutError{}
+	})
+
+	start := time.Now()
+	for _, text := range []string{"one\n", "two\n", "three\n"} {
+		_, err := h.Write([]byte(text))
+		assert.Nil(t, err)
+	}
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+
+	assert.Nil(t, h.Close())
+	assert.Equal(t, int32(9), atomic.LoadInt32(&posts))
+	assert.Equal(t, uint64(3), h.dropped)
+}
+
+func TestServeMetrics(t *testing.T) {
+	defer withWebhookSettings(1, time.Hour)()
+	defer closeOutputs()
+
+	server, batches := newBatchServer(t, http.StatusOK)
+	defer server.Close()
+
+	out, err := openOut(server.URL)
+	assert.Nil(t, err)
+
+	writeText("one", out, "\n")
+	<-batches
+
+	var body []byte
+	assert.Eventually(t, func() bool {
+		recorder := httptest.NewRecorder()
+		serveMetrics(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		body, err = ioutil.ReadAll(recorder.Body)
+		assert.Nil(t, err)
+
+		return strings.Contains(string(body), `sauron_webhook_batches_sent_total{url="`+server.URL+`"} 1`)
+	}, time.Second, time.Millisecond)
+	assert.Contains(t, string(body), `sauron_webhook_lines_dropped_total{url="`+server.URL+`"} 0`)
+	assert.Contains(t, string(body), `sauron_webhook_failures_total{url="`+server.URL+`",class="dns"} 0`)
+}
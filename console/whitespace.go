@@ -0,0 +1,44 @@
+package console
+
+import (
+	"regexp"
+	"strings"
+)
+
+// spacesReg matches runs of whitespace collapsed by CollapseWhitespace.
+var spacesReg = regexp.MustCompile(`\s+`)
+
+// spaceCleaner trims and collapses the whitespace of lines.
+type spaceCleaner struct {
+	trim        bool
+	collapse    bool
+	beforeMatch bool
+}
+
+// newSpaceCleaner returns the cleaner of a watch, or nil when it neither trims
+// nor collapses whitespace.
+func newSpaceCleaner(w watch) *spaceCleaner {
+	if !w.TrimSpace && !w.CollapseWhitespace {
+		return nil
+	}
+
+	return &spaceCleaner{
+		trim:        w.TrimSpace,
+		collapse:    w.CollapseWhitespace,
+		beforeMatch: w.TrimBeforeMatch,
+	}
+}
+
+// clean returns text without its leading and trailing whitespace, and with
+// every run of whitespace replaced by a single space, as configured.
+func (s *spaceCleaner) clean(text string) string {
+	if s.trim {
+		text = strings.TrimSpace(text)
+	}
+
+	if s.collapse {
+		text = spacesReg.ReplaceAllString(text, " ")
+	}
+
+	return text
+}
@@ -0,0 +1,63 @@
+package console
+
+import (
+	"../eye"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpaceCleaner(t *testing.T) {
+	assert.Nil(t, newSpaceCleaner(watch{}))
+
+	trim := newSpaceCleaner(watch{TrimSpace: true})
+	assert.Equal(t, "a  b\tc", trim.clean(" \ta  b\tc \t "))
+
+	collapse := newSpaceCleaner(watch{CollapseWhitespace: true})
+	assert.Equal(t, " a b c ", collapse.clean(" \ta  b\tc \t "))
+
+	both := newSpaceCleaner(watch{TrimSpace: true, CollapseWhitespace: true})
+	assert.Equal(t, "a b c", both.clean(" \ta  b\tc \t "))
+	assert.Equal(t, "", both.clean(" \t "))
+}
+
+func TestGetHandlerWhitespace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	defer closeOutputs()
+
+	for _, test := range []struct {
+		name        string
+		beforeMatch bool
+		out         string
+	}{
+		// Lines are matched as read, and cleaned once matched.
+		{"after.log", false, "GET /\nPOST /\n"},
+		// Lines are cleaned before being matched.
+		{"before.log", true, "GET /\nPOST /\nPUT /\n"},
+	} {
+		w := watch{
+			LinePattern:        `^[A-Z]+\s+/`,
+			TrimSpace:          true,
+			CollapseWhitespace: true,
+			TrimBeforeMatch:    test.beforeMatch,
+			Out:                filepath.Join(dir, test.name),
+		}
+
+		routes, err := openRoutes(w)
+		assert.Nil(t, err)
+
+		handler := getHandler(newTestContext(), routes, nil, w)
+		assert.Nil(t, handler(eye.Line{Text: "GET /"}))
+		assert.Nil(t, handler(eye.Line{Text: "POST \t /"}))
+		assert.Nil(t, handler(eye.Line{Text: "  PUT /\t"}))
+
+		out, err := ioutil.ReadFile(w.Out)
+		assert.Nil(t, err)
+		assert.Equal(t, test.out, string(out), test.name)
+	}
+}
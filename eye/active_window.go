@@ -0,0 +1,49 @@
+package eye
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/hpcloud/tail"
+)
+
+// markStale records where the tail of a file aging out of the ActiveWindow
+// stopped reading, as counted from the lines it read, so that it can be
+// resumed from there on its next write. The trail mutex must be held.
+func (t *Trail) markStale(current *tail.Tail) {
+	reads := t.reads[current]
+	if reads == nil {
+		return
+	}
+
+	offset := atomic.LoadInt64(&reads.offset)
+	if offset < 0 {
+		return
+	}
+
+	if t.stale == nil {
+		t.stale = make(map[string]int64)
+	}
+	t.stale[current.Filename] = offset
+}
+
+// resumeLocation returns where to follow a stale file from again, forgetting
+// it: where its tail stopped, or its beginning when it was truncated since.
+// It returns nil for files which didn't go stale.
+func (t *Trail) resumeLocation(path string) *tail.SeekInfo {
+	t.mutex.Lock()
+	offset, ok := t.stale[path]
+	delete(t.stale, path)
+	t.mutex.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size() < offset {
+		offset = 0
+	}
+
+	return &tail.SeekInfo{Offset: offset, Whence: io.SeekStart}
+}
@@ -0,0 +1,56 @@
+package eye
+
+import (
+	"io"
+	"os"
+)
+
+// binarySniffLen is the number of bytes inspected at the start of a file when
+// guessing whether it contains binary content.
+const binarySniffLen = 8192
+
+// isBinaryFile reads the beginning of the file at path and reports whether it
+// looks like binary content. Files that cannot be read are not considered
+// binary, so that the usual error handling applies when following them. Only
+// regular files are inspected, since reading a pipe would consume its data.
+func isBinaryFile(path string) bool {
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	buf := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false
+	}
+
+	return isBinary(buf[:n])
+}
+
+// isBinary guesses whether data is binary. Any NUL byte gives it away,
+// otherwise data is considered binary when more than 30% of its bytes are
+// control characters that do not usually appear in text.
+func isBinary(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	control := 0
+	for _, b := range data {
+		switch {
+		case b == 0:
+			return true
+		case b == '\t', b == '\n', b == '\r', b == '\f', b == '\b', b == 0x1b:
+		case b < 0x20, b == 0x7f:
+			control++
+		}
+	}
+
+	return control*100/len(data) > 30
+}
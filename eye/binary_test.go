@@ -0,0 +1,57 @@
+package eye
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsBinary(t *testing.T) {
+	assert.False(t, isBinary([]byte("")))
+	assert.False(t, isBinary([]byte("2018-01-01 ERROR something\tfailed\r\n")))
+	assert.False(t, isBinary([]byte("überprüfung fehlgeschlagen\n")))
+	assert.True(t, isBinary([]byte("ELF\x00\x01\x02")))
+	assert.True(t, isBinary([]byte("\x01\x02\x03\x04abc")))
+}
+
+func TestIgnoreSkipBinary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	text := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(text, []byte("INFO started\n"), 0644))
+
+	core := filepath.Join(dir, "core")
+	assert.Nil(t, ioutil.WriteFile(core, []byte("\x7fELF\x02\x01\x01\x00\x00\x00"), 0644))
+
+	// Gzip streams are judged by their decoded content, other gzip files by
+	// their compressed one.
+	stream := filepath.Join(dir, "app.log.gz")
+	appendGzipMember(t, stream, "INFO started\n")
+	archive := filepath.Join(dir, "app.log.1.gz")
+	appendGzipMember(t, archive, "INFO started\n")
+	dump := filepath.Join(dir, "core.gz")
+	appendGzipMember(t, dump, "\x7fELF\x02\x01\x01\x00\x00\x00")
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		SkipBinary:         true,
+		GzipStreamReg:      regexp.MustCompile(`^(app\.log|core)\.gz$`),
+	})
+
+	assert.False(t, ignore(trail, text))
+	assert.True(t, ignore(trail, core))
+	assert.False(t, ignore(trail, stream))
+	assert.True(t, ignore(trail, archive))
+	assert.True(t, ignore(trail, dump))
+
+	trail.options.SkipBinary = false
+
+	assert.False(t, ignore(trail, core))
+}
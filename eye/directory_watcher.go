@@ -2,21 +2,59 @@ package eye
 
 import (
 	"errors"
+	"github.com/Sirupsen/logrus"
 	"gopkg.in/fsnotify.v1"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
 // DirectoryWatcher is an implementation of a Watcher capable of monitoring
 // for changes on a directory recursively.
 type DirectoryWatcher struct {
-	path string
-	done chan bool
+	path    string
+	options DirectoryWatcherOptions
+	done    chan bool
+	stopped chan bool
+	endOnce sync.Once
+}
+
+// DirectoryWatcherOptions tunes how a DirectoryWatcher watches its directory.
+type DirectoryWatcherOptions struct {
+	// Recursive watches the subdirectories as well, including those created
+	// later, instead of the directory alone.
+	Recursive bool
+	// MaxWatches bounds the directories watched at once when Recursive, 0 for
+	// no limit. Beyond it, the least recently active directories are polled
+	// instead, and watched again once they change.
+	MaxWatches int
+	// FollowSymlinks watches the target of the directory when it is a
+	// symlink, as resolved when the watcher is created. The link is not
+	// resolved again: once repointed, the previous target is still watched
+	// until a new watcher is created, as on reload. Otherwise the link is
+	// walked as a file of its own.
+	FollowSymlinks bool
+	// Logger logs the directories evicted.
+	Logger *logrus.Logger
 }
 
 // NewDirectoryWatcher creates a new instance of a DirectoryWatcher.
 func NewDirectoryWatcher(path string) (*DirectoryWatcher, error) {
+	return NewDirectoryWatcherWithOptions(path, &DirectoryWatcherOptions{})
+}
+
+// NewDirectoryWatcherWithOptions creates a DirectoryWatcher with the given
+// options.
+func NewDirectoryWatcherWithOptions(path string, options *DirectoryWatcherOptions) (*DirectoryWatcher, error) {
+	if options.FollowSymlinks {
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil, err
+		}
+		path = target
+	}
+
 	fileInfo, err := os.Stat(path)
 
 	if err != nil {
@@ -27,9 +65,16 @@ func NewDirectoryWatcher(path string) (*DirectoryWatcher, error) {
 		return nil, errors.New("Unable to watch. Cannot watch a file.")
 	}
 
-	return &DirectoryWatcher{
-		path: path,
-	}, nil
+	watcher := &DirectoryWatcher{
+		path:    path,
+		options: *options,
+	}
+
+	if watcher.options.Logger == nil {
+		watcher.options.Logger = logrus.New()
+	}
+
+	return watcher, nil
 }
 
 // Walk returns a list of all the files within the target directory.
@@ -55,7 +100,8 @@ func (w *DirectoryWatcher) Walk() (paths []string, err error) {
 	return
 }
 
-// Watch starts watching for filesystem events.
+// Watch starts watching for filesystem events. Events are sent to newf until
+// End is called.
 func (w *DirectoryWatcher) Watch(newf chan FileEvent) error {
 	watcher, err := fsnotify.NewWatcher()
 
@@ -63,32 +109,89 @@ func (w *DirectoryWatcher) Watch(newf chan FileEvent) error {
 		return err
 	}
 
+	var dirs *watchedDirs
+	if w.options.Recursive {
+		if dirs, err = newWatchedDirs(watcher, w.path, w.options.MaxWatches, w.options.Logger); err != nil {
+			watcher.Close()
+			return err
+		}
+	} else if err := watcher.Add(w.path); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	// Directories evicted beyond MaxWatches are polled.
+	var ticker *time.Ticker
+	var poll <-chan time.Time
+	if dirs != nil && w.options.MaxWatches > 0 {
+		ticker = time.NewTicker(evictedPollInterval)
+		poll = ticker.C
+	}
+
 	w.done = make(chan bool)
+	w.stopped = make(chan bool)
+
+	// send passes an event on, unless the consumer stopped reading.
+	send := func(event fsnotify.Event) bool {
+		abs, err := filepath.Abs(event.Name)
+		if err != nil {
+			return true
+		}
+
+		select {
+		case newf <- FileEvent{
+			Name: event.Name,
+			Path: abs,
+			Time: time.Now(),
+			Op:   event.Op,
+		}:
+			return true
+		case <-w.done:
+			return false
+		}
+	}
 
 	go func() {
+		defer close(w.stopped)
+		defer watcher.Close()
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
 		for {
 			select {
-			case event := <-watcher.Events:
-				if abs, err := filepath.Abs(event.Name); err == nil {
-					newf <- FileEvent{
-						Name: event.Name,
-						Path: abs,
-						Time: time.Now(),
-						Op:   event.Op,
-					}
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if dirs != nil {
+					dirs.handle(event)
+				}
+
+				if !send(event) {
+					return
+				}
+			case <-poll:
+				if !dirs.poll(send) {
+					return
 				}
 			case <-w.done:
-				watcher.Close()
+				return
 			}
 		}
 	}()
 
-	watcher.Add(w.path)
-
 	return nil
 }
 
-// End stops the watching operation.
+// End stops the watching operation, returning once no more events will be
+// sent. It does nothing if the watcher isn't watching, or was already ended.
 func (w *DirectoryWatcher) End() {
-	w.done <- true
+	if w.done == nil {
+		return
+	}
+
+	w.endOnce.Do(func() { close(w.done) })
+	<-w.stopped
 }
@@ -1,9 +1,17 @@
 package eye
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/fsnotify.v1"
 )
 
 func TestWalk(t *testing.T) {
@@ -13,3 +21,172 @@ func TestWalk(t *testing.T) {
 
 	assert.True(t, len(files) == 2)
 }
+
+func TestFollowStartStopStress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// Files keep being written while trails start and stop, so that events
+	// are in flight whenever a trail ends.
+	stop := make(chan bool)
+	writing := make(chan bool)
+	go func() {
+		defer close(writing)
+
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			path := filepath.Join(dir, "file"+strconv.Itoa(i%5)+".log")
+			ioutil.WriteFile(path, []byte("line\n"), 0644)
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		watcher, err := NewDirectoryWatcher(dir)
+		assert.Nil(t, err)
+
+		trail := NewTrailWithOptions(watcher, &TrailOptions{Logger: logrus.New()})
+		trail.options.Logger.SetOutput(ioutil.Discard)
+		assert.Nil(t, trail.Follow(func(line Line) error { return nil }))
+
+		ended := make(chan bool)
+		go func() {
+			trail.End()
+			trail.End()
+			close(ended)
+		}()
+
+		select {
+		case <-ended:
+		case <-time.After(5 * time.Second):
+			t.Fatal("trail did not end")
+		}
+	}
+
+	close(stop)
+	<-writing
+}
+
+func TestDirectoryWatcherEndWithoutWatch(t *testing.T) {
+	watcher, err := NewDirectoryWatcher("../_resources")
+	assert.Nil(t, err)
+
+	watcher.End()
+}
+
+func TestWatchedDirsEviction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Nil(t, os.Mkdir(filepath.Join(dir, name), 0755))
+	}
+	a, b, c := filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(a, "old.log"), []byte("line\n"), 0644))
+
+	watcher, err := fsnotify.NewWatcher()
+	assert.Nil(t, err)
+	defer watcher.Close()
+
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &logs
+
+	// The root takes one of the 3 watches, a is evicted to watch c.
+	dirs, err := newWatchedDirs(watcher, dir, 3, logger)
+	assert.Nil(t, err)
+
+	watchedOf := func() []string {
+		var watched []string
+		for e := dirs.recent.Front(); e != nil; e = e.Next() {
+			watched = append(watched, e.Value.(string))
+		}
+		return watched
+	}
+
+	assert.Equal(t, []string{c, b}, watchedOf())
+	assert.Contains(t, dirs.evicted, a)
+	assert.Contains(t, logs.String(), "evicting")
+
+	// Activity in b makes c the least recently active.
+	dirs.handle(fsnotify.Event{Name: filepath.Join(b, "app.log"), Op: fsnotify.Write})
+	assert.Equal(t, []string{b, c}, watchedOf())
+
+	var events []fsnotify.Event
+	send := func(event fsnotify.Event) bool {
+		events = append(events, event)
+		return true
+	}
+
+	// Nothing changed in a, so it isn't watched again.
+	assert.True(t, dirs.poll(send))
+	assert.Equal(t, 0, len(events))
+
+	// Once a changes, it is watched again in place of c, and what happened
+	// to it meanwhile is reported.
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(a, "new.log"), []byte("line\n"), 0644))
+	assert.Nil(t, os.Remove(filepath.Join(a, "old.log")))
+
+	assert.True(t, dirs.poll(send))
+	assert.Equal(t, []string{a, b}, watchedOf())
+	assert.Contains(t, dirs.evicted, c)
+	assert.NotContains(t, dirs.evicted, a)
+	assert.Equal(t, []fsnotify.Event{
+		{Name: filepath.Join(a, "new.log"), Op: fsnotify.Create},
+		{Name: filepath.Join(a, "old.log"), Op: fsnotify.Remove},
+	}, events)
+
+	// Removed directories are forgotten.
+	assert.Nil(t, os.RemoveAll(c))
+	dirs.handle(fsnotify.Event{Name: c, Op: fsnotify.Remove})
+	assert.NotContains(t, dirs.evicted, c)
+}
+
+func TestDirectoryWatcherMaxWatches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a", "b", "c"} {
+		assert.Nil(t, os.Mkdir(filepath.Join(dir, name), 0755))
+	}
+
+	defer func(interval time.Duration) { evictedPollInterval = interval }(evictedPollInterval)
+	evictedPollInterval = 10 * time.Millisecond
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	watcher, err := NewDirectoryWatcherWithOptions(dir, &DirectoryWatcherOptions{
+		Recursive:  true,
+		MaxWatches: 2,
+		Logger:     logger,
+	})
+	assert.Nil(t, err)
+
+	events := make(chan FileEvent, 100)
+	assert.Nil(t, watcher.Watch(events))
+	defer watcher.End()
+
+	// Only one of the subdirectories fits, the others are polled. Files
+	// created in each of them are reported all the same.
+	for i, name := range []string{"a", "b", "c", "a"} {
+		path, _ := filepath.Abs(filepath.Join(dir, name, "app"+strconv.Itoa(i)+".log"))
+		assert.Nil(t, ioutil.WriteFile(path, []byte("line\n"), 0644))
+
+		for created := false; !created; {
+			select {
+			case event := <-events:
+				created = event.Path == path && event.Op&fsnotify.Create != 0
+			case <-time.After(5 * time.Second):
+				t.Fatal("no event for " + path)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package eye
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID returns the inode and device numbers of a file, or zeros when it
+// can't be stat'ed.
+func fileID(path string) (inode, dev uint64) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0
+	}
+
+	return infoID(info)
+}
+
+// infoID returns the inode and device numbers of a file from its info, or
+// zeros when it has none.
+func infoID(info os.FileInfo) (inode, dev uint64) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0
+	}
+
+	return uint64(stat.Ino), uint64(stat.Dev)
+}
@@ -0,0 +1,116 @@
+//go:build !windows
+// +build !windows
+
+package eye
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFollowFileID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("first\n"), 0644))
+
+	var stat syscall.Stat_t
+	assert.Nil(t, syscall.Stat(path, &stat))
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{ReOpen: true})
+
+	lines := make(chan Line, 2)
+	trail.followFile(path, func(line Line) error {
+		lines <- line
+
+		return nil
+	}, true)
+	defer trail.unfollowFile(path)
+
+	receive := func() Line {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("line not delivered")
+			return Line{}
+		}
+	}
+
+	line := receive()
+	assert.NotZero(t, line.Inode)
+	assert.Equal(t, uint64(stat.Ino), line.Inode)
+	assert.Equal(t, uint64(stat.Dev), line.Dev)
+
+	// The tail library only notices the rotation once it watches the file,
+	// which it starts doing at its end.
+	time.Sleep(100 * time.Millisecond)
+
+	// The lines of the file rotated in carry its own inode.
+	assert.Nil(t, os.Rename(path, path+".1"))
+	assert.Nil(t, ioutil.WriteFile(path+".tmp", []byte("second\n"), 0644))
+	assert.Nil(t, syscall.Stat(path+".tmp", &stat))
+	assert.Nil(t, os.Rename(path+".tmp", path))
+
+	line = receive()
+	assert.Equal(t, "second", line.Text)
+	assert.Equal(t, uint64(stat.Ino), line.Inode)
+	assert.Equal(t, uint64(stat.Dev), line.Dev)
+}
+
+func TestTailLoggerReplacedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, nil, 0644))
+
+	var stat syscall.Stat_t
+	assert.Nil(t, syscall.Stat(path, &stat))
+
+	logger := newTailLogger(path)
+	defer logger.close()
+
+	go logger.Printf("Seeked %s - %+v\n", path, nil)
+	assert.Equal(t, openedFile{inode: uint64(stat.Ino), dev: uint64(stat.Dev)}, <-logger.opened)
+
+	// A file replaced while it is re-opened can't be told.
+	go func() {
+		logger.Printf("Re-opening moved/deleted file %s ...", path)
+		assert.Nil(t, os.Rename(path, path+".1"))
+		assert.Nil(t, ioutil.WriteFile(path, nil, 0644))
+		logger.Printf("Successfully reopened %s", path)
+	}()
+	assert.Equal(t, openedFile{reopened: true}, <-logger.opened)
+}
+
+func TestFileIDMissing(t *testing.T) {
+	inode, dev := fileID(filepath.Join(os.TempDir(), "sauron-missing.log"))
+	assert.Zero(t, inode)
+	assert.Zero(t, dev)
+}
+
+func TestIsTruncatedRotated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("a longer line\n"), 0644))
+	inode, dev := fileID(path)
+	assert.True(t, isTruncated(path, 20, inode, dev))
+
+	// A shorter file rotated in is re-opened by the tail library instead.
+	assert.Nil(t, os.Rename(path, path+".1"))
+	assert.Nil(t, ioutil.WriteFile(path, []byte("line\n"), 0644))
+	assert.False(t, isTruncated(path, 14, inode, dev))
+}
@@ -0,0 +1,15 @@
+package eye
+
+import "os"
+
+// fileID returns zeros, since files have no inode nor device numbers on
+// Windows.
+func fileID(path string) (inode, dev uint64) {
+	return 0, 0
+}
+
+// infoID returns zeros, since files have no inode nor device numbers on
+// Windows.
+func infoID(info os.FileInfo) (inode, dev uint64) {
+	return 0, 0
+}
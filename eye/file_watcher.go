@@ -0,0 +1,50 @@
+package eye
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// FileWatcher is an implementation of a Watcher for a single, explicitly
+// named file. It never reports filesystem events: the file is followed
+// directly, and rotation is left to the ReOpen trail option.
+type FileWatcher struct {
+	path string
+}
+
+// NewFileWatcher creates a new instance of a FileWatcher.
+func NewFileWatcher(path string) (*FileWatcher, error) {
+	fileInfo, err := os.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !fileInfo.Mode().IsRegular() {
+		return nil, errors.New("Unable to watch. Not a regular file.")
+	}
+
+	return &FileWatcher{
+		path: path,
+	}, nil
+}
+
+// Walk returns the absolute path of the watched file.
+func (w *FileWatcher) Walk() (paths []string, err error) {
+	abs, err := filepath.Abs(w.path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{abs}, nil
+}
+
+// Watch does nothing, since there is no directory to scan for new files.
+func (w *FileWatcher) Watch(newf chan FileEvent) error {
+	return nil
+}
+
+// End does nothing, since Watch starts no work.
+func (w *FileWatcher) End() {}
@@ -0,0 +1,25 @@
+package eye
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileWatcherWalk(t *testing.T) {
+	watcher, err := NewFileWatcher("../_resources/example.log")
+	assert.Nil(t, err)
+
+	files, err := watcher.Walk()
+	assert.Nil(t, err)
+
+	path, _ := filepath.Abs("../_resources/example.log")
+	assert.Equal(t, []string{path}, files)
+}
+
+func TestNewFileWatcherDirectory(t *testing.T) {
+	_, err := NewFileWatcher("../_resources")
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,92 @@
+package eye
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// followedClaim records which trail follows a path and how many of its tails
+// are currently running for it.
+type followedClaim struct {
+	trail *Trail
+	count int
+}
+
+// followedPaths keeps track of the files followed by every trail of the
+// process, so that overlapping watches don't tail the same file twice.
+type followedPaths struct {
+	mutex  sync.Mutex
+	claims map[string]*followedClaim
+}
+
+var followed = &followedPaths{claims: map[string]*followedClaim{}}
+
+// claim reserves a path for a trail. It fails when the path is already
+// followed by a different trail.
+func (f *followedPaths) claim(path string, t *Trail) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	key := followedKey(path)
+
+	if current, ok := f.claims[key]; ok {
+		if current.trail != t {
+			return false
+		}
+
+		current.count++
+
+		return true
+	}
+
+	f.claims[key] = &followedClaim{trail: t, count: 1}
+
+	return true
+}
+
+// claimedBy reports whether a path is claimed by a trail, including while its
+// tail is being opened or re-opened.
+func (f *followedPaths) claimedBy(path string, t *Trail) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	current, ok := f.claims[followedKey(path)]
+
+	return ok && current.trail == t
+}
+
+// claimed reports whether a path is claimed by any trail.
+func (f *followedPaths) claimed(path string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_, ok := f.claims[followedKey(path)]
+
+	return ok
+}
+
+// release gives back a path previously claimed by a trail.
+func (f *followedPaths) release(path string, t *Trail) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	key := followedKey(path)
+
+	if current, ok := f.claims[key]; ok && current.trail == t {
+		current.count--
+
+		if current.count <= 0 {
+			delete(f.claims, key)
+		}
+	}
+}
+
+// followedKey normalizes a path so that the same file is always claimed under
+// the same key.
+func followedKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+
+	return filepath.Clean(path)
+}
@@ -0,0 +1,200 @@
+package eye
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gzipPollInterval is how often a followed gzip file is checked for new data
+// once all of it was decoded.
+var gzipPollInterval = 250 * time.Millisecond
+
+// isGzipStream reports whether path names a file to follow as a gzip stream.
+func (t *Trail) isGzipStream(path string) bool {
+	return t.options.GzipStreamReg != nil && t.options.GzipStreamReg.MatchString(filepath.Base(path))
+}
+
+// isBinaryGzip reads the beginning of the decoded content of the gzip file at
+// path and reports whether it looks like binary content. Files that cannot be
+// read or decoded yet, such as created ones, are not considered binary.
+func isBinaryGzip(path string) bool {
+	if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return false
+	}
+	defer reader.Close()
+
+	buf := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(reader, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+
+	return isBinary(buf[:n])
+}
+
+// growingReader reads a file which is still being appended to, checking for
+// more data at its end every interval instead of returning io.EOF, until done
+// is closed.
+type growingReader struct {
+	file     *os.File
+	interval time.Duration
+	done     chan bool
+}
+
+func (r *growingReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.file.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+
+		select {
+		case <-r.done:
+			return 0, io.EOF
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// countingReader counts the bytes read through it. It reads byte by byte on
+// demand, so that the gzip reader doesn't buffer past the end of a member and
+// the count tells where the next member starts.
+type countingReader struct {
+	reader *bufio.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+
+	return n, err
+}
+
+func (r *countingReader) ReadByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		r.count++
+	}
+
+	return b, err
+}
+
+// followGzip decodes the lines of a gzip file as they are appended to it, one
+// gzip member after the other, until the file is unfollowed or the trail
+// ends. Unless isNew is set, the members already in the file are skipped.
+// Offsets don't apply to compressed files, so lines carry an offset of -1.
+//
+// Gzip files are registered along with the pipes, as they are read as streams
+// too.
+func (t *Trail) followGzip(path string, handler LineHandler, isNew bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		t.logFor(path).WithError(err).Errorln("failed to open")
+		followed.release(path, t)
+		return
+	}
+
+	var skipped int64
+	if !isNew {
+		if info, err := file.Stat(); err == nil {
+			skipped = info.Size()
+		}
+	}
+
+	t.addPipe(path, file)
+
+	go func() {
+		defer followed.release(path, t)
+
+		err := t.readGzip(path, file, skipped, handler)
+
+		if !t.removePipe(path, file) {
+			// The file was closed by unfollowFile or End.
+			return
+		}
+		file.Close()
+
+		if err != nil && err != io.EOF {
+			t.logFor(path).WithError(err).Errorln("failed to decode")
+		}
+	}()
+}
+
+// readGzip passes the lines of the gzip members of a file to the handler,
+// skipping the members starting before the skipped first bytes, until reading
+// or decoding the file fails. A line left unterminated at the end of a member
+// is completed by the next one.
+func (t *Trail) readGzip(path string, file *os.File, skipped int64, handler LineHandler) error {
+	input := &countingReader{reader: bufio.NewReader(&growingReader{file: file, interval: gzipPollInterval, done: t.done})}
+
+	decoder, err := gzip.NewReader(input)
+	if err != nil {
+		return err
+	}
+
+	var start int64
+	pending := ""
+	for {
+		decoder.Multistream(false)
+		skip := start < skipped
+
+		lines := bufio.NewReader(decoder)
+		for {
+			text, err := lines.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+
+			if skip {
+				pending = ""
+			} else if strings.HasSuffix(text, "\n") {
+				t.handleStreamLine(path, pending+strings.TrimSuffix(text, "\n"), handler)
+				pending = ""
+			} else {
+				pending += text
+			}
+
+			if err == io.EOF {
+				break
+			}
+		}
+
+		start = input.count
+		if err := decoder.Reset(input); err != nil {
+			return err
+		}
+	}
+}
+
+// handleStreamLine passes a line read from a stream, which has no offset, to
+// the handler.
+func (t *Trail) handleStreamLine(path, text string, handler LineHandler) {
+	start := time.Now()
+	if err := handler(Line{
+		Path:    path,
+		Text:    text,
+		Time:    time.Now(),
+		Offset:  -1,
+		Matched: matchLine(t.options.LineReg, text),
+	}); err != nil {
+		t.logFor(path).WithError(err).Errorln("handler failed")
+	}
+	t.latency.Observe(time.Since(start))
+}
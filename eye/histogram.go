@@ -0,0 +1,120 @@
+package eye
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds used by a Trail to bucket the
+// time spent by its handler on each line.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram is a Prometheus-style cumulative histogram of durations. It is
+// safe for concurrent use.
+type Histogram struct {
+	mu      sync.Mutex
+	bounds  []time.Duration
+	buckets []uint64
+	count   uint64
+	sum     time.Duration
+}
+
+// NewHistogram creates a new instance of a Histogram using the provided
+// bucket upper bounds, which must be sorted in increasing order.
+func NewHistogram(bounds []time.Duration) *Histogram {
+	return &Histogram{
+		bounds:  bounds,
+		buckets: make([]uint64, len(bounds)),
+	}
+}
+
+// Observe records a single duration.
+func (h *Histogram) Observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.bounds {
+		if d <= bound {
+			h.buckets[i]++
+		}
+	}
+
+	h.count++
+	h.sum += d
+}
+
+// Count returns the number of observed durations.
+func (h *Histogram) Count() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.count
+}
+
+// Sum returns the total of all observed durations.
+func (h *Histogram) Sum() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.sum
+}
+
+// Bucket returns the number of observations less than or equal to bound, or
+// the total count if bound is not one of the histogram's upper bounds.
+func (h *Histogram) Bucket(bound time.Duration) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, current := range h.bounds {
+		if current == bound {
+			return h.buckets[i]
+		}
+	}
+
+	return h.count
+}
+
+// Buckets returns the upper bounds of the histogram, the cumulative count of
+// each, the number of observed durations and their total, all read at once so
+// that they are consistent.
+func (h *Histogram) Buckets() ([]time.Duration, []uint64, uint64, time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.bounds, append([]uint64{}, h.buckets...), h.count, h.sum
+}
+
+// String summarizes the histogram in a single line, listing the cumulative
+// count of each bucket followed by the +Inf bucket.
+func (h *Histogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	parts := []string{
+		fmt.Sprintf("count=%d", h.count),
+		fmt.Sprintf("sum=%s", h.sum),
+	}
+
+	for i, bound := range h.bounds {
+		parts = append(parts, fmt.Sprintf("le_%s=%d", bound, h.buckets[i]))
+	}
+
+	parts = append(parts, fmt.Sprintf("le_+Inf=%d", h.count))
+
+	return strings.Join(parts, " ")
+}
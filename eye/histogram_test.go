@@ -0,0 +1,28 @@
+package eye
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	h := NewHistogram([]time.Duration{time.Millisecond, 10 * time.Millisecond})
+
+	h.Observe(500 * time.Microsecond)
+	h.Observe(5 * time.Millisecond)
+	h.Observe(time.Second)
+
+	assert.Equal(t, uint64(3), h.Count())
+	assert.Equal(t, time.Second+5500*time.Microsecond, h.Sum())
+	assert.Equal(t, uint64(1), h.Bucket(time.Millisecond))
+	assert.Equal(t, uint64(2), h.Bucket(10*time.Millisecond))
+	assert.Contains(t, h.String(), "le_+Inf=3")
+
+	bounds, buckets, count, sum := h.Buckets()
+	assert.Equal(t, []time.Duration{time.Millisecond, 10 * time.Millisecond}, bounds)
+	assert.Equal(t, []uint64{1, 2}, buckets)
+	assert.Equal(t, uint64(3), count)
+	assert.Equal(t, time.Second+5500*time.Microsecond, sum)
+}
@@ -0,0 +1,41 @@
+package eye
+
+import (
+	"os"
+	"sort"
+)
+
+const (
+	// OrderPath follows the files found at startup sorted by path.
+	OrderPath = "path"
+
+	// OrderModTime follows the files found at startup from the least to the
+	// most recently modified, so that their existing lines come out roughly
+	// in the order they were written.
+	OrderModTime = "mtime"
+)
+
+// sortFiles sorts the files found at startup in the given order, by path
+// unless it is OrderModTime. Files whose modification time can't be read come
+// first.
+func sortFiles(files []string, order string) {
+	if order != OrderModTime {
+		sort.Strings(files)
+		return
+	}
+
+	modTimes := make(map[string]int64, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime().UnixNano()
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if modTimes[files[i]] != modTimes[files[j]] {
+			return modTimes[files[i]] < modTimes[files[j]]
+		}
+
+		return files[i] < files[j]
+	})
+}
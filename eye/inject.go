@@ -0,0 +1,35 @@
+package eye
+
+import (
+	"errors"
+	"time"
+)
+
+// errNotFollowing is returned when injecting lines into a trail which wasn't
+// told to follow yet.
+var errNotFollowing = errors.New("the trail is not following")
+
+// Inject passes a line to the handler of the trail as if it was read from a
+// followed file, so that embedders can test their handlers without writing
+// files. Its Matched is set against the LineReg of the trail, and its Time
+// defaults to now. The handler is called before Inject returns, bypassing the
+// buffer of HandlerBufferSize. It is meant for tests; lines are rejected until
+// Follow was called.
+func (t *Trail) Inject(line Line) error {
+	t.mutex.Lock()
+	handler := t.handler
+	t.mutex.Unlock()
+
+	if handler == nil {
+		return errNotFollowing
+	}
+
+	if line.Time.IsZero() {
+		line.Time = time.Now()
+	}
+	line.Matched = matchLine(t.options.LineReg, line.Text)
+
+	t.deliver(line, handler)
+
+	return nil
+}
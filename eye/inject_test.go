@@ -0,0 +1,46 @@
+package eye
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestInject(t *testing.T) {
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		LineReg: regexp.MustCompile("ERROR"),
+	})
+
+	// Nothing handles lines before the trail follows.
+	assert.Equal(t, errNotFollowing, trail.Inject(Line{Text: "too early"}))
+
+	var lines []Line
+	trail.Follow(func(line Line) error {
+		lines = append(lines, line)
+		if line.Text == "fails" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	defer trail.End()
+
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Nil(t, trail.Inject(Line{Path: "/var/log/app.log", Text: "ERROR boom", Time: at, Offset: 42}))
+	assert.Nil(t, trail.Inject(Line{Path: "/var/log/app.log", Text: "INFO fine"}))
+	assert.Nil(t, trail.Inject(Line{Path: "/var/log/app.log", Text: "fails"}))
+
+	// Lines reach the handler right away, matched against the trail pattern.
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, Line{Path: "/var/log/app.log", Text: "ERROR boom", Time: at, Offset: 42, Matched: true}, lines[0])
+	assert.False(t, lines[1].Matched)
+	assert.False(t, lines[1].Time.IsZero())
+	assert.Equal(t, uint64(3), trail.HandlerLatency().Count())
+}
@@ -0,0 +1,60 @@
+package eye
+
+// newestFile returns the most recently modified of files, in a slice of its
+// own, or no file if none of them can be read.
+func newestFile(files []string) []string {
+	return newestFiles(files, 1)
+}
+
+// followLatest switches a LatestOnly trail to a newly created file: the files
+// followed so far are unfollowed, and path followed from its beginning.
+func (t *Trail) followLatest(path string, handler LineHandler) {
+	t.mutex.Lock()
+	t.latest = path
+	t.mutex.Unlock()
+
+	for _, file := range t.FollowedFiles() {
+		if file != path {
+			t.logFor(file).Infoln("unfollowing for a newer file")
+			t.unfollowFile(file)
+		}
+	}
+
+	t.followFile(path, handler, true)
+}
+
+// isLatest reports whether path is the file a LatestOnly trail follows.
+func (t *Trail) isLatest(path string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.latest == path
+}
+
+// selectFiles keeps the files of a trail to read at startup: those which
+// aren't ignored, and only the newest of them for LatestOnly trails or the
+// MaxFiles newest otherwise.
+func (t *Trail) selectFiles(files []string) []string {
+	var selected []string
+	for _, file := range files {
+		if !ignore(t, file) {
+			selected = append(selected, file)
+		}
+	}
+
+	if !t.options.LatestOnly {
+		return t.limitFiles(selected)
+	}
+
+	selected = newestFile(selected)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.latest = ""
+	if len(selected) > 0 {
+		t.latest = selected[0]
+	}
+
+	return selected
+}
@@ -0,0 +1,86 @@
+package eye
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	// BufferBlock makes a full handler buffer block the reading of new lines
+	// until the handler catches up.
+	BufferBlock = "block"
+
+	// BufferDropOldest makes a full handler buffer discard its oldest line to
+	// make room for the newest one.
+	BufferDropOldest = "drop-oldest"
+)
+
+// lineBuffer decouples the reading of lines from a slow handler. Lines are
+// pushed by a single producer and handled in order by a separate goroutine.
+type lineBuffer struct {
+	trail   *Trail
+	path    string
+	lines   chan Line
+	policy  string
+	dropped uint64
+	done    chan bool
+}
+
+// newLineBuffer creates a buffer of the given size and starts passing its
+// lines to handle.
+func newLineBuffer(t *Trail, path string, size int, policy string, handle func(Line)) *lineBuffer {
+	b := &lineBuffer{
+		trail:  t,
+		path:   path,
+		lines:  make(chan Line, size),
+		policy: policy,
+		done:   make(chan bool),
+	}
+
+	go func() {
+		for line := range b.lines {
+			handle(line)
+		}
+
+		close(b.done)
+	}()
+
+	return b
+}
+
+// push adds a line to the buffer, applying the buffer policy when it is full.
+func (b *lineBuffer) push(line Line) {
+	if b.policy != BufferDropOldest {
+		b.lines <- line
+		return
+	}
+
+	for {
+		select {
+		case b.lines <- line:
+			return
+		default:
+		}
+
+		select {
+		case <-b.lines:
+			dropped := atomic.AddUint64(&b.dropped, 1)
+			if dropped == 1 || dropped%1000 == 0 {
+				b.trail.options.Logger.Warnln("Handler buffer full for " + b.path +
+					", dropped " + strconv.FormatUint(dropped, 10) + " lines so far")
+			}
+		default:
+		}
+	}
+}
+
+// Dropped returns the number of lines discarded by the buffer.
+func (b *lineBuffer) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// close stops accepting lines and waits for the buffered ones to be handled.
+func (b *lineBuffer) close() {
+	close(b.lines)
+	<-b.done
+}
@@ -0,0 +1,56 @@
+package eye
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineBufferBlock(t *testing.T) {
+	trail := NewTrail(&MockedWatcher{})
+
+	var handled []string
+	buffer := newLineBuffer(trail, "app.log", 1, BufferBlock, func(line Line) {
+		time.Sleep(5 * time.Millisecond)
+		handled = append(handled, line.Text)
+	})
+
+	for _, text := range []string{"1", "2", "3", "4", "5"} {
+		buffer.push(Line{Text: text})
+	}
+	buffer.close()
+
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, handled)
+	assert.Equal(t, uint64(0), buffer.Dropped())
+}
+
+func TestLineBufferDropOldest(t *testing.T) {
+	trail := NewTrail(&MockedWatcher{})
+
+	started := make(chan bool)
+	gate := make(chan bool)
+
+	var handled []string
+	buffer := newLineBuffer(trail, "app.log", 2, BufferDropOldest, func(line Line) {
+		if line.Text == "0" {
+			started <- true
+			<-gate
+		}
+		handled = append(handled, line.Text)
+	})
+
+	// The handler is stuck on the first line while the others pile up.
+	buffer.push(Line{Text: "0"})
+	<-started
+
+	for _, text := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"} {
+		buffer.push(Line{Text: text})
+	}
+
+	close(gate)
+	buffer.close()
+
+	assert.Equal(t, []string{"0", "8", "9"}, handled)
+	assert.Equal(t, uint64(7), buffer.Dropped())
+}
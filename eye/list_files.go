@@ -0,0 +1,143 @@
+package eye
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Reasons why the files found by the watcher of a trail aren't followed, as
+// listed by ListFiles.
+const (
+	IgnoredByPathPattern       = "path pattern"
+	IgnoredByPathIgnorePattern = "path ignore pattern"
+	IgnoredByFullPathPattern   = "full path pattern"
+	IgnoredByFilePattern       = "file pattern"
+	IgnoredByFileIgnorePattern = "file ignore pattern"
+	IgnoredTooOld              = "too old"
+	IgnoredTooSmall            = "too small"
+	IgnoredTooBig              = "too big"
+	IgnoredBinary              = "binary"
+	IgnoredNotLatest           = "not the latest"
+	IgnoredBeyondMaxFiles      = "beyond max files"
+)
+
+// FileStatus tells whether a file would be followed by a trail starting now,
+// and why not otherwise.
+type FileStatus struct {
+	Path     string
+	Followed bool
+	Reason   string
+}
+
+// ListFiles returns the status of every file found by the watcher of the
+// trail, in the order the watcher finds them, without following any.
+func (t *Trail) ListFiles() ([]FileStatus, error) {
+	files, err := t.watcher.Walk()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]FileStatus, len(files))
+	var candidates []string
+	for i, file := range files {
+		statuses[i] = FileStatus{Path: file, Reason: ignoreReason(t, file)}
+		if statuses[i].Reason == "" {
+			candidates = append(candidates, file)
+		}
+	}
+
+	// Of the files left, LatestOnly and MaxFiles keep the newest.
+	selected := make(map[string]bool)
+	reason := IgnoredBeyondMaxFiles
+	switch {
+	case t.options.LatestOnly:
+		candidates = newestFile(candidates)
+		reason = IgnoredNotLatest
+	case t.options.MaxFiles > 0:
+		candidates = newestFiles(candidates, t.options.MaxFiles)
+	}
+	for _, file := range candidates {
+		selected[file] = true
+	}
+
+	for i := range statuses {
+		switch {
+		case statuses[i].Reason != "":
+		case selected[statuses[i].Path]:
+			statuses[i].Followed = true
+		default:
+			statuses[i].Reason = reason
+		}
+	}
+
+	return statuses, nil
+}
+
+// matchPath reports whether reg matches path, either with the separators of
+// the platform or with forward slashes, so that the same patterns work on
+// every platform while those written with backslashes keep working on
+// Windows.
+func matchPath(reg *regexp.Regexp, path string) bool {
+	return reg.MatchString(path) || reg.MatchString(filepath.ToSlash(path))
+}
+
+// ignoreReason returns why a file is ignored, the first of the options of
+// the trail leaving it out, or an empty string when it isn't.
+func ignoreReason(t *Trail, path string) string {
+	dir := filepath.Dir(path)
+	name := filepath.Base(path)
+
+	switch {
+	case t.options.PathReg != nil && !matchPath(t.options.PathReg, dir):
+		return IgnoredByPathPattern
+	case t.options.PathIgnoreReg != nil && matchPath(t.options.PathIgnoreReg, dir):
+		return IgnoredByPathIgnorePattern
+	case t.options.FullPathReg != nil && !matchPath(t.options.FullPathReg, path):
+		return IgnoredByFullPathPattern
+	case t.options.FileReg != nil && !t.options.FileReg.MatchString(name):
+		return IgnoredByFilePattern
+	case t.options.FileIgnoreReg != nil && t.options.FileIgnoreReg.MatchString(name):
+		return IgnoredByFileIgnorePattern
+	case t.isOldToIgnore(path):
+		return IgnoredTooOld
+	}
+
+	if reason := t.sizeReason(path); reason != "" {
+		return reason
+	}
+
+	binary := isBinaryFile
+	if t.isGzipStream(path) {
+		binary = isBinaryGzip
+	}
+
+	if t.options.SkipBinary && binary(path) {
+		return IgnoredBinary
+	}
+
+	return ""
+}
+
+// sizeReason returns whether a file is smaller than MinFileSize or larger
+// than MaxFileSize. Files are only measured when deciding whether to follow
+// them, so followed files keep being followed as they grow.
+func (t *Trail) sizeReason(path string) string {
+	if t.options.MinFileSize <= 0 && t.options.MaxFileSize <= 0 {
+		return ""
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+
+	switch {
+	case t.options.MinFileSize > 0 && info.Size() < t.options.MinFileSize:
+		return IgnoredTooSmall
+	case t.options.MaxFileSize > 0 && info.Size() > t.options.MaxFileSize:
+		return IgnoredTooBig
+	}
+
+	return ""
+}
@@ -0,0 +1,125 @@
+package eye
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listFiles returns the reason of each file of dir, "followed" for the files
+// followed.
+func listFiles(t *testing.T, dir string, options *TrailOptions) map[string]string {
+	watcher, err := NewDirectoryWatcher(dir)
+	assert.Nil(t, err)
+
+	if options.FileIgnoreDuration == 0 {
+		options.FileIgnoreDuration = time.Hour
+	}
+
+	statuses, err := NewTrailWithOptions(watcher, options).ListFiles()
+	assert.Nil(t, err)
+
+	reasons := make(map[string]string)
+	for _, status := range statuses {
+		rel, err := filepath.Rel(dir, status.Path)
+		assert.Nil(t, err)
+
+		if status.Followed {
+			assert.Equal(t, "", status.Reason)
+			reasons[filepath.ToSlash(rel)] = "followed"
+		} else {
+			reasons[filepath.ToSlash(rel)] = status.Reason
+		}
+	}
+
+	return reasons
+}
+
+func TestListFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	write("app.log", "line\n")
+	write("app.txt", "line\n")
+	write("app.log.1", "line\n")
+	write("archive/app.log", "line\n")
+	write("current/app.log", "line\n")
+	write("small.log", "x")
+	write("big.log", "a line much longer than the others\n")
+	write("binary.log", "\x00\x01\x02\x03")
+	old := write("old.log", "line\n")
+	longAgo := time.Now().Add(-2 * time.Hour)
+	assert.Nil(t, os.Chtimes(old, longAgo, longAgo))
+
+	reasons := listFiles(t, dir, &TrailOptions{
+		FileReg:       regexp.MustCompile(`\.log`),
+		FileIgnoreReg: regexp.MustCompile(`\.\d+$`),
+		PathIgnoreReg: regexp.MustCompile(`/archive$`),
+		MinFileSize:   2,
+		MaxFileSize:   10,
+		SkipBinary:    true,
+	})
+
+	assert.Equal(t, map[string]string{
+		"app.log":         "followed",
+		"current/app.log": "followed",
+		"app.txt":         IgnoredByFilePattern,
+		"app.log.1":       IgnoredByFileIgnorePattern,
+		"archive/app.log": IgnoredByPathIgnorePattern,
+		"small.log":       IgnoredTooSmall,
+		"big.log":         IgnoredTooBig,
+		"binary.log":      IgnoredBinary,
+		"old.log":         IgnoredTooOld,
+	}, reasons)
+
+	reasons = listFiles(t, dir, &TrailOptions{
+		PathReg:     regexp.MustCompile(`/current$`),
+		FullPathReg: regexp.MustCompile(`/current/`),
+	})
+	assert.Equal(t, "followed", reasons["current/app.log"])
+	assert.Equal(t, IgnoredByPathPattern, reasons["app.log"])
+
+	reasons = listFiles(t, dir, &TrailOptions{
+		FullPathReg: regexp.MustCompile(`/current/`),
+	})
+	assert.Equal(t, "followed", reasons["current/app.log"])
+	assert.Equal(t, IgnoredByFullPathPattern, reasons["app.log"])
+}
+
+func TestListFilesNewest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for i, name := range []string{"a.log", "b.log", "c.log"} {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(path, []byte("line\n"), 0644))
+		modified := time.Now().Add(time.Duration(i-3) * time.Minute)
+		assert.Nil(t, os.Chtimes(path, modified, modified))
+	}
+
+	assert.Equal(t, map[string]string{
+		"a.log": IgnoredBeyondMaxFiles,
+		"b.log": "followed",
+		"c.log": "followed",
+	}, listFiles(t, dir, &TrailOptions{MaxFiles: 2}))
+
+	assert.Equal(t, map[string]string{
+		"a.log": IgnoredNotLatest,
+		"b.log": IgnoredNotLatest,
+		"c.log": "followed",
+	}, listFiles(t, dir, &TrailOptions{LatestOnly: true}))
+}
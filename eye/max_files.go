@@ -0,0 +1,84 @@
+package eye
+
+import (
+	"os"
+	"sort"
+	"strconv"
+)
+
+// newestFiles returns the n most recently modified of files, from the newest,
+// leaving out the files which can't be read.
+func newestFiles(files []string, n int) []string {
+	modTimes := make(map[string]int64, len(files))
+	var readable []string
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime().UnixNano()
+			readable = append(readable, file)
+		}
+	}
+
+	sort.SliceStable(readable, func(i, j int) bool {
+		return modTimes[readable[i]] > modTimes[readable[j]]
+	})
+
+	if len(readable) > n {
+		readable = readable[:n]
+	}
+
+	return readable
+}
+
+// limitFiles keeps the MaxFiles newest of the files found at startup, logging
+// how many were left out.
+func (t *Trail) limitFiles(files []string) []string {
+	if t.options.MaxFiles <= 0 || len(files) <= t.options.MaxFiles {
+		return files
+	}
+
+	newest := newestFiles(files, t.options.MaxFiles)
+	t.options.Logger.Warnln("Following the " + strconv.Itoa(len(newest)) + " newest files, skipping " +
+		strconv.Itoa(len(files)-len(newest)) + " beyond MaxFiles")
+
+	return newest
+}
+
+// makeRoom unfollows the least recently modified files of a trail, so that it
+// follows no more than MaxFiles once path gets followed. Files whose tail is
+// still being opened count as followed, but are left alone.
+func (t *Trail) makeRoom(path string) {
+	var others []string
+	for _, file := range t.FollowedFiles() {
+		if file != path {
+			others = append(others, file)
+		}
+	}
+
+	excess := len(others) + t.openingFiles(path) - t.options.MaxFiles + 1
+	if excess <= 0 {
+		return
+	}
+	if excess > len(others) {
+		excess = len(others)
+	}
+
+	sortFiles(others, OrderModTime)
+	for _, file := range others[:excess] {
+		t.logFor(file).Infoln("unfollowing for a newer file beyond MaxFiles")
+		t.unfollowFile(file)
+	}
+}
+
+// openingFiles returns how many files other than path have their first tail
+// being opened.
+func (t *Trail) openingFiles(path string) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	n := len(t.opening)
+	if t.opening[path] {
+		n--
+	}
+
+	return n
+}
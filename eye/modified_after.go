@@ -0,0 +1,44 @@
+package eye
+
+import (
+	"errors"
+	"time"
+)
+
+// ModifiedAfter is a cut-off on the modification time of files, either
+// relative to the current time or to the start of a day.
+type ModifiedAfter struct {
+	duration time.Duration
+	daysAgo  int
+	calendar bool
+}
+
+// ParseModifiedAfter parses a cut-off given as a duration, such as "6h", or
+// as one of the days "today" and "yesterday", which start at midnight in the
+// local time zone.
+func ParseModifiedAfter(expr string) (*ModifiedAfter, error) {
+	switch expr {
+	case "today":
+		return &ModifiedAfter{calendar: true}, nil
+	case "yesterday":
+		return &ModifiedAfter{calendar: true, daysAgo: 1}, nil
+	}
+
+	d, err := time.ParseDuration(expr)
+	if err != nil {
+		return nil, errors.New("invalid modification cut-off " + expr + ": expected a duration, today or yesterday")
+	}
+
+	return &ModifiedAfter{duration: d}, nil
+}
+
+// Cutoff returns the time files must have been modified after, as of now.
+func (m *ModifiedAfter) Cutoff(now time.Time) time.Time {
+	if !m.calendar {
+		return now.Add(-m.duration)
+	}
+
+	year, month, day := now.Date()
+
+	return time.Date(year, month, day-m.daysAgo, 0, 0, 0, 0, now.Location())
+}
@@ -0,0 +1,74 @@
+package eye
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseModifiedAfter(t *testing.T) {
+	now := time.Date(2020, 3, 1, 15, 30, 0, 0, time.Local)
+
+	today, err := ParseModifiedAfter("today")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2020, 3, 1, 0, 0, 0, 0, time.Local), today.Cutoff(now))
+
+	yesterday, err := ParseModifiedAfter("yesterday")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2020, 2, 29, 0, 0, 0, 0, time.Local), yesterday.Cutoff(now))
+
+	hours, err := ParseModifiedAfter("6h")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2020, 3, 1, 9, 30, 0, 0, time.Local), hours.Cutoff(now))
+
+	_, err = ParseModifiedAfter("last week")
+	assert.NotNil(t, err)
+}
+
+func TestIgnoreFileModifiedAfter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	stamp := func(name string, modTime time.Time) string {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+		assert.Nil(t, os.Chtimes(path, modTime, modTime))
+
+		return path
+	}
+
+	fresh := stamp("fresh.log", now)
+	beforeMidnight := stamp("before-midnight.log", midnight.Add(-time.Minute))
+	lastWeek := stamp("last-week.log", now.Add(-7*24*time.Hour))
+
+	today, err := ParseModifiedAfter("today")
+	assert.Nil(t, err)
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		FileIgnoreDuration: 30 * 24 * time.Hour,
+		FileModifiedAfter:  today,
+	})
+	assert.False(t, ignore(trail, fresh))
+	assert.True(t, ignore(trail, beforeMidnight))
+	assert.True(t, ignore(trail, lastWeek))
+
+	yesterday, err := ParseModifiedAfter("yesterday")
+	assert.Nil(t, err)
+
+	// FileIgnoreDuration still applies when its cut-off is more recent.
+	trail = NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		FileModifiedAfter:  yesterday,
+	})
+	assert.False(t, ignore(trail, fresh))
+	assert.Equal(t, now.Sub(midnight)+time.Minute > time.Hour, ignore(trail, beforeMidnight))
+	assert.True(t, ignore(trail, lastWeek))
+}
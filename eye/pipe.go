@@ -0,0 +1,103 @@
+package eye
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"time"
+)
+
+// isPipe reports whether path is a named pipe.
+func isPipe(path string) bool {
+	info, err := os.Stat(path)
+
+	return err == nil && info.Mode()&os.ModeNamedPipe != 0
+}
+
+// followPipe reads the lines of a named pipe as a stream, until the pipe is
+// unfollowed or the trail ends. Offsets don't apply to pipes, so lines carry
+// an offset of -1. The pipe is re-opened after a short delay when reading it
+// fails.
+func (t *Trail) followPipe(path string, handler LineHandler) {
+	go func() {
+		defer followed.release(path, t)
+
+		for {
+			pipe, err := openPipe(path)
+			if err != nil {
+				t.logFor(path).WithError(err).Errorln("failed to open pipe")
+				return
+			}
+
+			t.addPipe(path, pipe)
+
+			err = t.readPipe(path, pipe, handler)
+
+			if !t.removePipe(path, pipe) {
+				// The pipe was closed by unfollowFile or End.
+				return
+			}
+			pipe.Close()
+
+			if err != nil {
+				t.logFor(path).WithError(err).Errorln("pipe failed")
+			}
+
+			time.Sleep(tailRetryDelay)
+			t.logFor(path).Infoln("re-opening")
+		}
+	}()
+}
+
+// readPipe passes the lines of a pipe to the handler until reading it fails,
+// or its writers are gone.
+func (t *Trail) readPipe(path string, pipe *os.File, handler LineHandler) error {
+	reader := bufio.NewReader(pipe)
+
+	for {
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		t.handleStreamLine(path, strings.TrimSuffix(text, "\n"), handler)
+	}
+}
+
+// addPipe registers an open pipe.
+func (t *Trail) addPipe(path string, pipe *os.File) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.pipes == nil {
+		t.pipes = make(map[string]*os.File)
+	}
+
+	t.pipes[path] = pipe
+}
+
+// removePipe unregisters a pipe, without closing it. It returns false when the
+// pipe was already unregistered.
+func (t *Trail) removePipe(path string, pipe *os.File) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.pipes[path] != pipe {
+		return false
+	}
+
+	delete(t.pipes, path)
+
+	return true
+}
+
+// closePipes unregisters and closes the pipes for which keep returns false.
+// The caller must hold the mutex.
+func (t *Trail) closePipes(keep func(path string) bool) {
+	for path, pipe := range t.pipes {
+		if !keep(path) {
+			delete(t.pipes, path)
+			pipe.Close()
+		}
+	}
+}
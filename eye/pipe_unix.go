@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package eye
+
+import "os"
+
+// openPipe opens a named pipe for reading. It is opened for writing too, so
+// that opening it doesn't wait for a writer and reading it doesn't end when
+// writers come and go.
+func openPipe(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
@@ -0,0 +1,62 @@
+//go:build !windows
+// +build !windows
+
+package eye
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFollowPipe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.fifo")
+	assert.Nil(t, syscall.Mkfifo(path, 0644))
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{})
+
+	lines := make(chan Line, 4)
+	trail.followFile(path, func(line Line) error {
+		lines <- line
+
+		return nil
+	}, false)
+
+	// Writers come and go.
+	for _, text := range []string{"first\n", "second\nthird\n"} {
+		writer, err := os.OpenFile(path, os.O_WRONLY, 0)
+		assert.Nil(t, err)
+		_, err = writer.WriteString(text)
+		assert.Nil(t, err)
+		writer.Close()
+	}
+
+	for _, text := range []string{"first", "second", "third"} {
+		select {
+		case line := <-lines:
+			assert.Equal(t, text, line.Text)
+			assert.Equal(t, path, line.Path)
+			assert.Equal(t, int64(-1), line.Offset)
+		case <-time.After(time.Second):
+			t.Fatal("line not delivered: " + text)
+		}
+	}
+
+	assert.Equal(t, []string{path}, trail.FollowedFiles())
+
+	trail.unfollowFile(path)
+
+	assert.Equal(t, []string{}, trail.FollowedFiles())
+	assert.Eventually(t, func() bool {
+		return !followed.claimedBy(path, trail)
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,11 @@
+package eye
+
+import (
+	"errors"
+	"os"
+)
+
+// openPipe fails, since named pipes don't live on the filesystem on Windows.
+func openPipe(path string) (*os.File, error) {
+	return nil, errors.New("named pipes are not supported on Windows")
+}
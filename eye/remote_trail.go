@@ -0,0 +1,246 @@
+package eye
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// remoteChunkSize bounds what a RemoteTrail reads of its file at once.
+var remoteChunkSize = 64 * 1024
+
+// RemoteOptions are the different options supported by the RemoteTrail
+// object.
+type RemoteOptions struct {
+	// Address of the SSH server, as host:port.
+	Addr string
+
+	// Path of the file to follow on the remote host.
+	Path string
+
+	// ClientConfig holds the user, authentication methods and host key
+	// verification used to connect to the SSH server.
+	ClientConfig *ssh.ClientConfig
+
+	// PollInterval is how often the remote file is checked for growth.
+	// Defaults to one second.
+	PollInterval time.Duration
+
+	// MinBackoff and MaxBackoff bound the delay between reconnection
+	// attempts, which doubles after every failure. They default to one second
+	// and one minute.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Regex of the lines to flag as matched. It doesn't filter lines out.
+	LineReg *regexp.Regexp
+
+	// Logger to be used by the trail.
+	Logger *logrus.Logger
+}
+
+// RemoteTrail follows a single file on a remote host over SFTP. The file is
+// polled for growth and new lines are passed to a handler, just like a Trail
+// does for local files. Dropped connections are re-established with an
+// exponential backoff, resuming from the last read offset.
+type RemoteTrail struct {
+	options *RemoteOptions
+	done    chan bool
+	endOnce sync.Once
+	offset  int64
+	pending []byte
+}
+
+// NewRemoteTrail creates a new instance of a RemoteTrail. Unset options are
+// replaced with safe defaults.
+func NewRemoteTrail(options *RemoteOptions) *RemoteTrail {
+	defaults := *options
+
+	if defaults.PollInterval <= 0 {
+		defaults.PollInterval = time.Second
+	}
+
+	if defaults.MinBackoff <= 0 {
+		defaults.MinBackoff = time.Second
+	}
+
+	if defaults.MaxBackoff <= 0 {
+		defaults.MaxBackoff = time.Minute
+	}
+
+	if defaults.Logger == nil {
+		defaults.Logger = logrus.New()
+	}
+
+	return &RemoteTrail{
+		options: &defaults,
+		done:    make(chan bool),
+		offset:  -1,
+	}
+}
+
+// Follow starts following the remote file in the background. Lines already
+// present when the first connection is made are skipped.
+func (r *RemoteTrail) Follow(handler LineHandler) error {
+	r.options.Logger.Infoln("Sauron is now watching " + r.name())
+
+	go func() {
+		backoff := r.options.MinBackoff
+
+		for {
+			err := r.session(handler)
+			if err == nil {
+				return
+			}
+
+			r.options.Logger.Errorln("Connection to " + r.name() + " failed: " + err.Error() +
+				". Reconnecting in " + backoff.String())
+
+			select {
+			case <-time.After(backoff):
+			case <-r.done:
+				return
+			}
+
+			backoff *= 2
+			if backoff > r.options.MaxBackoff {
+				backoff = r.options.MaxBackoff
+			}
+		}
+	}()
+
+	return nil
+}
+
+// End stops following the remote file. Ending a trail again does nothing.
+func (r *RemoteTrail) End() {
+	r.options.Logger.Infoln("Stopping...")
+
+	r.endOnce.Do(func() { close(r.done) })
+}
+
+// name identifies the remote file in logs and lines.
+func (r *RemoteTrail) name() string {
+	return r.options.Addr + ":" + r.options.Path
+}
+
+// session connects to the remote host and polls the file until the trail ends,
+// in which case it returns nil, or the connection fails.
+func (r *RemoteTrail) session(handler LineHandler) error {
+	conn, err := ssh.Dial("tcp", r.options.Addr, r.options.ClientConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	r.options.Logger.Debugln("Connected to " + r.options.Addr)
+
+	ticker := time.NewTicker(r.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.poll(client, handler); err != nil {
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.done:
+			return nil
+		}
+	}
+}
+
+// poll reads whatever was appended to the remote file since the last poll, in
+// chunks of remoteChunkSize, and passes every complete line to the handler.
+func (r *RemoteTrail) poll(client *sftp.Client, handler LineHandler) error {
+	info, err := client.Stat(r.options.Path)
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+
+	if r.offset < 0 {
+		r.offset = size
+	}
+
+	if size < r.offset {
+		r.options.Logger.Debugln("Truncated: " + r.name())
+		r.offset = 0
+		r.pending = nil
+	}
+
+	if size == r.offset {
+		return nil
+	}
+
+	file, err := client.Open(r.options.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(r.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, remoteChunkSize)
+	for r.offset < size {
+		n := size - r.offset
+		if n > int64(len(chunk)) {
+			n = int64(len(chunk))
+		}
+
+		read, err := io.ReadFull(file, chunk[:n])
+		r.offset += int64(read)
+		r.pending = append(r.pending, chunk[:read]...)
+		r.emit(handler)
+
+		// The file shrinking while read is noticed by the next poll.
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// emit passes the complete lines read so far to the handler, keeping what is
+// left of the last one until the rest of it is read.
+func (r *RemoteTrail) emit(handler LineHandler) {
+	for {
+		i := bytes.IndexByte(r.pending, '\n')
+		if i < 0 {
+			return
+		}
+
+		line := Line{
+			Path:    r.name(),
+			Text:    string(r.pending[:i]),
+			Time:    time.Now(),
+			Offset:  r.offset - int64(len(r.pending)),
+			Matched: matchLine(r.options.LineReg, string(r.pending[:i])),
+		}
+		r.pending = r.pending[i+1:]
+
+		if err := handler(line); err != nil {
+			r.options.Logger.Errorln("Handler failed for " + r.name() + ": " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package eye
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpFixture is an in-process SSH server exposing the local filesystem
+// through the sftp subsystem.
+type sftpFixture struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	mutex    sync.Mutex
+	conns    []net.Conn
+	sessions chan bool
+}
+
+func newSftpFixture(t *testing.T) *sftpFixture {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	signer, err := ssh.NewSignerFromKey(key)
+	assert.Nil(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	fixture := &sftpFixture{
+		listener: listener,
+		config:   config,
+		sessions: make(chan bool, 16),
+	}
+
+	go fixture.serve()
+
+	return fixture
+}
+
+func (f *sftpFixture) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		f.mutex.Lock()
+		f.conns = append(f.conns, conn)
+		f.mutex.Unlock()
+
+		go f.handle(conn)
+	}
+}
+
+func (f *sftpFixture) handle(conn net.Conn) {
+	_, channels, requests, err := ssh.NewServerConn(conn, f.config)
+	if err != nil {
+		return
+	}
+
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			for req := range requests {
+				req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}()
+
+		server, err := sftp.NewServer(channel)
+		if err != nil {
+			return
+		}
+
+		f.sessions <- true
+
+		go server.Serve()
+	}
+}
+
+// drop closes every open connection, simulating a network failure.
+func (f *sftpFixture) drop() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+
+	f.conns = nil
+}
+
+func (f *sftpFixture) close() {
+	f.listener.Close()
+	f.drop()
+}
+
+func appendToFile(t *testing.T, path string, text string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+
+	_, err = file.WriteString(text)
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+}
+
+func TestRemoteTrailFollow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "remote.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("old\n"), 0644))
+
+	fixture := newSftpFixture(t)
+	defer fixture.close()
+
+	// Lines are read across several chunks.
+	defer func(size int) { remoteChunkSize = size }(remoteChunkSize)
+	remoteChunkSize = 4
+
+	trail := NewRemoteTrail(&RemoteOptions{
+		Addr: fixture.listener.Addr().String(),
+		Path: path,
+		ClientConfig: &ssh.ClientConfig{
+			User:            "sauron",
+			Auth:            []ssh.AuthMethod{ssh.Password("secret")},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		},
+		PollInterval: 10 * time.Millisecond,
+		MinBackoff:   10 * time.Millisecond,
+		LineReg:      regexp.MustCompile("new 2"),
+	})
+
+	lines := make(chan Line, 16)
+	trail.Follow(func(line Line) error {
+		lines <- line
+
+		return nil
+	})
+
+	<-fixture.sessions
+	time.Sleep(100 * time.Millisecond)
+
+	appendToFile(t, path, "new 1\npartial")
+
+	select {
+	case line := <-lines:
+		assert.Equal(t, "new 1", line.Text)
+		assert.Equal(t, fixture.listener.Addr().String()+":"+path, line.Path)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the first line")
+	}
+
+	// Drop the connection and make sure the trail picks up where it left.
+	fixture.drop()
+	<-fixture.sessions
+
+	appendToFile(t, path, " line\nnew 2\n")
+
+	for _, expected := range []string{"partial line", "new 2"} {
+		select {
+		case line := <-lines:
+			assert.Equal(t, expected, line.Text)
+			assert.Equal(t, expected == "new 2", line.Matched)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for " + expected)
+		}
+	}
+
+	// Ending the trail again does nothing.
+	trail.End()
+	trail.End()
+}
+
+func TestRemoteTrailEndNotFollowing(t *testing.T) {
+	trail := NewRemoteTrail(&RemoteOptions{Addr: "127.0.0.1:0", Path: "/var/log/app.log"})
+
+	ended := make(chan bool)
+	go func() {
+		trail.End()
+		close(ended)
+	}()
+
+	select {
+	case <-ended:
+	case <-time.After(time.Second):
+		t.Fatal("End blocked")
+	}
+}
@@ -0,0 +1,164 @@
+package eye
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hpcloud/tail"
+	"github.com/hpcloud/tail/util"
+)
+
+// drainPollInterval is how often removed files are read again, with
+// FollowAfterRemove.
+var drainPollInterval = 250 * time.Millisecond
+
+// drainFile has the tails of a removed file drain it, through the descriptors
+// they keep open on it, before they are unfollowed.
+func (t *Trail) drainFile(name string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, current := range t.tails {
+		if reads := t.reads[current]; reads != nil && current.Filename == name {
+			reads.removing.Do(func() { close(reads.removed) })
+		}
+	}
+	t.closePipes(func(path string) bool { return path != name })
+}
+
+// keepOpen opens a file again, closing the descriptor previously kept open on
+// it, so that its lines can still be read once it is removed. It returns nil
+// when the file can't be opened, or isn't the one identified by inode and dev
+// when known.
+func keepOpen(path string, previous *os.File, inode, dev uint64) *os.File {
+	if previous != nil {
+		previous.Close()
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil
+	}
+
+	if current, currentDev := infoID(info); inode != 0 && (current != inode || currentDev != dev) {
+		file.Close()
+		return nil
+	}
+
+	return file
+}
+
+// isRemoved reports whether a path no longer names the file identified by
+// inode and dev, or no file at all when they are unknown.
+func isRemoved(path string, inode, dev uint64) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+
+	current, currentDev := infoID(info)
+
+	return inode != 0 && (current != inode || currentDev != dev)
+}
+
+// isFollowing reports whether a tail is still registered.
+func (t *Trail) isFollowing(current *tail.Tail) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	_, ok := t.reads[current]
+
+	return ok
+}
+
+// drainRemoved stops the tail of a removed file, and passes the lines still
+// written to it to emit, reading them from offset through the descriptor kept
+// open on it, until none is written for FollowAfterRemove, the file is
+// unfollowed or the trail ends. It then unregisters the tail. The lines the
+// tail read past offset are dropped, and read again.
+func (t *Trail) drainRemoved(path string, current *tail.Tail, kept *os.File, offset int64, emit func(line *tail.Line)) {
+	defer t.removeTail(current)
+
+	go func() {
+		for range current.Lines {
+		}
+	}()
+	current.Stop()
+
+	if kept == nil || offset < 0 {
+		t.logFor(path).Debugln("removed, unfollowing")
+		return
+	}
+
+	if _, err := kept.Seek(offset, io.SeekStart); err != nil {
+		t.logFor(path).WithError(err).Errorln("failed to drain")
+		return
+	}
+
+	t.logFor(path).Debugln("removed, draining")
+
+	poll := drainPollInterval
+	if poll > t.options.FollowAfterRemove {
+		poll = t.options.FollowAfterRemove
+	}
+
+	reader := bufio.NewReader(kept)
+	var pending string
+	written := time.Now()
+	for {
+		text, err := reader.ReadString('\n')
+		pending += text
+
+		if err == nil {
+			t.emitDrained(strings.TrimSuffix(pending, "\n"), emit)
+			pending = ""
+			written = time.Now()
+			continue
+		}
+
+		if err != io.EOF {
+			t.logFor(path).WithError(err).Errorln("failed to drain")
+			return
+		}
+
+		if time.Since(written) >= t.options.FollowAfterRemove || !t.isFollowing(current) {
+			break
+		}
+
+		select {
+		case <-time.After(poll):
+		case <-t.done:
+			return
+		}
+	}
+
+	// A line left unterminated is never going to be.
+	if pending != "" {
+		t.emitDrained(pending, emit)
+	}
+
+	t.logFor(path).Debugln("drained, unfollowing")
+}
+
+// emitDrained passes a line read from a removed file to emit, split at
+// MaxLineSize as the tail library does.
+func (t *Trail) emitDrained(text string, emit func(line *tail.Line)) {
+	now := time.Now()
+	parts := []string{text}
+	if t.options.MaxLineSize > 0 && len(text) > t.options.MaxLineSize {
+		parts = util.PartitionString(text, t.options.MaxLineSize)
+	}
+
+	for _, part := range parts {
+		emit(&tail.Line{Text: part, Time: now})
+	}
+}
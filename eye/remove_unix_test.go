@@ -0,0 +1,87 @@
+//go:build !windows
+// +build !windows
+
+package eye
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/fsnotify.v1"
+)
+
+func TestFollowAfterRemove(t *testing.T) {
+	defer func(interval time.Duration) { drainPollInterval = interval }(drainPollInterval)
+	drainPollInterval = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "removed.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("first\n"), 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{path}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		FollowAfterRemove:  300 * time.Millisecond,
+		SeekStart:          SeekFromStart,
+	})
+
+	received := make(chan Line, 4)
+	trail.Follow(func(line Line) error {
+		received <- line
+		return nil
+	})
+	defer trail.End()
+
+	receive := func() Line {
+		select {
+		case line := <-received:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("line not delivered")
+			return Line{}
+		}
+	}
+
+	assert.Equal(t, "first", receive().Text)
+
+	// The file is removed while a writer keeps it open.
+	writer, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	defer writer.Close()
+
+	assert.Nil(t, os.Remove(path))
+	events := watcher.TestData()["watchChannel"].(chan FileEvent)
+	events <- FileEvent{Name: "removed.log", Path: path, Time: time.Now(), Op: fsnotify.Remove}
+
+	for _, text := range []string{"second", "third"} {
+		_, err := writer.WriteString(text + "\n")
+		assert.Nil(t, err)
+	}
+
+	second := receive()
+	assert.Equal(t, "second", second.Text)
+	assert.Equal(t, int64(6), second.Offset)
+
+	third := receive()
+	assert.Equal(t, "third", third.Text)
+	assert.Equal(t, int64(13), third.Offset)
+
+	// Once nothing is written for FollowAfterRemove, it is unfollowed.
+	assert.Eventually(t, func() bool {
+		return len(trail.FollowedFiles()) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return !followed.claimedBy(path, trail)
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,126 @@
+package eye
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// A Sink is a destination for the lines of a Trail. Unlike a LineHandler, a
+// Sink owns the resources it writes to and must be closed once the trail has
+// ended.
+type Sink interface {
+	Write(line Line) error
+	Close() error
+}
+
+// SinkHandler wraps a Sink as a LineHandler, so that it can be used anywhere a
+// handler is expected. Errors returned by the sink are passed through.
+func SinkHandler(sink Sink) LineHandler {
+	return func(line Line) error {
+		return sink.Write(line)
+	}
+}
+
+// WriterSink is a Sink writing the text of every line, followed by a newline,
+// to an io.Writer. It is safe for concurrent use.
+type WriterSink struct {
+	writer io.Writer
+	mutex  sync.Mutex
+}
+
+// NewWriterSink creates a new instance of a WriterSink.
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{writer: writer}
+}
+
+// Write writes a single line to the underlying writer.
+func (s *WriterSink) Write(line Line) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, err := io.WriteString(s.writer, line.Text+"\n")
+
+	return err
+}
+
+// Close does nothing, since the underlying writer is owned by the caller.
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// FileSink is a Sink appending lines to a file.
+type FileSink struct {
+	*WriterSink
+	file *os.File
+}
+
+// NewFileSink creates a new instance of a FileSink. The file is created if it
+// does not exist yet.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{
+		WriterSink: NewWriterSink(file),
+		file:       file,
+	}, nil
+}
+
+// Close closes the underlying file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// channelSink is a Sink delivering lines on a channel, which is closed when
+// the sink is. Writes block until the line is received, or done is closed.
+type channelSink struct {
+	lines  chan Line
+	done   chan bool
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// newChannelSink creates a new instance of a channelSink, whose writes give up
+// once done is closed.
+func newChannelSink(done chan bool) *channelSink {
+	return &channelSink{
+		lines: make(chan Line),
+		done:  done,
+	}
+}
+
+// Write delivers a line on the channel. Lines written after the sink was
+// closed are discarded.
+func (s *channelSink) Write(line Line) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.closed {
+		return nil
+	}
+
+	select {
+	case s.lines <- line:
+	case <-s.done:
+	}
+
+	return nil
+}
+
+// Close closes the channel once the writes in progress are over. Closing the
+// sink again does nothing.
+func (s *channelSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.lines)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package eye
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingSink struct {
+	err error
+}
+
+func (s *failingSink) Write(line Line) error { return s.err }
+
+func (s *failingSink) Close() error { return nil }
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	assert.Nil(t, sink.Write(Line{Text: "first"}))
+	assert.Nil(t, sink.Write(Line{Text: "second"}))
+	assert.Nil(t, sink.Close())
+
+	assert.Equal(t, "first\nsecond\n", buf.String())
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "out.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("existing\n"), 0644))
+
+	sink, err := NewFileSink(path)
+	assert.Nil(t, err)
+
+	assert.Nil(t, sink.Write(Line{Text: "appended"}))
+	assert.Nil(t, sink.Close())
+
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "existing\nappended\n", string(content))
+}
+
+func TestFileSinkOpenError(t *testing.T) {
+	_, err := NewFileSink(filepath.Join("does", "not", "exist", "out.log"))
+
+	assert.NotNil(t, err)
+}
+
+func TestSinkHandlerPropagatesErrors(t *testing.T) {
+	expected := errors.New("disk full")
+	handler := SinkHandler(&failingSink{err: expected})
+
+	assert.Equal(t, expected, handler(Line{Text: "lost"}))
+}
+
+func TestTrailLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	watcher, err := NewDirectoryWatcher(dir)
+	assert.Nil(t, err)
+
+	trail := NewTrailWithOptions(watcher, &TrailOptions{FileIgnoreDuration: time.Hour})
+	lines := trail.Lines()
+
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("one\ntwo\n"), 0644))
+
+	for _, expected := range []string{"one", "two"} {
+		select {
+		case line := <-lines:
+			assert.Equal(t, expected, line.Text)
+			assert.Equal(t, path, line.Path)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no line received")
+		}
+	}
+
+	trail.End()
+
+	select {
+	case _, ok := <-lines:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed on End")
+	}
+}
+
+func TestTrailLinesFollowFailure(t *testing.T) {
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{}, errors.New("oops"))
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{})
+
+	_, ok := <-trail.Lines()
+	assert.False(t, ok)
+}
+
+func TestChannelSinkClosedWhileBlocked(t *testing.T) {
+	done := make(chan bool)
+	sink := newChannelSink(done)
+
+	written := make(chan error)
+	go func() {
+		written <- sink.Write(Line{Text: "nobody reads this"})
+	}()
+
+	close(done)
+	assert.Nil(t, <-written)
+	assert.Nil(t, sink.Close())
+	assert.Nil(t, sink.Close())
+	assert.Nil(t, sink.Write(Line{Text: "after close"}))
+
+	_, ok := <-sink.lines
+	assert.False(t, ok)
+}
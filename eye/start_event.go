@@ -0,0 +1,36 @@
+package eye
+
+import (
+	"strconv"
+	"time"
+)
+
+// StartLine returns a line marking that following began, such as "[desc]
+// sauron started watching 3 files", flagged with Line.Start.
+func StartLine(desc string, files int) Line {
+	text := "sauron started watching " + strconv.Itoa(files) + " files"
+	if files == 1 {
+		text = "sauron started watching 1 file"
+	}
+
+	if desc != "" {
+		text = "[" + desc + "] " + text
+	}
+
+	return Line{
+		Text:    text,
+		Time:    time.Now(),
+		Offset:  -1,
+		Matched: true,
+		Start:   true,
+	}
+}
+
+// FoundFiles returns how many files Follow found to follow when it began,
+// those which aren't ignored.
+func (t *Trail) FoundFiles() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.found
+}
@@ -0,0 +1,58 @@
+package eye
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hpcloud/tail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFollowFoundFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var paths []string
+	for _, name := range []string{"a.log", "b.log", "c.txt"} {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+		paths = append(paths, path)
+	}
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return(paths, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileReg:            regexp.MustCompile(`\.log$`),
+		FileIgnoreDuration: time.Hour,
+	})
+	defer trail.End()
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, _ := fakeTail(filename)
+
+		return current, nil
+	}
+
+	assert.Equal(t, 0, trail.FoundFiles())
+	assert.Nil(t, trail.Follow(func(line Line) error { return nil }))
+
+	// The ignored file isn't counted.
+	assert.Equal(t, 2, trail.FoundFiles())
+}
+
+func TestStartLine(t *testing.T) {
+	assert.Equal(t, "sauron started watching 1 file", StartLine("", 1).Text)
+	assert.Equal(t, "[db] sauron started watching 0 files", StartLine("db", 0).Text)
+
+	line := StartLine("app", 2)
+	assert.True(t, line.Start)
+	assert.True(t, line.Matched)
+	assert.Equal(t, int64(-1), line.Offset)
+}
@@ -0,0 +1,152 @@
+package eye
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+// symlinkCheckInterval is how often a SymlinkWatcher resolves its link again.
+var symlinkCheckInterval = time.Second
+
+// SymlinkWatcher is an implementation of a Watcher for a symlink to a file,
+// such as current pointing to the log of the day. The target of the link is
+// followed, and the link is resolved again every second: once it points to
+// another file, the previous target is reported removed and the new one
+// created, so that trails move on to it.
+type SymlinkWatcher struct {
+	link     string
+	interval time.Duration
+
+	mutex  sync.Mutex
+	target string
+
+	done    chan bool
+	stopped chan bool
+	endOnce sync.Once
+}
+
+// NewSymlinkWatcher creates a SymlinkWatcher for a symlink to a regular file.
+func NewSymlinkWatcher(link string) (*SymlinkWatcher, error) {
+	linkInfo, err := os.Lstat(link)
+	if err != nil {
+		return nil, err
+	}
+
+	if linkInfo.Mode()&os.ModeSymlink == 0 {
+		return nil, errors.New("Unable to watch. Not a symlink.")
+	}
+
+	w := &SymlinkWatcher{link: link, interval: symlinkCheckInterval}
+
+	target, err := w.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := os.Stat(target); err != nil {
+		return nil, err
+	} else if !info.Mode().IsRegular() {
+		return nil, errors.New("Unable to watch. Not a link to a regular file.")
+	}
+
+	w.target = target
+
+	return w, nil
+}
+
+// IsSymlink reports whether path is a symlink.
+func IsSymlink(path string) bool {
+	info, err := os.Lstat(path)
+
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// resolve returns the absolute path of the current target of the link.
+func (w *SymlinkWatcher) resolve() (string, error) {
+	target, err := filepath.EvalSymlinks(w.link)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(target)
+}
+
+// Walk returns the absolute path of the current target of the link.
+func (w *SymlinkWatcher) Walk() (paths []string, err error) {
+	target, err := w.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	w.mutex.Lock()
+	w.target = target
+	w.mutex.Unlock()
+
+	return []string{target}, nil
+}
+
+// Watch starts resolving the link periodically, reporting its new targets to
+// newf until End is called. The link missing for a while, as while it is
+// being replaced, goes unnoticed.
+func (w *SymlinkWatcher) Watch(newf chan FileEvent) error {
+	w.done = make(chan bool)
+	w.stopped = make(chan bool)
+
+	go func() {
+		defer close(w.stopped)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-w.done:
+				return
+			}
+
+			target, err := w.resolve()
+			if err != nil {
+				continue
+			}
+
+			w.mutex.Lock()
+			previous := w.target
+			w.target = target
+			w.mutex.Unlock()
+
+			if target == previous {
+				continue
+			}
+
+			for _, event := range []FileEvent{
+				{Name: filepath.Base(previous), Path: previous, Op: fsnotify.Remove, Time: time.Now()},
+				{Name: filepath.Base(target), Path: target, Op: fsnotify.Create, Time: time.Now()},
+			} {
+				select {
+				case newf <- event:
+				case <-w.done:
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// End stops resolving the link, returning once no more events will be sent.
+// It does nothing if the watcher isn't watching, or was already ended.
+func (w *SymlinkWatcher) End() {
+	if w.done == nil {
+		return
+	}
+
+	w.endOnce.Do(func() { close(w.done) })
+	<-w.stopped
+}
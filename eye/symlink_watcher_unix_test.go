@@ -0,0 +1,138 @@
+//go:build !windows
+// +build !windows
+
+package eye
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSymlinkWatcherRepointed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// EvalSymlinks resolves the temporary directory too, as on macOS.
+	dir, err = filepath.EvalSymlinks(dir)
+	assert.Nil(t, err)
+
+	first := filepath.Join(dir, "app-2024-06-01.log")
+	second := filepath.Join(dir, "app-2024-06-02.log")
+	current := filepath.Join(dir, "current")
+	assert.Nil(t, ioutil.WriteFile(first, nil, 0644))
+	assert.Nil(t, os.Symlink(filepath.Base(first), current))
+
+	interval := symlinkCheckInterval
+	symlinkCheckInterval = 10 * time.Millisecond
+	defer func() { symlinkCheckInterval = interval }()
+
+	watcher, err := NewSymlinkWatcher(current)
+	assert.Nil(t, err)
+
+	files, err := watcher.Walk()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{first}, files)
+
+	trail := NewTrailWithOptions(watcher, &TrailOptions{FileIgnoreDuration: time.Hour})
+	defer trail.End()
+
+	received := make(chan Line, 4)
+	assert.Nil(t, trail.Follow(func(line Line) error {
+		received <- line
+
+		return nil
+	}))
+
+	assert.Eventually(t, func() bool {
+		return len(trail.FollowedFiles()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	appendLine := func(path, text string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		assert.Nil(t, err)
+		f.WriteString(text + "\n")
+		f.Close()
+	}
+
+	receive := func() Line {
+		select {
+		case line := <-received:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("no line delivered")
+			return Line{}
+		}
+	}
+
+	appendLine(first, "day 1")
+	line := receive()
+	assert.Equal(t, "day 1", line.Text)
+	assert.Equal(t, first, line.Path)
+
+	// The link is repointed the way ln -sfn does, through a rename.
+	assert.Nil(t, ioutil.WriteFile(second, []byte("day 2\n"), 0644))
+	next := filepath.Join(dir, "current.next")
+	assert.Nil(t, os.Symlink(filepath.Base(second), next))
+	assert.Nil(t, os.Rename(next, current))
+
+	select {
+	case line = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the new target was not followed")
+	}
+	assert.Equal(t, "day 2", line.Text)
+	assert.Equal(t, second, line.Path)
+
+	assert.Eventually(t, func() bool {
+		files := trail.FollowedFiles()
+		return len(files) == 1 && files[0] == second
+	}, 5*time.Second, 10*time.Millisecond)
+	// The tail library only notices appended lines once it watches the file,
+	// which it starts doing at its end.
+	time.Sleep(100 * time.Millisecond)
+
+	// The previous target is no longer followed.
+	appendLine(first, "late day 1")
+	appendLine(second, "more day 2")
+	assert.Equal(t, "more day 2", receive().Text)
+}
+
+func TestNewSymlinkWatcherNotSymlink(t *testing.T) {
+	_, err := NewSymlinkWatcher("../_resources/example.log")
+	assert.NotNil(t, err)
+}
+
+func TestDirectoryWatcherFollowSymlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	dir, err = filepath.EvalSymlinks(dir)
+	assert.Nil(t, err)
+
+	release := filepath.Join(dir, "release-42")
+	assert.Nil(t, os.MkdirAll(release, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(release, "app.log"), []byte("line\n"), 0644))
+	link := filepath.Join(dir, "current")
+	assert.Nil(t, os.Symlink(release, link))
+
+	// Without FollowSymlinks, the link is walked as a file of its own.
+	watcher, err := NewDirectoryWatcherWithOptions(link, &DirectoryWatcherOptions{})
+	assert.Nil(t, err)
+	files, err := watcher.Walk()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{link}, files)
+
+	watcher, err = NewDirectoryWatcherWithOptions(link, &DirectoryWatcherOptions{FollowSymlinks: true})
+	assert.Nil(t, err)
+	files, err = watcher.Walk()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(release, "app.log")}, files)
+}
@@ -0,0 +1,93 @@
+package eye
+
+import (
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+const (
+	// seekedMessage starts the message the tail library logs once it opened
+	// the file of a tail and sought its starting location.
+	seekedMessage = "Seeked"
+
+	// reopeningMessage starts the message the tail library logs before it
+	// re-opens the file of a tail, after it was rotated or truncated.
+	reopeningMessage = "Re-opening"
+
+	// reopenedMessage starts the message the tail library logs once it
+	// re-opened the file of a tail, to read it from its beginning.
+	reopenedMessage = "Successfully reopened"
+)
+
+// openedFile tells that the tail library opened the file of a tail, or
+// re-opened it. Inode and dev identify it, and are 0 when unknown.
+type openedFile struct {
+	inode    uint64
+	dev      uint64
+	reopened bool
+}
+
+// tailLogger is the logger of the tail following a file. It discards what the
+// tail library logs, but for its opening and re-opening the file, which it
+// reports on opened. The library logs them before reading the file, and waits
+// for the report to be received, so that the lines received afterwards are
+// known to come from that file.
+//
+// The file opened is identified by the path naming the same one before and
+// after it is opened, which the library logs in between, and is unknown
+// otherwise: the path must already name the file the tail starts with when
+// the logger is created.
+type tailLogger struct {
+	*log.Logger
+	path   string
+	inode  uint64
+	dev    uint64
+	opened chan openedFile
+	done   chan bool
+}
+
+// newTailLogger creates the logger of the tail of a file, to be closed once
+// its lines are no longer consumed.
+func newTailLogger(path string) *tailLogger {
+	inode, dev := fileID(path)
+
+	return &tailLogger{
+		Logger: log.New(ioutil.Discard, "", 0),
+		path:   path,
+		inode:  inode,
+		dev:    dev,
+		opened: make(chan openedFile),
+		done:   make(chan bool),
+	}
+}
+
+// Printf reports the opening and re-opening of the file, until the logger is
+// closed. It is only called by the tail library, one message at a time.
+func (l *tailLogger) Printf(format string, v ...interface{}) {
+	if strings.HasPrefix(format, reopeningMessage) {
+		l.inode, l.dev = fileID(l.path)
+		return
+	}
+
+	reopened := strings.HasPrefix(format, reopenedMessage)
+	if !reopened && !strings.HasPrefix(format, seekedMessage) {
+		return
+	}
+
+	opened := openedFile{reopened: reopened}
+	if inode, dev := fileID(l.path); inode == l.inode && dev == l.dev {
+		opened.inode, opened.dev = inode, dev
+	}
+
+	select {
+	case l.opened <- opened:
+	case <-l.done:
+	}
+}
+
+// close stops reporting, so that the tail library never waits for reports
+// nobody receives.
+func (l *tailLogger) close() {
+	close(l.done)
+}
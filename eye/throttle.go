@@ -0,0 +1,106 @@
+package eye
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// statErrorWindow is how long the failures to get the info of a file are
+// suppressed once one of them was logged.
+var statErrorWindow = time.Minute
+
+// errorThrottle logs the failures of a kind once per path and window, counting
+// the others so that the next failure logged tells how many were suppressed.
+// It is safe for concurrent use.
+type errorThrottle struct {
+	window time.Duration
+	now    func() time.Time
+
+	mutex sync.Mutex
+	paths map[string]*throttledPath
+}
+
+// throttledPath is when a failure was last logged for a path, and how many
+// were suppressed since.
+type throttledPath struct {
+	logged     time.Time
+	suppressed int
+}
+
+// suppressedErrors is how many failures were suppressed for a path forgotten
+// by a throttle.
+type suppressedErrors struct {
+	path  string
+	count int
+}
+
+// newErrorThrottle creates a throttle suppressing failures for window after
+// logging one.
+func newErrorThrottle(window time.Duration) *errorThrottle {
+	return &errorThrottle{
+		window: window,
+		now:    time.Now,
+		paths:  make(map[string]*throttledPath),
+	}
+}
+
+// allow reports whether a failure for path is to be logged, along with the
+// number of failures suppressed since the previous one logged. Other paths
+// whose window is over are forgotten as failures are logged, so that paths
+// which went away for good don't pile up, and the failures suppressed for
+// them are returned too, sorted by path, so that they are reported anyway.
+func (e *errorThrottle) allow(path string) (bool, int, []suppressedErrors) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := e.now()
+
+	if p, ok := e.paths[path]; ok && now.Sub(p.logged) < e.window {
+		p.suppressed++
+		return false, 0, nil
+	}
+
+	suppressed := 0
+	if p, ok := e.paths[path]; ok {
+		suppressed = p.suppressed
+	}
+
+	var forgotten []suppressedErrors
+	for other, p := range e.paths {
+		if other == path || now.Sub(p.logged) < e.window {
+			continue
+		}
+
+		if p.suppressed > 0 {
+			forgotten = append(forgotten, suppressedErrors{path: other, count: p.suppressed})
+		}
+		delete(e.paths, other)
+	}
+	e.paths[path] = &throttledPath{logged: now}
+
+	sort.Slice(forgotten, func(i, j int) bool { return forgotten[i].path < forgotten[j].path })
+
+	return true, suppressed, forgotten
+}
+
+// logStatError logs a failure to get the info of a file, unless one was
+// logged for the same file within statErrorWindow.
+func (t *Trail) logStatError(path string, err error) {
+	allowed, suppressed, forgotten := t.statErrors.allow(path)
+	if !allowed {
+		return
+	}
+
+	for _, f := range forgotten {
+		t.options.Logger.Errorln("failed to get file info of " + f.path + " (" + strconv.Itoa(f.count) + " similar failures suppressed)")
+	}
+
+	message := "failed to get file info. " + err.Error()
+	if suppressed > 0 {
+		message += " (" + strconv.Itoa(suppressed) + " similar failures suppressed)"
+	}
+
+	t.options.Logger.Errorln(message)
+}
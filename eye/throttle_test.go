@@ -0,0 +1,64 @@
+package eye
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	logtest "github.com/Sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorThrottle(t *testing.T) {
+	throttle := newErrorThrottle(time.Minute)
+	now := time.Now()
+	throttle.now = func() time.Time { return now }
+
+	allowed, suppressed, _ := throttle.allow("/var/log/a.log")
+	assert.True(t, allowed)
+	assert.Equal(t, 0, suppressed)
+
+	for i := 0; i < 3; i++ {
+		allowed, _, _ = throttle.allow("/var/log/a.log")
+		assert.False(t, allowed)
+	}
+
+	// Paths are throttled on their own.
+	allowed, _, _ = throttle.allow("/var/log/b.log")
+	assert.True(t, allowed)
+	allowed, _, _ = throttle.allow("/var/log/b.log")
+	assert.False(t, allowed)
+
+	now = now.Add(time.Minute)
+	allowed, suppressed, forgotten := throttle.allow("/var/log/a.log")
+	assert.True(t, allowed)
+	assert.Equal(t, 3, suppressed)
+
+	// The path whose window is over was forgotten, reporting the failures
+	// suppressed for it.
+	assert.Equal(t, []suppressedErrors{{path: "/var/log/b.log", count: 1}}, forgotten)
+	assert.Equal(t, 1, len(throttle.paths))
+}
+
+func TestIsOldToIgnoreThrottlesStatErrors(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{Logger: logger, FileIgnoreDuration: time.Hour})
+
+	now := time.Now()
+	trail.statErrors.now = func() time.Time { return now }
+
+	missing := filepath.Join(os.TempDir(), "sauron-missing.log")
+	for i := 0; i < 10; i++ {
+		assert.False(t, trail.isOldToIgnore(missing))
+	}
+
+	assert.Equal(t, 1, len(hook.AllEntries()))
+	assert.Contains(t, hook.LastEntry().Message, "failed to get file info")
+
+	now = now.Add(statErrorWindow)
+	assert.False(t, trail.isOldToIgnore(missing))
+
+	assert.Equal(t, 2, len(hook.AllEntries()))
+	assert.Contains(t, hook.LastEntry().Message, "(9 similar failures suppressed)")
+}
@@ -2,23 +2,41 @@
 package eye
 
 import (
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strconv"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/hpcloud/tail"
+	"github.com/hpcloud/tail/ratelimiter"
 	"github.com/jasonlvhit/gocron"
 	fsnotify "gopkg.in/fsnotify.v1"
 )
 
-// Line contains a log line of a log file.
+// Line contains a log line of a log file. Offset is the position of the line
+// in the file, or -1 when it isn't known. Matched tells whether the line
+// matches the LineReg of the trail, and is always set when there is none.
+// Inode and Dev identify the file the line was read from, so that its lines
+// can be told from those of the file rotated in after it; they are 0 when
+// unknown, as on Windows, for streams, and for files replaced as they were
+// opened. Start marks the lines built by StartLine.
 type Line struct {
-	Path string
-	Text string
-	Time time.Time
-	Err  error
+	Path    string
+	Text    string
+	Time    time.Time
+	Offset  int64
+	Inode   uint64
+	Dev     uint64
+	Stream  string
+	Matched bool
+	Start   bool
+	Err     error
 }
 
 // LineHandler is a function capable to handle log lines.
@@ -30,20 +48,61 @@ type LineHandler func(line Line) error
 //
 // However, unlike the Watcher, a Trail is limited to traditional filesystems.
 type Trail struct {
-	watcher Watcher
-	done    chan bool
-	tails   []*tail.Tail
-	options *TrailOptions
+	watcher       Watcher
+	done          chan bool
+	tails         []*tail.Tail
+	opening       map[string]bool
+	reads         map[*tail.Tail]*tailReads
+	stale         map[string]int64
+	pipes         map[string]*os.File
+	mutex         sync.Mutex
+	options       *TrailOptions
+	latency       *Histogram
+	statErrors    *errorThrottle
+	truncateCheck time.Duration
+	tailFile      func(filename string, config tail.Config) (*tail.Tail, error)
+	stat          func(name string) (os.FileInfo, error)
+	now           func() time.Time
+	handler       LineHandler
+	found         int
+	latest        string
+	ended         chan bool
+	endOnce       sync.Once
 }
 
+const (
+	// SeekFromEnd follows the files found at startup from their end, so that
+	// only lines written afterwards are delivered.
+	SeekFromEnd = "end"
+
+	// SeekFromStart follows the files found at startup from their beginning,
+	// delivering their existing lines before the new ones.
+	SeekFromStart = "start"
+)
+
+// snapshotDebounceInterval is the DebounceInterval of snapshot trails which
+// don't set one.
+const snapshotDebounceInterval = 100 * time.Millisecond
+
+// tailRetryDelay is how long a trail waits before re-opening a file whose tail
+// reported an error.
+var tailRetryDelay = time.Second
+
 // NewTrail creates a new instance of a Trail.
 func NewTrail(watcher Watcher) *Trail {
 	return &Trail{
 		watcher: watcher,
 		done:    make(chan bool),
 		options: &TrailOptions{
-			Logger: logrus.New(),
+			Logger:           logrus.New(),
+			TailErrorRetries: 1,
 		},
+		latency:       NewHistogram(DefaultLatencyBuckets),
+		statErrors:    newErrorThrottle(statErrorWindow),
+		truncateCheck: truncateCheckInterval,
+		tailFile:      tail.TailFile,
+		stat:          os.Stat,
+		now:           time.Now,
 	}
 }
 
@@ -53,13 +112,45 @@ func NewTrail(watcher Watcher) *Trail {
 func NewTrailWithOptions(watcher Watcher, options *TrailOptions) *Trail {
 	// Create a default set of options.
 	defaults := &TrailOptions{
-		Logger:             logrus.New(),
-		PollChanges:        options.PollChanges,
-		FileReg:            options.FileReg,
-		FileIgnoreReg:      options.FileIgnoreReg,
-		FileIgnoreDuration: options.FileIgnoreDuration,
-		FileFollowDuration: options.FileFollowDuration,
-		PathReg:            options.PathReg,
+		Logger:              logrus.New(),
+		PollChanges:         options.PollChanges,
+		FileReg:             options.FileReg,
+		FileIgnoreReg:       options.FileIgnoreReg,
+		FileIgnoreDuration:  options.FileIgnoreDuration,
+		FileModifiedAfter:   options.FileModifiedAfter,
+		FileFollowDuration:  options.FileFollowDuration,
+		MinFileSize:         options.MinFileSize,
+		MaxFileSize:         options.MaxFileSize,
+		DisableUnfollower:   options.DisableUnfollower,
+		ActiveWindow:        options.ActiveWindow,
+		LatestOnly:          options.LatestOnly,
+		MaxFiles:            options.MaxFiles,
+		PathReg:             options.PathReg,
+		PathIgnoreReg:       options.PathIgnoreReg,
+		FullPathReg:         options.FullPathReg,
+		Desc:                options.Desc,
+		SkipBinary:          options.SkipBinary,
+		GzipStreamReg:       options.GzipStreamReg,
+		ReOpen:              options.ReOpen,
+		ReopenOnTruncate:    options.ReopenOnTruncate,
+		TailErrorRetries:    options.TailErrorRetries,
+		FollowAfterRemove:   options.FollowAfterRemove,
+		HandlerBufferSize:   options.HandlerBufferSize,
+		HandlerBufferPolicy: options.HandlerBufferPolicy,
+		DebounceInterval:    options.DebounceInterval,
+		NewFileGrace:        options.NewFileGrace,
+		Snapshot:            options.Snapshot,
+		MaxLineSize:         options.MaxLineSize,
+		RateLimitSize:       options.RateLimitSize,
+		RateLimitInterval:   options.RateLimitInterval,
+		InitialOrder:        options.InitialOrder,
+		SeekStart:           options.SeekStart,
+		LineReg:             options.LineReg,
+	}
+
+	// Snapshots are only taken once a file is done being rewritten.
+	if defaults.Snapshot && defaults.DebounceInterval <= 0 {
+		defaults.DebounceInterval = snapshotDebounceInterval
 	}
 
 	// Replace the logger if an alternative is provided.
@@ -67,24 +158,66 @@ func NewTrailWithOptions(watcher Watcher, options *TrailOptions) *Trail {
 		defaults.Logger = options.Logger
 	}
 
+	// The active window replaces the ignore and unfollow durations.
+	if defaults.ActiveWindow > 0 {
+		defaults.FileIgnoreDuration = defaults.ActiveWindow
+		defaults.FileFollowDuration = defaults.ActiveWindow
+		defaults.DisableUnfollower = false
+	}
+
+	// Retry once by default, negative values disable retries.
+	if defaults.TailErrorRetries == 0 {
+		defaults.TailErrorRetries = 1
+	}
+
 	return &Trail{
-		watcher: watcher,
-		done:    make(chan bool),
-		options: defaults,
+		watcher:       watcher,
+		done:          make(chan bool),
+		options:       defaults,
+		latency:       NewHistogram(DefaultLatencyBuckets),
+		statErrors:    newErrorThrottle(statErrorWindow),
+		truncateCheck: truncateCheckInterval,
+		tailFile:      tail.TailFile,
+		stat:          os.Stat,
+		now:           time.Now,
 	}
 }
+
+// HandlerLatency returns the histogram of the time spent by the handler on
+// each followed line.
+func (t *Trail) HandlerLatency() *Histogram {
+	return t.latency
+}
+
+// LogHandlerLatency writes a summary of the handler latency histogram to the
+// trail's logger, labeled with the trail description.
+func (t *Trail) LogHandlerLatency() {
+	t.options.Logger.Infoln("Handler latency [" + t.options.Desc + "]: " + t.latency.String())
+}
+
 func task(t *Trail) {
 	t.options.Logger.Debugln("task running...")
 	t.unfollowOldFiles()
 }
 
+// AddUnfollower unfollows the files not written to for FileFollowDuration,
+// checking every 10 seconds. It blocks until the trail is ended, stopping its
+// scheduler then.
 func (t *Trail) AddUnfollower() {
+	if t.options.DisableUnfollower {
+		t.options.Logger.Infoln("Old File Unfollower disabled.")
+		return
+	}
+
 	t.options.Logger.Infoln("added Old File Unfollower.")
 	t.options.Logger.Infoln("File Follow Duration: " + t.options.FileFollowDuration.String())
 
 	s := gocron.NewScheduler()
 	s.Every(10).Seconds().Do(task, t)
-	<-s.Start()
+	stop := s.Start()
+
+	<-t.done
+	stop <- true
 }
 
 // Follow starts following a trail. Every time a file is changed, the affected
@@ -94,6 +227,10 @@ func (t *Trail) AddUnfollower() {
 func (t *Trail) Follow(handler LineHandler) error {
 	t.options.Logger.Infoln("Sauron is now watching")
 
+	t.mutex.Lock()
+	t.handler = handler
+	t.mutex.Unlock()
+
 	// First, we tail all the files that we already know.
 	files, err := t.watcher.Walk()
 
@@ -103,49 +240,87 @@ func (t *Trail) Follow(handler LineHandler) error {
 		return err
 	}
 
+	files = t.selectFiles(files)
+	sortFiles(files, t.options.InitialOrder)
+
+	t.mutex.Lock()
+	t.found = len(files)
+	t.mutex.Unlock()
+
+	// Existing files are read from their end, unless told otherwise.
+	fromStart := t.options.SeekStart == SeekFromStart
+
 	for _, file := range files {
-		if ignore(t, file) {
+		if t.options.Snapshot {
 			continue
 		}
 
-		t.followFile(file, handler, false)
+		t.followFile(file, handler, fromStart)
 	}
 
-	// Second, we watch for new files, and tail them too.
+	// Second, we watch for new files, and tail them too. The watcher starts
+	// before the events are consumed, so that ending the trail always finds
+	// it running, and is ended before the consumer exits, so that it never
+	// sends events nobody reads.
 	events := make(chan FileEvent)
 
+	if err := t.watcher.Watch(events); err != nil {
+		t.options.Logger.Errorln("Failed to watch for changes: " + err.Error())
+	}
+
+	ended := make(chan bool)
+	t.mutex.Lock()
+	t.ended = ended
+	t.mutex.Unlock()
+
 	go func() {
+		defer close(ended)
+
+		// Events of a path are held back for DebounceInterval, collapsing
+		// repeated operations, before they are acted on.
+		pending := make(map[string][]FileEvent)
+		flushes := make(chan string)
+		stopped := make(chan bool)
+		defer close(stopped)
+
 		for {
 			select {
 			case event := <-events:
-				if ignore(t, event.Path) {
+				if t.options.DebounceInterval <= 0 {
+					t.handleEvent(event, handler)
 					continue
 				}
 
-				switch event.Op {
-				case fsnotify.Create:
-					t.options.Logger.Debugln("Created: " + event.Path)
-					t.followFile(event.Path, handler, true)
-				case fsnotify.Remove:
-					t.options.Logger.Debugln("Removed: " + event.Path)
-					t.unfollowFile(event.Path)
-				case fsnotify.Rename:
-					t.options.Logger.Debugln("Renamed: " + event.Path)
-				case fsnotify.Write:
-					t.options.Logger.Debugln("Write: " + event.Path)
-				default:
-					t.options.Logger.Debugln(
-						"Event " + strconv.Itoa(int(event.Op)) + ": " + event.Path,
-					)
+				queued, ok := pending[event.Path]
+				if !ok {
+					path := event.Path
+					time.AfterFunc(t.options.DebounceInterval, func() {
+						select {
+						case flushes <- path:
+						case <-stopped:
+						}
+					})
+				}
+
+				if len(queued) == 0 || queued[len(queued)-1].Op != event.Op {
+					pending[event.Path] = append(queued, event)
+				}
+			case path := <-flushes:
+				for _, event := range pending[path] {
+					t.handleEvent(event, handler)
 				}
+				delete(pending, path)
 			case <-t.done:
 				// Stop the watcher
 				t.watcher.End()
 
-				// Stop any tailers
+				// Stop any tailers and pipes
+				t.mutex.Lock()
 				for _, current := range t.tails {
 					current.Stop()
 				}
+				t.closePipes(func(string) bool { return false })
+				t.mutex.Unlock()
 
 				// Exit the goroutine
 				return
@@ -153,114 +328,742 @@ func (t *Trail) Follow(handler LineHandler) error {
 		}
 	}()
 
-	t.watcher.Watch(events)
+	return nil
+}
+
+// handleEvent acts on a single filesystem event of a followed directory.
+// Created files are only acted on once NewFileGrace elapsed, and if they still
+// exist by then.
+func (t *Trail) handleEvent(event FileEvent, handler LineHandler) {
+	if event.Op == fsnotify.Create && t.options.NewFileGrace > 0 {
+		time.AfterFunc(t.options.NewFileGrace, func() {
+			select {
+			case <-t.done:
+				return
+			default:
+			}
+
+			if _, err := os.Stat(event.Path); os.IsNotExist(err) {
+				t.logFor(event.Path).Debugln("vanished during its grace period")
+				return
+			}
+
+			t.actOnEvent(event, handler)
+		})
+
+		return
+	}
+
+	t.actOnEvent(event, handler)
+}
+
+// actOnEvent follows, unfollows or snapshots a file for an event.
+func (t *Trail) actOnEvent(event FileEvent, handler LineHandler) {
+	if ignore(t, event.Path) {
+		return
+	}
+
+	if t.options.Snapshot {
+		if event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+			t.logFor(event.Path).Debugln("snapshot")
+			t.snapshotFile(event.Path, handler)
+		}
+
+		return
+	}
+
+	switch event.Op {
+	case fsnotify.Create:
+		t.logFor(event.Path).Debugln("created")
+		if t.options.LatestOnly {
+			t.followLatest(event.Path, handler)
+		} else {
+			if t.options.MaxFiles > 0 {
+				t.makeRoom(event.Path)
+			}
+			t.followFile(event.Path, handler, true)
+		}
+	case fsnotify.Remove:
+		t.logFor(event.Path).Debugln("removed")
+		if t.options.FollowAfterRemove > 0 {
+			t.drainFile(event.Path)
+		} else {
+			t.unfollowFile(event.Path)
+		}
+	case fsnotify.Rename:
+		t.logFor(event.Path).Debugln("renamed")
+	case fsnotify.Write:
+		t.logFor(event.Path).Debugln("write")
+
+		// A file still written to after its tail died is followed again
+		// from its current end, unless a trail follows it already.
+		if !followed.claimed(event.Path) && (!t.options.LatestOnly || t.isLatest(event.Path)) {
+			t.logFor(event.Path).Infoln("re-following")
+			if !t.options.LatestOnly && t.options.MaxFiles > 0 {
+				t.makeRoom(event.Path)
+			}
+			t.followFile(event.Path, handler, false)
+		}
+	default:
+		t.logFor(event.Path).WithField("op", event.Op.String()).Debugln("event")
+	}
+}
+
+// Once reads every file of the trail from the beginning, passing each line to
+// the handler, without following them for further changes. Ignore rules are
+// honored as usual. It returns once all the files have been read.
+func (t *Trail) Once(handler LineHandler) error {
+	files, err := t.watcher.Walk()
+
+	if err != nil {
+		t.options.Logger.Errorln("Failed to walk directory")
+
+		return err
+	}
+
+	files = t.selectFiles(files)
+	sortFiles(files, t.options.InitialOrder)
+
+	for _, file := range files {
+		if err := t.readFile(file, handler); err != nil {
+			t.options.Logger.Errorln("Failed to read " + file + ": " + err.Error())
+		}
+	}
 
 	return nil
 }
 
+// FollowSink starts following a trail, delivering every line to the provided
+// sink instead of a handler function. The sink is not closed when the trail
+// ends, since it may be shared between several trails.
+func (t *Trail) FollowSink(sink Sink) error {
+	return t.Follow(SinkHandler(sink))
+}
+
+// Lines starts following a trail, delivering every line on the returned
+// channel instead of passing it to a handler. The channel is closed when the
+// trail ends, or right away if the trail can't be followed. Reading files
+// waits for the lines to be received, so consumers must keep draining the
+// channel until it is closed.
+func (t *Trail) Lines() <-chan Line {
+	sink := newChannelSink(t.done)
+
+	go func() {
+		<-t.done
+		sink.Close()
+	}()
+
+	if err := t.FollowSink(sink); err != nil {
+		sink.Close()
+	}
+
+	return sink.lines
+}
+
+// isOldToIgnore reports whether a file was last modified before the cut-offs
+// of FileIgnoreDuration or FileModifiedAfter.
 func (t *Trail) isOldToIgnore(path string) bool {
 	var result bool
 	if info, err := os.Stat(path); err == nil {
-		result = time.Now().Sub(info.ModTime()) > t.options.FileIgnoreDuration
+		result = time.Now().Sub(info.ModTime()) > t.options.FileIgnoreDuration ||
+			(t.options.FileModifiedAfter != nil && info.ModTime().Before(t.options.FileModifiedAfter.Cutoff(time.Now())))
 	} else {
-		t.options.Logger.Errorln("failed to get file info. " + err.Error())
+		t.logStatError(path, err)
 		result = false
 	}
 	return result
 }
 
 func ignore(t *Trail, path string) bool {
-	return (t.options.PathReg != nil && !t.options.PathReg.MatchString(filepath.Dir(path))) ||
-		(t.options.FileReg != nil && !t.options.FileReg.MatchString(filepath.Base(path))) ||
-		(t.options.FileIgnoreReg != nil && t.options.FileIgnoreReg.MatchString(filepath.Base(path)) ||
-			t.isOldToIgnore(path))
+	return ignoreReason(t, path) != ""
 }
 
-// End stops watching.
+// End stops watching, returning once the watcher and the tails are stopped.
+// Ending a trail again does nothing.
 func (t *Trail) End() {
 	t.options.Logger.Infoln("Stopping...")
 
-	t.done <- true
+	t.endOnce.Do(func() { close(t.done) })
+
+	t.mutex.Lock()
+	ended := t.ended
+	t.mutex.Unlock()
+
+	if ended != nil {
+		<-ended
+	}
+}
+
+// logFor returns the logger of the trail with the fields identifying a file
+// and the watch it belongs to.
+func (t *Trail) logFor(path string) *logrus.Entry {
+	return t.options.Logger.WithFields(logrus.Fields{
+		"desc": t.options.Desc,
+		"path": path,
+	})
 }
 
 // followFile simply setups the appropriate options for the tail library and
 // starts tailing that file. It also repackages events as Line objects for the
 // handler function. The isNew parameter tells the function whether the file
 // was just created or it already existed when the trail started following.
+//
+// When the tail library reports an error, the file is re-opened at the last
+// known offset after a short delay, up to TailErrorRetries times, before it is
+// unfollowed for good.
+//
+// A file already followed by another trail is skipped, so that overlapping
+// watches don't emit its lines twice. Named pipes and gzip files are read as
+// streams instead of being tailed.
 func (t *Trail) followFile(path string, handler LineHandler, isNew bool) {
-	t.options.Logger.Debugln("Following: " + path)
+	if !followed.claim(path, t) {
+		t.logFor(path).Warnln("already followed by another watch, skipping")
+		return
+	}
+
+	t.logFor(path).Debugln("following")
+
+	if isPipe(path) {
+		t.followPipe(path, handler)
+		return
+	}
+
+	if t.isGzipStream(path) {
+		t.followGzip(path, handler, isNew)
+		return
+	}
 
 	if t.options.PollChanges {
-		t.options.Logger.Debugln("Polling enabled")
+		t.logFor(path).Debugln("polling enabled")
+	}
+
+	config := t.tailConfig(true)
+
+	// The location of new files is set as well, so that the tail library
+	// tells when it opened them.
+	if isNew {
+		config.Location = &tail.SeekInfo{Offset: 0, Whence: io.SeekStart}
+	} else {
+		config.Location = &tail.SeekInfo{Offset: 0, Whence: 2}
+
+		if location := t.resumeLocation(path); location != nil {
+			t.logFor(path).Debugln("resuming where it went stale")
+			config.Location = location
+		}
 	}
 
+	t.setOpening(path, true)
+
 	go func() {
-		var current *tail.Tail
-		var err error
+		defer followed.release(path, t)
 
-		if isNew {
-			current, err = tail.TailFile(path, tail.Config{
-				Follow: true,
-				Logger: tail.DiscardingLogger,
-				Poll:   t.options.PollChanges,
-			})
+		for retries := 0; ; retries++ {
+			offset := startOffset(path, config.Location)
+			opens := newTailLogger(path)
+			config.Logger = opens
+			current, err := t.tailFile(path, config)
 
 			if err != nil {
+				t.logFor(path).WithError(err).Errorln("failed to tail")
+				t.setOpening(path, false)
 				return
 			}
-		} else {
-			current, err = tail.TailFile(path, tail.Config{
-				Follow:   true,
-				Location: &tail.SeekInfo{Offset: 0, Whence: 2},
-				Logger:   tail.DiscardingLogger,
-				Poll:     t.options.PollChanges,
-			})
 
-			if err != nil {
+			t.addTail(current)
+
+			next, err := t.consume(path, current, offset, opens, handler)
+			if err == nil {
 				return
 			}
-		}
 
-		t.tails = append(t.tails, current)
+			if err == errTruncated {
+				t.logFor(path).Infoln("truncated, following it from the start")
+				t.removeTail(current)
+				config.Location = restartTruncated(current)
+				retries--
+				continue
+			}
+
+			t.logFor(path).WithError(err).Errorln("tail failed")
+
+			// The offset of the lines read tells where to go on from, and
+			// the end of the file when it is unknown, so that no line is
+			// delivered twice.
+			if next >= 0 {
+				config.Location = &tail.SeekInfo{Offset: next, Whence: io.SeekStart}
+			} else {
+				config.Location = &tail.SeekInfo{Offset: 0, Whence: io.SeekEnd}
+			}
+
+			t.removeTail(current)
+			current.Stop()
 
-		for line := range current.Lines {
-			newLine := Line{
-				Path: path,
-				Text: line.Text,
-				Time: line.Time,
-				Err:  line.Err,
+			if retries >= t.options.TailErrorRetries {
+				t.logFor(path).Errorln("giving up")
+				return
 			}
 
-			handler(newLine)
+			time.Sleep(tailRetryDelay)
+			t.logFor(path).Infoln("re-opening")
 		}
 	}()
 }
 
+// matchLine reports whether text matches lineReg. Every line matches a nil
+// regex.
+func matchLine(lineReg *regexp.Regexp, text string) bool {
+	return lineReg == nil || lineReg.MatchString(text)
+}
+
+// startOffset returns the offset in a file at which a tail configured with
+// location starts reading, or -1 when it can't be told.
+func startOffset(path string, location *tail.SeekInfo) int64 {
+	if location == nil {
+		return 0
+	}
+
+	switch location.Whence {
+	case io.SeekStart:
+		return location.Offset
+	case io.SeekEnd:
+		if info, err := os.Stat(path); err == nil {
+			return info.Size() + location.Offset
+		}
+	}
+
+	return -1
+}
+
+// lineOffsets counts the offsets of the lines a tail reads, from the position
+// it started reading at, next being that of the line to come. It is -1 when
+// unknown. The tail library strips the "\n" ending a line, but not a "\r"
+// before it, and splits lines longer than maxLineSize into parts sent at once,
+// only the last of which is followed by a "\n".
+type lineOffsets struct {
+	next        int64
+	maxLineSize int
+	previous    *tail.Line
+}
+
+// newLineOffsets creates the offsets of lines read from offset.
+func newLineOffsets(offset int64, maxLineSize int) *lineOffsets {
+	return &lineOffsets{next: offset, maxLineSize: maxLineSize}
+}
+
+// add returns the offset of a line read, counting the bytes it took.
+func (o *lineOffsets) add(line *tail.Line) int64 {
+	if o.next < 0 {
+		return -1
+	}
+
+	// The parts of a split line have the same time, all but the last being
+	// maxLineSize long.
+	if o.previous != nil && o.maxLineSize > 0 && len(o.previous.Text) == o.maxLineSize && o.previous.Time.Equal(line.Time) {
+		o.next--
+	}
+
+	offset := o.next
+	o.next += int64(len(line.Text)) + 1
+	o.previous = line
+
+	return offset
+}
+
+// reset counts the offsets of the lines to come from offset.
+func (o *lineOffsets) reset(offset int64) {
+	o.next = offset
+	o.previous = nil
+}
+
+// consume passes the lines of a tail to the handler until the tail stops, in
+// which case it returns nil, or fails, be it by killing the tail or sending a
+// line carrying an error, which is returned. Reaching the rate limit isn't an
+// error: the tail skips to the end of the file and goes on. Lines go through
+// a buffer first when HandlerBufferSize is set.
+//
+// The offset of every line is counted from offset, the position the tail
+// started reading at, and the offset of the line to come is returned. It is
+// -1 for every line when that position is unknown, and from the first time
+// the rate limit is reached on. Files re-opened by the tail library, as
+// reported through opens, are counted from their beginning again.
+//
+// Every line carries the Inode and Dev of the file it was read from, as
+// reported through opens when the tail library opens or re-opens it. They are
+// 0 until then, and when the file opened can't be told.
+//
+// With FollowAfterRemove, a descriptor is kept open on that file, through
+// which it is drained once removed, before consume returns nil.
+func (t *Trail) consume(path string, current *tail.Tail, offset int64, opens *tailLogger, handler LineHandler) (int64, error) {
+	defer opens.close()
+
+	handle := func(line Line) {
+		t.deliver(line, handler)
+	}
+
+	if t.options.HandlerBufferSize > 0 {
+		buffer := newLineBuffer(t, path, t.options.HandlerBufferSize, t.options.HandlerBufferPolicy, handle)
+		defer buffer.close()
+
+		handle = buffer.push
+	}
+
+	t.mutex.Lock()
+	reads := t.reads[current]
+	t.mutex.Unlock()
+
+	var inode, dev uint64
+	offsets := newLineOffsets(offset, t.options.MaxLineSize)
+	reads.setOffset(offsets.next)
+
+	emit := func(line *tail.Line) {
+		if reads != nil {
+			atomic.AddInt64(&reads.count, 1)
+			atomic.StoreInt64(&reads.last, line.Time.UnixNano())
+		}
+
+		handle(Line{
+			Path:    path,
+			Text:    line.Text,
+			Time:    line.Time,
+			Offset:  offsets.add(line),
+			Inode:   inode,
+			Dev:     dev,
+			Matched: matchLine(t.options.LineReg, line.Text),
+		})
+		reads.setOffset(offsets.next)
+	}
+
+	var kept *os.File
+	defer func() {
+		if kept != nil {
+			kept.Close()
+		}
+	}()
+
+	var removed <-chan bool
+	if reads != nil {
+		removed = reads.removed
+	}
+
+	var truncateCheck <-chan time.Time
+	if t.options.ReopenOnTruncate {
+		ticker := time.NewTicker(t.truncateCheck)
+		defer ticker.Stop()
+
+		truncateCheck = ticker.C
+	}
+
+	for {
+		var line *tail.Line
+		select {
+		case line = <-current.Lines:
+		case opened := <-opens.opened:
+			inode, dev = opened.inode, opened.dev
+			if opened.reopened {
+				t.logFor(path).Debugln("re-opened by the tail library")
+				offsets.reset(0)
+				reads.setOffset(offsets.next)
+			}
+			if t.options.FollowAfterRemove > 0 {
+				kept = keepOpen(path, kept, inode, dev)
+			}
+			continue
+		case <-removed:
+			removed = nil
+			if !isRemoved(path, inode, dev) {
+				t.logFor(path).Debugln("created again, still following")
+				continue
+			}
+			t.drainRemoved(path, current, kept, offsets.next, emit)
+			return offsets.next, nil
+		case <-truncateCheck:
+			if isTruncated(path, offsets.next, inode, dev) {
+				return offsets.next, errTruncated
+			}
+			continue
+		}
+
+		if line == nil {
+			// The tail library closes the lines of a tail it kills on
+			// failure, telling why through Wait, and of a tail whose
+			// file it noticed the removal of, which is drained then.
+			if kept != nil && t.isFollowing(current) && isRemoved(path, inode, dev) {
+				t.drainRemoved(path, current, kept, offsets.next, emit)
+				return offsets.next, nil
+			}
+			return offsets.next, current.Wait()
+		}
+
+		if isCooloff(line) {
+			t.logFor(path).Warnln("rate limit reached, dropping lines")
+			offsets.reset(-1)
+			reads.setOffset(offsets.next)
+			continue
+		}
+
+		if line.Err != nil {
+			return offsets.next, line.Err
+		}
+
+		emit(line)
+	}
+}
+
+// deliver passes a line to the handler, timing it and logging its failure.
+func (t *Trail) deliver(line Line, handler LineHandler) {
+	start := time.Now()
+	if err := handler(line); err != nil {
+		t.logFor(line.Path).WithError(err).Errorln("handler failed")
+	}
+	t.latency.Observe(time.Since(start))
+}
+
+// FollowedFiles returns a snapshot of the files and pipes currently being
+// followed.
+func (t *Trail) FollowedFiles() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	files := make([]string, 0, len(t.tails)+len(t.pipes))
+	for _, current := range t.tails {
+		files = append(files, current.Filename)
+	}
+	for path := range t.pipes {
+		files = append(files, path)
+	}
+
+	return files
+}
+
+// addTail registers a running tail, whose file is then no longer opening.
+func (t *Trail) addTail(current *tail.Tail) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.tails = append(t.tails, current)
+	delete(t.opening, current.Filename)
+
+	if t.reads == nil {
+		t.reads = make(map[*tail.Tail]*tailReads)
+	}
+	t.reads[current] = &tailReads{offset: -1, removed: make(chan bool)}
+}
+
+// setOpening records whether the first tail of a file is being opened, so that
+// the file counts as followed before its tail is registered.
+func (t *Trail) setOpening(path string, opening bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !opening {
+		delete(t.opening, path)
+		return
+	}
+
+	if t.opening == nil {
+		t.opening = make(map[string]bool)
+	}
+	t.opening[path] = true
+}
+
+// tailReads counts the lines read by a tail, and records when it read the
+// last one, in nanoseconds since the epoch, zero until then, and the offset of
+// the line it reads next, -1 when unknown. All are updated atomically. Removed
+// is closed once the file of the tail is removed.
+type tailReads struct {
+	count    int64
+	last     int64
+	offset   int64
+	removed  chan bool
+	removing sync.Once
+}
+
+// setOffset records the offset of the line a tail reads next.
+func (r *tailReads) setOffset(offset int64) {
+	if r != nil {
+		atomic.StoreInt64(&r.offset, offset)
+	}
+}
+
+// readSince reports whether the tail of a file read a line within d, in which
+// case the file was modified since as well.
+func (t *Trail) readSince(current *tail.Tail, d time.Duration) bool {
+	reads := t.reads[current]
+	if reads == nil {
+		return false
+	}
+
+	last := atomic.LoadInt64(&reads.last)
+
+	return last != 0 && t.now().Sub(time.Unix(0, last)) <= d
+}
+
+// removeTail unregisters a tail, without stopping it.
+func (t *Trail) removeTail(current *tail.Tail) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.reads, current)
+
+	for i, registered := range t.tails {
+		if registered == current {
+			t.tails = append(t.tails[:i], t.tails[i+1:]...)
+			return
+		}
+	}
+}
+
+// snapshotFile passes the whole content of a file to the handler, as a single
+// line.
+func (t *Trail) snapshotFile(path string, handler LineHandler) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.options.Logger.Errorln("Failed to read " + path + ": " + err.Error())
+		return
+	}
+
+	line := Line{
+		Path:    path,
+		Text:    string(content),
+		Time:    time.Now(),
+		Offset:  0,
+		Matched: matchLine(t.options.LineReg, string(content)),
+	}
+
+	if err := handler(line); err != nil {
+		t.options.Logger.Errorln("Handler failed for " + path + ": " + err.Error())
+	}
+}
+
+// tailConfig returns the config of the tails reading files, following them
+// or reading them once.
+func (t *Trail) tailConfig(follow bool) tail.Config {
+	config := tail.Config{
+		Follow:      follow,
+		Logger:      tail.DiscardingLogger,
+		MaxLineSize: t.options.MaxLineSize,
+	}
+
+	if follow {
+		config.ReOpen = t.options.ReOpen
+		config.Poll = t.options.PollChanges
+	}
+
+	if t.options.RateLimitSize > 0 && t.options.RateLimitInterval > 0 {
+		config.RateLimiter = ratelimiter.NewLeakyBucket(t.options.RateLimitSize, t.options.RateLimitInterval)
+	}
+
+	return config
+}
+
+// cooloffMessage starts the text of the line the tail library sends, with an
+// error, when the rate limit of a file is reached.
+const cooloffMessage = "Too much log activity"
+
+// isCooloff reports whether a line only tells that the rate limit of its file
+// was reached, in which case the tail goes on from the end of the file after a
+// second rather than failing.
+func isCooloff(line *tail.Line) bool {
+	return line.Err != nil && strings.HasPrefix(line.Err.Error(), cooloffMessage)
+}
+
+// readFile passes every line of a file to the handler, stopping at the end of
+// the file.
+func (t *Trail) readFile(path string, handler LineHandler) error {
+	t.options.Logger.Debugln("Reading: " + path)
+
+	current, err := t.tailFile(path, t.tailConfig(false))
+
+	if err != nil {
+		return err
+	}
+
+	offsets := newLineOffsets(0, t.options.MaxLineSize)
+	for line := range current.Lines {
+		if isCooloff(line) {
+			t.logFor(path).Warnln("rate limit reached, dropping lines")
+			offsets.reset(-1)
+			continue
+		}
+
+		newLine := Line{
+			Path:    path,
+			Text:    line.Text,
+			Time:    line.Time,
+			Offset:  offsets.add(line),
+			Matched: matchLine(t.options.LineReg, line.Text),
+			Err:     line.Err,
+		}
+
+		if err := handler(newLine); err != nil {
+			t.options.Logger.Errorln("Handler failed for " + path + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// unfollowFile stops the tails of a file.
 func (t *Trail) unfollowFile(name string) error {
-	for i, tail := range t.tails {
-		if tail.Filename == name {
-			tail.Stop()
-			t.tails = append(t.tails[:i], t.tails[i:]...)
+	t.logFor(name).Debugln("unfollowing")
+
+	t.mutex.Lock()
+	var stopped []*tail.Tail
+	kept := t.tails[:0]
+	for _, current := range t.tails {
+		if current.Filename == name {
+			stopped = append(stopped, current)
+			delete(t.reads, current)
+		} else {
+			kept = append(kept, current)
 		}
 	}
+	t.tails = kept
+	delete(t.stale, name)
+	t.closePipes(func(path string) bool { return path != name })
+	t.mutex.Unlock()
+
+	for _, current := range stopped {
+		current.Stop()
+	}
 	return nil
 }
 
 func (t *Trail) isOlderThanADay(tm time.Time) bool {
-	return time.Now().Sub(tm) > t.options.FileFollowDuration
+	return t.now().Sub(tm) > t.options.FileFollowDuration
 	//d, _ := time.ParseDuration("1m")
 	//return time.Now().Sub(tm) > d
 }
 
+// unfollowOldFiles unfollows the files not modified for FileFollowDuration.
+// Files whose tail read a line within it are known to be recent without
+// checking, the others are stat-ed.
 func (t *Trail) unfollowOldFiles() error {
+	if t.options.DisableUnfollower {
+		return nil
+	}
+
 	t.options.Logger.Debugln("starting...unfollow old files")
 
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
 	i := 0
 	for i < len(t.tails) {
-		if info, err := os.Stat(t.tails[i].Filename); err == nil {
+		if t.readSince(t.tails[i], t.options.FileFollowDuration) {
+			t.options.Logger.Debugln("follow: " + filepath.Base(t.tails[i].Filename))
+			i++
+			continue
+		}
+
+		if info, err := t.stat(t.tails[i].Filename); err == nil {
 			if t.isOlderThanADay(info.ModTime()) {
 				t.options.Logger.Debugln("unfollow: " + info.Name())
+				if t.options.ActiveWindow > 0 {
+					t.markStale(t.tails[i])
+				}
 				t.tails[i].Stop()
+				delete(t.reads, t.tails[i])
 				copy(t.tails[i:], t.tails[i+1:])
 				t.tails[len(t.tails)-1] = nil // or the zero value of T
 				t.tails = t.tails[:len(t.tails)-1]
@@ -270,10 +1073,30 @@ func (t *Trail) unfollowOldFiles() error {
 			}
 
 		} else {
-			t.options.Logger.Errorln("failed to get file info. " + err.Error())
+			t.logStatError(t.tails[i].Filename, err)
+			i++
 		}
 	}
 
+	t.closePipes(func(path string) bool {
+		if !t.isGzipStream(path) {
+			return true
+		}
+
+		info, err := t.stat(path)
+		if err != nil {
+			t.logStatError(path, err)
+			return true
+		}
+
+		if t.isOlderThanADay(info.ModTime()) {
+			t.options.Logger.Debugln("unfollow: " + info.Name())
+			return false
+		}
+
+		return true
+	})
+
 	t.options.Logger.Debugln("unfollow completed. ")
 	for _, tail := range t.tails {
 		t.options.Logger.Debugln("following: " + tail.Filename)
@@ -30,9 +30,134 @@ type TrailOptions struct {
 	// Ignore If File Mod time is order than duration.
 	FileIgnoreDuration time.Duration
 
+	// Ignore files modified before a cut-off such as today. Both this and
+	// FileIgnoreDuration apply, so the most recent of their cut-offs wins.
+	FileModifiedAfter *ModifiedAfter
+
 	// Unfollow If File Mod time is order than duration.
 	FileFollowDuration time.Duration
 
-	// Path Regex to follow.
+	// Ignore files smaller than MinFileSize or larger than MaxFileSize bytes.
+	// Zero disables either bound. Created files start empty, so with a
+	// minimum they are followed from their end once they grow past it.
+	MinFileSize int64
+	MaxFileSize int64
+
+	// ActiveWindow follows only the files modified within this long: files
+	// older than it are ignored, followed ones are unfollowed once they get
+	// older, and stale files are followed again on their next write, from
+	// where they were left. It overrides FileIgnoreDuration,
+	// FileFollowDuration and DisableUnfollower. Zero disables it.
+	ActiveWindow time.Duration
+
+	// DisableUnfollower keeps following files however old they get, until
+	// they are removed, instead of unfollowing them after FileFollowDuration.
+	DisableUnfollower bool
+
+	// Path Regex to follow. Paths are matched both with the separators of the
+	// platform and with forward slashes, as are those of the options below.
 	PathReg *regexp.Regexp
+
+	// Path Regex to ignore. Takes precedence over PathReg.
+	PathIgnoreReg *regexp.Regexp
+
+	// Regex the whole absolute path of a file must match to be followed.
+	FullPathReg *regexp.Regexp
+
+	// SkipBinary dictates whether files that look like binary content should
+	// be ignored instead of followed.
+	SkipBinary bool
+
+	// GzipStreamReg names the files followed as gzip streams, decoding the
+	// members appended to them, instead of tailed as text. Matching files are
+	// decoded from their beginning when created, so rotated archives such as
+	// app.log.1.gz are best left out. If nil then none are.
+	GzipStreamReg *regexp.Regexp
+
+	// ReOpen dictates whether followed files should be reopened when they are
+	// recreated, as it happens when a log file is rotated.
+	ReOpen bool
+
+	// ReopenOnTruncate checks followed files every second for having been
+	// truncated in place, shorter than where their tail got, and follows them
+	// again from their beginning when they were. Otherwise their new lines
+	// are missed until they grow past where the tail stopped. Files the tail
+	// library already re-opened after noticing the truncation itself are
+	// left alone, so that their lines aren't delivered twice.
+	ReopenOnTruncate bool
+
+	// FollowAfterRemove keeps reading removed files until nothing is written
+	// to them for this long, delivering the lines still written through
+	// descriptors left open on them. They are read through a descriptor kept
+	// open on the file each tail reads. Zero unfollows them right away.
+	FollowAfterRemove time.Duration
+
+	// TailErrorRetries is how many times a file is re-opened after its tail
+	// reports an error, before it is unfollowed. Defaults to one when zero,
+	// negative values unfollow the file on the first error.
+	TailErrorRetries int
+
+	// HandlerBufferSize is the number of lines of each file that may wait for
+	// the handler, so that a slow handler does not stall reading. Zero
+	// disables buffering and calls the handler as lines are read.
+	HandlerBufferSize int
+
+	// HandlerBufferPolicy is what happens when the handler buffer is full:
+	// BufferBlock (the default) or BufferDropOldest.
+	HandlerBufferPolicy string
+
+	// DebounceInterval is how long filesystem events of a path are collected
+	// before being acted on, so that bursts of identical events collapse into
+	// one. It does not delay the delivery of lines. Zero disables debouncing.
+	DebounceInterval time.Duration
+
+	// NewFileGrace is how long created files are left alone before being
+	// followed, so that files renamed or removed right away, as with atomic
+	// writes, are never tailed. Zero follows them right away.
+	NewFileGrace time.Duration
+
+	// Snapshot delivers the whole content of a file as a single line every
+	// time it is created or written to, instead of following new lines. It is
+	// meant for state files rewritten entirely. Events are debounced for
+	// 100ms unless DebounceInterval says otherwise.
+	Snapshot bool
+
+	// MaxLineSize splits lines longer than this many bytes into several
+	// lines. Zero leaves lines whole.
+	MaxLineSize int
+
+	// RateLimitSize and RateLimitInterval limit how fast lines are read from
+	// each file: a burst of RateLimitSize lines is allowed, and one more every
+	// RateLimitInterval. Once the limit is reached, the tail library waits for
+	// a second and goes on from the end of the file, so that the lines left
+	// unread by then are dropped. Either being zero disables the limit.
+	RateLimitSize     uint16
+	RateLimitInterval time.Duration
+
+	// LatestOnly follows only the most recently modified of the files which
+	// aren't ignored, switching to new files as they are created, as when an
+	// application writes to a new dated file every day.
+	LatestOnly bool
+
+	// MaxFiles bounds how many files are followed at once, zero for no bound.
+	// Only the most recently modified of the files found at startup are
+	// followed, and files created afterwards take the place of the least
+	// recently modified ones, as do files written to again after being
+	// skipped or unfollowed.
+	MaxFiles int
+
+	// SeekStart is where the files found at startup are followed from:
+	// SeekFromEnd (the default) or SeekFromStart. Files created afterwards
+	// are always followed from their beginning.
+	SeekStart string
+
+	// InitialOrder is the order in which the files found at startup are
+	// followed or read: OrderPath (the default) or OrderModTime.
+	InitialOrder string
+
+	// Regex of the lines to flag as matched. It doesn't filter lines out.
+	LineReg *regexp.Regexp
+
+	// Description of the trail, used to label its logs and metrics.
+	Desc string
 }
@@ -1,12 +1,24 @@
 package eye
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"errors"
+	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/Sirupsen/logrus"
+	logtest "github.com/Sirupsen/logrus/hooks/test"
+	"github.com/hpcloud/tail"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gopkg.in/fsnotify.v1"
@@ -96,3 +108,1832 @@ func TestFollowWalkError(t *testing.T) {
 
 	watcher.AssertExpectations(t)
 }
+
+func TestFollowRecordsHandlerLatency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "slow.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("slow line\n"), 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+	})
+
+	trail.Follow(func(line Line) error {
+		time.Sleep(20 * time.Millisecond)
+
+		return nil
+	})
+
+	watcher.TestData()["watchChannel"].(chan FileEvent) <- FileEvent{
+		Name: "slow.log",
+		Path: path,
+		Time: time.Now(),
+		Op:   fsnotify.Create,
+	}
+
+	assert.Eventually(t, func() bool {
+		return trail.HandlerLatency().Count() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.True(t, trail.HandlerLatency().Sum() >= 20*time.Millisecond)
+	assert.Equal(t, uint64(0), trail.HandlerLatency().Bucket(10*time.Millisecond))
+
+	trail.End()
+}
+
+func TestOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	first := filepath.Join(dir, "first.log")
+	assert.Nil(t, ioutil.WriteFile(first, []byte("one\ntwo\n"), 0644))
+
+	second := filepath.Join(dir, "second.log")
+	assert.Nil(t, ioutil.WriteFile(second, []byte("three\nfour"), 0644))
+
+	ignored := filepath.Join(dir, "ignored.txt")
+	assert.Nil(t, ioutil.WriteFile(ignored, []byte("five\n"), 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{first, second, ignored}, nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileReg:            regexp.MustCompile(`\.log$`),
+		FileIgnoreDuration: time.Hour,
+	})
+
+	var lines []string
+	err = trail.Once(func(line Line) error {
+		lines = append(lines, line.Text)
+
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"one", "two", "three", "four"}, lines)
+
+	watcher.AssertExpectations(t)
+}
+
+// fakeTail creates a tail whose lines are provided by the test through the
+// returned channel. Stopping it closes the channel.
+func fakeTail(path string) (*tail.Tail, chan *tail.Line) {
+	lines := make(chan *tail.Line)
+	current := &tail.Tail{Filename: path, Lines: lines}
+
+	go func() {
+		<-current.Dying()
+		close(lines)
+		current.Done()
+	}()
+
+	return current, lines
+}
+
+func TestFollowFileRetriesOnTailError(t *testing.T) {
+	tailRetryDelay = time.Millisecond
+	defer func() { tailRetryDelay = time.Second }()
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{TailErrorRetries: 1})
+
+	opened := make(chan chan *tail.Line, 4)
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, lines := fakeTail(filename)
+		opened <- lines
+
+		return current, nil
+	}
+
+	received := make(chan string, 4)
+	trail.followFile("/var/log/app.log", func(line Line) error {
+		received <- line.Text
+
+		return nil
+	}, true)
+
+	lines := <-opened
+	lines <- &tail.Line{Text: "before"}
+	assert.Equal(t, "before", <-received)
+
+	// The first error re-opens the file.
+	lines <- &tail.Line{Err: errors.New("permission denied")}
+	lines = <-opened
+	lines <- &tail.Line{Text: "after"}
+	assert.Equal(t, "after", <-received)
+	assert.Equal(t, 1, len(trail.tails))
+
+	// The second error exhausts the retries and unfollows the file.
+	lines <- &tail.Line{Err: errors.New("permission denied")}
+	assert.Eventually(t, func() bool {
+		trail.mutex.Lock()
+		defer trail.mutex.Unlock()
+
+		return len(trail.tails) == 0
+	}, time.Second, time.Millisecond)
+
+	select {
+	case <-opened:
+		t.Fatal("file was re-opened after exhausting the retries")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFollowFileRetriesFromEndWhenOffsetUnknown(t *testing.T) {
+	tailRetryDelay = time.Millisecond
+	defer func() { tailRetryDelay = time.Second }()
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{TailErrorRetries: 1})
+
+	type opening struct {
+		lines  chan *tail.Line
+		config tail.Config
+	}
+	opened := make(chan opening, 4)
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, lines := fakeTail(filename)
+		opened <- opening{lines, config}
+
+		return current, nil
+	}
+
+	trail.followFile("/var/log/app.log", func(line Line) error {
+		return nil
+	}, true)
+
+	// Reaching the rate limit loses track of the offset.
+	first := <-opened
+	first.lines <- &tail.Line{Text: "before"}
+	first.lines <- &tail.Line{Text: cooloffMessage, Err: errors.New(cooloffMessage)}
+	first.lines <- &tail.Line{Err: errors.New("permission denied")}
+
+	select {
+	case second := <-opened:
+		assert.Equal(t, &tail.SeekInfo{Offset: 0, Whence: io.SeekEnd}, second.config.Location)
+		second.lines <- &tail.Line{Text: "after"}
+	case <-time.After(time.Second):
+		t.Fatal("file was not re-opened after its tail failed")
+	}
+
+	trail.unfollowFile("/var/log/app.log")
+	assert.Eventually(t, func() bool {
+		return !followed.claimedBy("/var/log/app.log", trail)
+	}, time.Second, time.Millisecond)
+}
+
+func TestFollowFileRetriesOnTailKilled(t *testing.T) {
+	tailRetryDelay = time.Millisecond
+	defer func() { tailRetryDelay = time.Second }()
+
+	logger, hook := logtest.NewNullLogger()
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{Logger: logger, TailErrorRetries: 1})
+
+	type opening struct {
+		current *tail.Tail
+		lines   chan *tail.Line
+		config  tail.Config
+	}
+	opened := make(chan opening, 4)
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, lines := fakeTail(filename)
+		opened <- opening{current, lines, config}
+
+		return current, nil
+	}
+
+	received := make(chan string, 4)
+	trail.followFile("/var/log/app.log", func(line Line) error {
+		received <- line.Text
+
+		return nil
+	}, true)
+
+	first := <-opened
+	first.lines <- &tail.Line{Text: "before"}
+	assert.Equal(t, "before", <-received)
+
+	// The tail library kills the tail, closing its lines, when reading fails.
+	first.current.Killf("Error reading %s: %s", "/var/log/app.log", "input/output error")
+
+	select {
+	case second := <-opened:
+		assert.Equal(t, &tail.SeekInfo{Offset: 7, Whence: 0}, second.config.Location)
+		second.lines <- &tail.Line{Text: "after"}
+		assert.Equal(t, "after", <-received)
+	case <-time.After(time.Second):
+		t.Fatal("file was not re-opened after its tail was killed")
+	}
+
+	var failed bool
+	for _, entry := range hook.AllEntries() {
+		failed = failed || entry.Message == "tail failed"
+	}
+	assert.True(t, failed)
+
+	trail.unfollowFile("/var/log/app.log")
+	assert.Eventually(t, func() bool {
+		return !followed.claimedBy("/var/log/app.log", trail)
+	}, time.Second, time.Millisecond)
+}
+
+func TestFollowFileNoRetries(t *testing.T) {
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{TailErrorRetries: -1})
+
+	opened := make(chan chan *tail.Line, 4)
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, lines := fakeTail(filename)
+		opened <- lines
+
+		return current, nil
+	}
+
+	trail.followFile("/var/log/app.log", func(line Line) error {
+		return nil
+	}, true)
+
+	lines := <-opened
+	lines <- &tail.Line{Err: errors.New("permission denied")}
+
+	select {
+	case <-opened:
+		t.Fatal("file was re-opened with retries disabled")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFollowDebounce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "busy.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	logger, hook := logtest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		Logger:             logger,
+		FileIgnoreDuration: time.Hour,
+		DebounceInterval:   50 * time.Millisecond,
+	})
+
+	trail.Follow(func(line Line) error {
+		return nil
+	})
+
+	events := watcher.TestData()["watchChannel"].(chan FileEvent)
+	for i := 0; i < 10; i++ {
+		events <- FileEvent{Name: "busy.log", Path: path, Time: time.Now(), Op: fsnotify.Write}
+	}
+
+	countWrites := func() int {
+		writes := 0
+		for _, entry := range hook.AllEntries() {
+			if entry.Message == "write" && entry.Data["path"] == path {
+				writes++
+			}
+		}
+
+		return writes
+	}
+
+	assert.Eventually(t, func() bool {
+		return countWrites() == 1
+	}, time.Second, 5*time.Millisecond)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, countWrites())
+
+	trail.End()
+}
+
+func TestIgnorePathPatterns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	current := filepath.Join(dir, "log", "app.log")
+	archived := filepath.Join(dir, "log", "archive", "app.log")
+	other := filepath.Join(dir, "tmp", "app.log")
+	for _, path := range []string{current, archived, other} {
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+	}
+
+	includeOnly := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		PathReg:            regexp.MustCompile("/log"),
+	})
+	assert.False(t, ignore(includeOnly, current))
+	assert.False(t, ignore(includeOnly, archived))
+	assert.True(t, ignore(includeOnly, other))
+
+	excludeOnly := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		PathIgnoreReg:      regexp.MustCompile("/archive$"),
+	})
+	assert.False(t, ignore(excludeOnly, current))
+	assert.True(t, ignore(excludeOnly, archived))
+	assert.False(t, ignore(excludeOnly, other))
+
+	both := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		PathReg:            regexp.MustCompile("/log"),
+		PathIgnoreReg:      regexp.MustCompile("/archive$"),
+	})
+	assert.False(t, ignore(both, current))
+	assert.True(t, ignore(both, archived))
+	assert.True(t, ignore(both, other))
+}
+
+func TestFollowOverlappingTrails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "shared.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("once\n"), 0644))
+
+	lines := make(chan string, 4)
+	handler := func(line Line) error {
+		lines <- line.Text
+
+		return nil
+	}
+
+	var trails []*Trail
+	for i := 0; i < 2; i++ {
+		watcher := MockedWatcher{}
+		watcher.On("Walk").Return([]string{}, nil)
+		watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+		trail := NewTrailWithOptions(&watcher, &TrailOptions{
+			FileIgnoreDuration: time.Hour,
+		})
+		trail.Follow(handler)
+		trails = append(trails, trail)
+
+		watcher.TestData()["watchChannel"].(chan FileEvent) <- FileEvent{
+			Name: "shared.log",
+			Path: path,
+			Time: time.Now(),
+			Op:   fsnotify.Create,
+		}
+	}
+
+	assert.Equal(t, "once", <-lines)
+
+	select {
+	case line := <-lines:
+		t.Fatal("line emitted twice: " + line)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	for _, trail := range trails {
+		trail.End()
+	}
+
+	// Ending the trails releases the path.
+	assert.Eventually(t, func() bool {
+		followed.mutex.Lock()
+		defer followed.mutex.Unlock()
+
+		_, ok := followed.claims[path]
+
+		return !ok
+	}, time.Second, time.Millisecond)
+}
+
+func TestFollowedFiles(t *testing.T) {
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{})
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, _ := fakeTail(filename)
+
+		return current, nil
+	}
+
+	assert.Equal(t, []string{}, trail.FollowedFiles())
+
+	trail.followFile("/var/log/first.log", func(line Line) error { return nil }, true)
+	trail.followFile("/var/log/second.log", func(line Line) error { return nil }, true)
+
+	assert.Eventually(t, func() bool {
+		return len(trail.FollowedFiles()) == 2
+	}, time.Second, time.Millisecond)
+	assert.ElementsMatch(t, []string{"/var/log/first.log", "/var/log/second.log"}, trail.FollowedFiles())
+
+	trail.unfollowFile("/var/log/first.log")
+
+	assert.Equal(t, []string{"/var/log/second.log"}, trail.FollowedFiles())
+
+	trail.unfollowFile("/var/log/second.log")
+}
+
+func TestIgnoreFullPathPattern(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	api := filepath.Join(dir, "api", "access.log")
+	apiError := filepath.Join(dir, "api", "error.log")
+	web := filepath.Join(dir, "web", "access.log")
+	for _, path := range []string{api, apiError, web} {
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+	}
+
+	// Separate base and directory patterns can't tell api/access.log apart
+	// from the combinations of its parts.
+	separate := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		FileReg:            regexp.MustCompile(`^access\.log$`),
+		PathReg:            regexp.MustCompile(`/(api|web)$`),
+	})
+	assert.False(t, ignore(separate, api))
+	assert.True(t, ignore(separate, apiError))
+	assert.False(t, ignore(separate, web))
+
+	full := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		FullPathReg:        regexp.MustCompile(`/api/access\.log$`),
+	})
+	assert.False(t, ignore(full, api))
+	assert.True(t, ignore(full, apiError))
+	assert.True(t, ignore(full, web))
+}
+
+func TestFollowFileOffsets(t *testing.T) {
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{})
+
+	opened := make(chan chan *tail.Line, 1)
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, lines := fakeTail(filename)
+		opened <- lines
+
+		return current, nil
+	}
+
+	offsets := make(chan int64, 3)
+	trail.followFile("/var/log/offsets.log", func(line Line) error {
+		offsets <- line.Offset
+
+		return nil
+	}, true)
+
+	lines := <-opened
+	for _, text := range []string{"first", "", "third"} {
+		lines <- &tail.Line{Text: text}
+	}
+
+	assert.Equal(t, int64(0), <-offsets)
+	assert.Equal(t, int64(6), <-offsets)
+	assert.Equal(t, int64(7), <-offsets)
+
+	trail.unfollowFile("/var/log/offsets.log")
+}
+
+func TestFollowFileOffsetsCRLF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("first\r\nsecond\r\nthird\r\n"), 0644))
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{})
+
+	var offsets []int64
+	assert.Nil(t, trail.readFile(path, func(line Line) error {
+		offsets = append(offsets, line.Offset)
+
+		return nil
+	}))
+
+	assert.Equal(t, []int64{0, 7, 15}, offsets)
+}
+
+func TestFollowFileOffsetsAfterReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("first\n"), 0644))
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{ReOpen: true})
+
+	lines := make(chan Line, 2)
+	trail.followFile(path, func(line Line) error {
+		lines <- line
+
+		return nil
+	}, true)
+	defer trail.unfollowFile(path)
+
+	receive := func() Line {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("line not delivered")
+			return Line{}
+		}
+	}
+
+	line := receive()
+	assert.Equal(t, "first", line.Text)
+	assert.Equal(t, int64(0), line.Offset)
+
+	// The tail library only notices the rotation once it watches the file,
+	// which it starts doing at its end.
+	time.Sleep(100 * time.Millisecond)
+
+	// The file rotated in is re-opened by the tail library, and its lines
+	// are counted from its beginning.
+	assert.Nil(t, os.Rename(path, path+".1"))
+	assert.Nil(t, ioutil.WriteFile(path, []byte("second\n"), 0644))
+
+	line = receive()
+	assert.Equal(t, "second", line.Text)
+	assert.Equal(t, int64(0), line.Offset)
+}
+
+func TestLineOffsets(t *testing.T) {
+	offsets := newLineOffsets(10, 4)
+	now := time.Now()
+
+	// A line split at MaxLineSize is only followed by a single "\n".
+	assert.Equal(t, int64(10), offsets.add(&tail.Line{Text: "abcd", Time: now}))
+	assert.Equal(t, int64(14), offsets.add(&tail.Line{Text: "efgh", Time: now}))
+	assert.Equal(t, int64(18), offsets.add(&tail.Line{Text: "ij", Time: now}))
+	assert.Equal(t, int64(21), offsets.add(&tail.Line{Text: "abcd", Time: now.Add(time.Second)}))
+	assert.Equal(t, int64(26), offsets.add(&tail.Line{Text: "k", Time: now.Add(2 * time.Second)}))
+
+	offsets.reset(-1)
+	assert.Equal(t, int64(-1), offsets.add(&tail.Line{Text: "lost"}))
+	assert.Equal(t, int64(-1), offsets.next)
+}
+
+func TestStartOffset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "start.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("0123456789"), 0644))
+
+	assert.Equal(t, int64(0), startOffset(path, nil))
+	assert.Equal(t, int64(4), startOffset(path, &tail.SeekInfo{Offset: 4, Whence: 0}))
+	assert.Equal(t, int64(10), startOffset(path, &tail.SeekInfo{Offset: 0, Whence: 2}))
+	assert.Equal(t, int64(-1), startOffset(filepath.Join(dir, "missing.log"), &tail.SeekInfo{Offset: 0, Whence: 2}))
+}
+
+func TestFollowWriteRefollowsDeadTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "flaky.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{path}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		TailErrorRetries:   -1,
+	})
+
+	opened := make(chan chan *tail.Line, 2)
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, lines := fakeTail(filename)
+		opened <- lines
+
+		return current, nil
+	}
+
+	trail.Follow(func(line Line) error {
+		return nil
+	})
+
+	// Kill the tail.
+	lines := <-opened
+	lines <- &tail.Line{Err: errors.New("input/output error")}
+	assert.Eventually(t, func() bool {
+		return !followed.claimedBy(path, trail)
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{}, trail.FollowedFiles())
+
+	watcher.TestData()["watchChannel"].(chan FileEvent) <- FileEvent{
+		Name: "flaky.log",
+		Path: path,
+		Time: time.Now(),
+		Op:   fsnotify.Write,
+	}
+
+	select {
+	case <-opened:
+	case <-time.After(time.Second):
+		t.Fatal("file was not followed again")
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(trail.FollowedFiles()) == 1
+	}, time.Second, time.Millisecond)
+
+	trail.End()
+}
+
+func TestFollowWriteLeavesFileOfOtherTrail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "shared.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+
+	owner := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{})
+	owner.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, _ := fakeTail(filename)
+
+		return current, nil
+	}
+	owner.followFile(path, func(line Line) error { return nil }, true)
+	defer owner.unfollowFile(path)
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	logger, hook := logtest.NewNullLogger()
+	logger.Level = logrus.DebugLevel
+	other := NewTrailWithOptions(&watcher, &TrailOptions{
+		Logger:             logger,
+		FileIgnoreDuration: time.Hour,
+	})
+	other.Follow(func(line Line) error { return nil })
+	defer other.End()
+
+	for i := 0; i < 3; i++ {
+		watcher.TestData()["watchChannel"].(chan FileEvent) <- FileEvent{
+			Name: "shared.log",
+			Path: path,
+			Time: time.Now(),
+			Op:   fsnotify.Write,
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	for _, entry := range hook.AllEntries() {
+		assert.NotEqual(t, "re-following", entry.Message)
+		assert.NotContains(t, entry.Message, "already followed")
+	}
+	assert.Equal(t, []string{}, other.FollowedFiles())
+}
+
+func TestOnceFlagsMatchedLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("ERROR one\nINFO two\nERROR three\n"), 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{path}, nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		LineReg:            regexp.MustCompile("^ERROR"),
+	})
+
+	var matched []bool
+	err = trail.Once(func(line Line) error {
+		matched = append(matched, line.Matched)
+
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{true, false, true}, matched)
+}
+
+func TestFollowFileFlagsMatchedLines(t *testing.T) {
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{})
+
+	opened := make(chan chan *tail.Line, 1)
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, lines := fakeTail(filename)
+		opened <- lines
+
+		return current, nil
+	}
+
+	matched := make(chan bool, 1)
+	trail.followFile("/var/log/unfiltered.log", func(line Line) error {
+		matched <- line.Matched
+
+		return nil
+	}, true)
+
+	// Without a LineReg every line matches.
+	lines := <-opened
+	lines <- &tail.Line{Text: "anything"}
+	assert.True(t, <-matched)
+
+	trail.unfollowFile("/var/log/unfiltered.log")
+}
+
+func TestFollowSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "status.json")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"state": "starting"}`), 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{path}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		Snapshot:           true,
+		DebounceInterval:   10 * time.Millisecond,
+	})
+
+	snapshots := make(chan string, 4)
+	trail.Follow(func(line Line) error {
+		snapshots <- line.Text
+
+		return nil
+	})
+
+	events := watcher.TestData()["watchChannel"].(chan FileEvent)
+	for _, state := range []string{`{"state": "running"}`, `{"state": "stopped"}`} {
+		assert.Nil(t, ioutil.WriteFile(path, []byte(state), 0644))
+
+		// A burst of writes makes a single snapshot.
+		for i := 0; i < 3; i++ {
+			events <- FileEvent{Name: "status.json", Path: path, Time: time.Now(), Op: fsnotify.Write}
+		}
+
+		select {
+		case snapshot := <-snapshots:
+			assert.Equal(t, state, snapshot)
+		case <-time.After(time.Second):
+			t.Fatal("no snapshot delivered")
+		}
+	}
+
+	select {
+	case snapshot := <-snapshots:
+		t.Fatal("unexpected snapshot: " + snapshot)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Equal(t, []string{}, trail.FollowedFiles())
+
+	trail.End()
+}
+
+func TestFollowFileLogFields(t *testing.T) {
+	var out bytes.Buffer
+
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	logger.SetLevel(logrus.DebugLevel)
+	logger.Formatter = &logrus.JSONFormatter{}
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		Logger:           logger,
+		Desc:             "app",
+		TailErrorRetries: -1,
+	})
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, lines := fakeTail(filename)
+
+		go func() {
+			lines <- &tail.Line{Err: errors.New("input/output error")}
+		}()
+
+		return current, nil
+	}
+
+	trail.followFile("/var/log/app.log", func(line Line) error { return nil }, true)
+
+	assert.Eventually(t, func() bool {
+		return !followed.claimedBy("/var/log/app.log", trail)
+	}, time.Second, time.Millisecond)
+
+	var entries []map[string]interface{}
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var entry map[string]interface{}
+		assert.Nil(t, decoder.Decode(&entry))
+		entries = append(entries, entry)
+	}
+
+	messages := []string{}
+	for _, entry := range entries {
+		messages = append(messages, entry["msg"].(string))
+		assert.Equal(t, "app", entry["desc"])
+		assert.Equal(t, "/var/log/app.log", entry["path"])
+	}
+
+	assert.Equal(t, []string{"following", "tail failed", "giving up"}, messages)
+	assert.Equal(t, "input/output error", entries[1]["error"])
+}
+
+func TestTailConfigLimits(t *testing.T) {
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		ReOpen:            true,
+		MaxLineSize:       1024,
+		RateLimitSize:     100,
+		RateLimitInterval: time.Second,
+	})
+
+	configs := make(chan tail.Config, 1)
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		configs <- config
+		current, _ := fakeTail(filename)
+
+		return current, nil
+	}
+
+	trail.followFile("/var/log/app.log", func(line Line) error {
+		return nil
+	}, true)
+
+	config := <-configs
+	assert.True(t, config.Follow)
+	assert.True(t, config.ReOpen)
+	assert.Equal(t, 1024, config.MaxLineSize)
+	assert.NotNil(t, config.RateLimiter)
+	assert.Equal(t, uint16(100), config.RateLimiter.Size)
+	assert.Equal(t, time.Second, config.RateLimiter.LeakInterval)
+
+	assert.Eventually(t, func() bool {
+		return len(trail.FollowedFiles()) == 1
+	}, time.Second, time.Millisecond)
+	trail.unfollowFile("/var/log/app.log")
+
+	config = trail.tailConfig(false)
+	assert.False(t, config.Follow)
+	assert.False(t, config.ReOpen)
+	assert.Equal(t, 1024, config.MaxLineSize)
+	assert.NotNil(t, config.RateLimiter)
+}
+
+func TestFollowRateLimitKeepsFollowing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "busy.log")
+	var content bytes.Buffer
+	for i := 0; i < 20; i++ {
+		content.WriteString("line " + strconv.Itoa(i) + "\n")
+	}
+	assert.Nil(t, ioutil.WriteFile(path, content.Bytes(), 0644))
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		RateLimitSize:     4,
+		RateLimitInterval: time.Hour,
+	})
+
+	lines := make(chan Line, 32)
+	trail.followFile(path, func(line Line) error {
+		lines <- line
+		return nil
+	}, true)
+	defer trail.unfollowFile(path)
+
+	// The lines left once the limit is reached are dropped, and the file is
+	// still followed after the cooloff.
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, []string{path}, trail.FollowedFiles())
+
+	count := len(lines)
+	assert.True(t, count > 0 && count < 20, "%d lines delivered", count)
+	for i := 0; i < count; i++ {
+		line := <-lines
+		assert.NotContains(t, line.Text, cooloffMessage)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	_, err = f.WriteString("after\n")
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	select {
+	case line := <-lines:
+		assert.Equal(t, "after", line.Text)
+		assert.Equal(t, int64(-1), line.Offset)
+	case <-time.After(5 * time.Second):
+		t.Fatal("line not delivered after the cooloff")
+	}
+}
+
+func TestOnceRateLimitDropsCooloff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "busy.log")
+	var content bytes.Buffer
+	for i := 0; i < 20; i++ {
+		content.WriteString("line " + strconv.Itoa(i) + "\n")
+	}
+	assert.Nil(t, ioutil.WriteFile(path, content.Bytes(), 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{path}, nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		RateLimitSize:      4,
+		RateLimitInterval:  time.Hour,
+	})
+
+	var lines []Line
+	assert.Nil(t, trail.Once(func(line Line) error {
+		lines = append(lines, line)
+		return nil
+	}))
+
+	assert.True(t, len(lines) > 0 && len(lines) < 20, "%d lines delivered", len(lines))
+	for _, line := range lines {
+		assert.Nil(t, line.Err)
+		assert.NotContains(t, line.Text, cooloffMessage)
+	}
+}
+
+func TestTailConfigNoLimits(t *testing.T) {
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{RateLimitSize: 100})
+
+	config := trail.tailConfig(true)
+	assert.Equal(t, 0, config.MaxLineSize)
+	assert.Nil(t, config.RateLimiter)
+}
+
+func TestOnceInitialOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	files := map[string]time.Time{
+		"b.log": now.Add(-3 * time.Minute),
+		"c.log": now.Add(-2 * time.Minute),
+		"a.log": now.Add(-time.Minute),
+	}
+
+	var paths []string
+	for name, modTime := range files {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(path, []byte(name+"\n"), 0644))
+		assert.Nil(t, os.Chtimes(path, modTime, modTime))
+		paths = append(paths, path)
+	}
+
+	for order, expected := range map[string][]string{
+		"":           {"a.log", "b.log", "c.log"},
+		OrderPath:    {"a.log", "b.log", "c.log"},
+		OrderModTime: {"b.log", "c.log", "a.log"},
+	} {
+		watcher := MockedWatcher{}
+		watcher.On("Walk").Return(append([]string{}, paths...), nil)
+
+		trail := NewTrailWithOptions(&watcher, &TrailOptions{
+			FileIgnoreDuration: time.Hour,
+			InitialOrder:       order,
+		})
+
+		var lines []string
+		assert.Nil(t, trail.Once(func(line Line) error {
+			lines = append(lines, line.Text)
+
+			return nil
+		}))
+
+		assert.Equal(t, expected, lines, order)
+	}
+}
+
+func TestUnfollowOldFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, disabled := range []bool{false, true} {
+		// Paths are released asynchronously, so every trail gets its own.
+		path := filepath.Join(dir, strconv.FormatBool(disabled)+".log")
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+		old := time.Now().Add(-48 * time.Hour)
+		assert.Nil(t, os.Chtimes(path, old, old))
+
+		trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+			FileFollowDuration: 24 * time.Hour,
+			DisableUnfollower:  disabled,
+		})
+		trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+			current, _ := fakeTail(filename)
+
+			return current, nil
+		}
+
+		trail.followFile(path, func(line Line) error { return nil }, true)
+		assert.Eventually(t, func() bool {
+			return len(trail.FollowedFiles()) == 1
+		}, time.Second, time.Millisecond)
+
+		assert.Nil(t, trail.unfollowOldFiles())
+
+		if disabled {
+			assert.Equal(t, []string{path}, trail.FollowedFiles())
+			trail.unfollowFile(path)
+		} else {
+			assert.Equal(t, []string{}, trail.FollowedFiles())
+		}
+	}
+}
+
+func TestAddUnfollowerEndsWithTrail(t *testing.T) {
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{FileFollowDuration: time.Hour})
+
+	returned := make(chan bool)
+	go func() {
+		trail.AddUnfollower()
+		close(returned)
+	}()
+
+	trail.End()
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("the unfollower outlived its trail")
+	}
+}
+
+func TestUnfollowOldFilesStatsIdleFilesOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{FileFollowDuration: time.Hour})
+
+	stats := 0
+	trail.stat = func(name string) (os.FileInfo, error) {
+		stats++
+		return os.Stat(name)
+	}
+
+	opened := make(chan chan *tail.Line, 1)
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, lines := fakeTail(filename)
+		opened <- lines
+
+		return current, nil
+	}
+
+	received := make(chan bool)
+	handler := func(line Line) error {
+		received <- true
+		return nil
+	}
+
+	var paths []string
+	var tails []chan *tail.Line
+	for i := 0; i < 10; i++ {
+		path := filepath.Join(dir, "file"+strconv.Itoa(i)+".log")
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+		paths = append(paths, path)
+
+		trail.followFile(path, handler, true)
+		tails = append(tails, <-opened)
+	}
+	assert.Eventually(t, func() bool {
+		return len(trail.FollowedFiles()) == len(paths)
+	}, time.Second, time.Millisecond)
+
+	// Files nothing was read from yet are stat-ed.
+	assert.Nil(t, trail.unfollowOldFiles())
+	assert.Equal(t, len(paths), stats)
+
+	// Files with recent lines aren't.
+	for _, lines := range tails {
+		lines <- &tail.Line{Text: "line", Time: time.Now()}
+		<-received
+	}
+
+	stats = 0
+	assert.Nil(t, trail.unfollowOldFiles())
+	assert.Equal(t, 0, stats)
+	assert.Equal(t, len(paths), len(trail.FollowedFiles()))
+
+	// Once their last line gets old, they are stat-ed again, and unfollowed
+	// as they weren't modified since.
+	later := time.Now().Add(2 * time.Hour)
+	trail.now = func() time.Time { return later }
+	assert.Nil(t, trail.unfollowOldFiles())
+	assert.Equal(t, len(paths), stats)
+	assert.Equal(t, []string{}, trail.FollowedFiles())
+}
+
+func TestFollowLatestOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	first := filepath.Join(dir, "app-2024-06-01.log")
+	second := filepath.Join(dir, "app-2024-06-02.log")
+	ignored := filepath.Join(dir, "other.txt")
+	for i, path := range []string{first, second, ignored} {
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+		modTime := time.Now().Add(time.Duration(i-3) * time.Hour)
+		assert.Nil(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	watcher, err := NewDirectoryWatcher(dir)
+	assert.Nil(t, err)
+
+	trail := NewTrailWithOptions(watcher, &TrailOptions{
+		FileReg:            regexp.MustCompile(`^app-.*\.log$`),
+		FileIgnoreDuration: 24 * time.Hour,
+		LatestOnly:         true,
+	})
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, _ := fakeTail(filename)
+
+		return current, nil
+	}
+
+	assert.Nil(t, trail.Follow(func(line Line) error { return nil }))
+	defer trail.End()
+
+	assert.Eventually(t, func() bool {
+		return reflect.DeepEqual(trail.FollowedFiles(), []string{second})
+	}, 5*time.Second, 10*time.Millisecond)
+
+	for _, name := range []string{"app-2024-06-03.log", "app-2024-06-04.log"} {
+		newest := filepath.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(newest, []byte{}, 0644))
+
+		assert.Eventually(t, func() bool {
+			return reflect.DeepEqual(trail.FollowedFiles(), []string{newest})
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestOnceLatestOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	old := filepath.Join(dir, "app-1.log")
+	assert.Nil(t, ioutil.WriteFile(old, []byte("old\n"), 0644))
+	modTime := time.Now().Add(-time.Hour)
+	assert.Nil(t, os.Chtimes(old, modTime, modTime))
+
+	newest := filepath.Join(dir, "app-2.log")
+	assert.Nil(t, ioutil.WriteFile(newest, []byte("new\n"), 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{old, newest}, nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileIgnoreDuration: 24 * time.Hour,
+		LatestOnly:         true,
+	})
+
+	var lines []string
+	assert.Nil(t, trail.Once(func(line Line) error {
+		lines = append(lines, line.Text)
+
+		return nil
+	}))
+
+	assert.Equal(t, []string{"new"}, lines)
+}
+
+func TestIgnoreFileSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	sizes := map[string]int{"empty.log": 0, "small.log": 10, "medium.log": 100, "large.log": 1000}
+	paths := make(map[string]string)
+	for name, size := range sizes {
+		paths[name] = filepath.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(paths[name], bytes.Repeat([]byte("x"), size), 0644))
+	}
+
+	for _, c := range []struct {
+		min, max int64
+		ignored  []string
+	}{
+		{0, 0, nil},
+		{10, 0, []string{"empty.log"}},
+		{0, 100, []string{"large.log"}},
+		{11, 999, []string{"empty.log", "small.log", "large.log"}},
+		{100, 100, []string{"empty.log", "small.log", "large.log"}},
+	} {
+		trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+			FileIgnoreDuration: time.Hour,
+			MinFileSize:        c.min,
+			MaxFileSize:        c.max,
+		})
+
+		var ignored []string
+		for _, name := range []string{"empty.log", "small.log", "medium.log", "large.log"} {
+			if ignore(trail, paths[name]) {
+				ignored = append(ignored, name)
+			}
+		}
+
+		assert.Equal(t, c.ignored, ignored, "min %d max %d", c.min, c.max)
+	}
+}
+
+func TestFollowSeekStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, seek := range []string{"", SeekFromEnd, SeekFromStart} {
+		// Paths are released asynchronously, so every trail gets its own.
+		path := filepath.Join(dir, "seek-"+seek+".log")
+		assert.Nil(t, ioutil.WriteFile(path, []byte("existing\n"), 0644))
+
+		watcher := MockedWatcher{}
+		watcher.On("Walk").Return([]string{path}, nil)
+		watcher.On("Watch", mock.Anything).Return(nil)
+
+		trail := NewTrailWithOptions(&watcher, &TrailOptions{
+			FileIgnoreDuration: time.Hour,
+			SeekStart:          seek,
+		})
+
+		received := make(chan string, 4)
+		assert.Nil(t, trail.Follow(func(line Line) error {
+			received <- line.Text
+
+			return nil
+		}))
+
+		assert.Eventually(t, func() bool {
+			return len(trail.FollowedFiles()) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		assert.Nil(t, err)
+		f.WriteString("appended\n")
+		f.Close()
+
+		if seek == SeekFromStart {
+			assert.Equal(t, "existing", <-received, seek)
+		}
+		assert.Equal(t, "appended", <-received, seek)
+
+		trail.End()
+	}
+}
+
+func TestFollowNewFileGrace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	logger, hook := logtest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		Logger:             logger,
+		FileIgnoreDuration: time.Hour,
+		NewFileGrace:       50 * time.Millisecond,
+	})
+
+	tailed := make(chan string, 2)
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		tailed <- filename
+		current, _ := fakeTail(filename)
+
+		return current, nil
+	}
+
+	trail.Follow(func(line Line) error {
+		return nil
+	})
+	defer trail.End()
+
+	events := watcher.TestData()["watchChannel"].(chan FileEvent)
+
+	// A file written atomically is gone before its grace period ends.
+	temporary := filepath.Join(dir, "app.log.tmp")
+	assert.Nil(t, ioutil.WriteFile(temporary, []byte("line\n"), 0644))
+	events <- FileEvent{Name: "app.log.tmp", Path: temporary, Time: time.Now(), Op: fsnotify.Create}
+	assert.Nil(t, os.Remove(temporary))
+
+	kept := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(kept, []byte("line\n"), 0644))
+	events <- FileEvent{Name: "app.log", Path: kept, Time: time.Now(), Op: fsnotify.Create}
+
+	select {
+	case path := <-tailed:
+		assert.Equal(t, kept, path)
+	case <-time.After(5 * time.Second):
+		t.Fatal("file not followed after its grace period")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, len(tailed))
+
+	for _, entry := range hook.AllEntries() {
+		assert.True(t, entry.Level > logrus.WarnLevel, entry.Message)
+	}
+}
+
+func TestFollowMaxFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var files []string
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(dir, "app-"+strconv.Itoa(i)+".log")
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+
+		// app-4.log is the oldest, app-0.log the newest.
+		modTime := time.Now().Add(-time.Duration(i+1) * time.Minute)
+		assert.Nil(t, os.Chtimes(path, modTime, modTime))
+		files = append(files, path)
+	}
+
+	watcher, err := NewDirectoryWatcher(dir)
+	assert.Nil(t, err)
+
+	logger, hook := logtest.NewNullLogger()
+	trail := NewTrailWithOptions(watcher, &TrailOptions{
+		Logger:             logger,
+		FileIgnoreDuration: time.Hour,
+		MaxFiles:           3,
+	})
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, _ := fakeTail(filename)
+
+		return current, nil
+	}
+
+	assert.Nil(t, trail.Follow(func(line Line) error { return nil }))
+	defer trail.End()
+
+	followed := func(expected ...string) func() bool {
+		return func() bool {
+			actual := trail.FollowedFiles()
+			sort.Strings(actual)
+			return reflect.DeepEqual(actual, expected)
+		}
+	}
+
+	assert.Eventually(t, followed(files[0], files[1], files[2]), 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, hook.LastEntry().Message, "skipping 2")
+
+	// A new file takes the place of the least recently modified one.
+	newest := filepath.Join(dir, "app-5.log")
+	assert.Nil(t, ioutil.WriteFile(newest, []byte{}, 0644))
+
+	assert.Eventually(t, followed(files[0], files[1], newest), 5*time.Second, 10*time.Millisecond)
+}
+
+func TestFollowMaxFilesOnWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var files []string
+	for i := 0; i < 5; i++ {
+		path := filepath.Join(dir, "app-"+strconv.Itoa(i)+".log")
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+
+		// app-4.log is the oldest, app-0.log the newest.
+		modTime := time.Now().Add(-time.Duration(i+1) * time.Minute)
+		assert.Nil(t, os.Chtimes(path, modTime, modTime))
+		files = append(files, path)
+	}
+
+	watcher, err := NewDirectoryWatcher(dir)
+	assert.Nil(t, err)
+
+	trail := NewTrailWithOptions(watcher, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		MaxFiles:           3,
+	})
+	trail.tailFile = func(filename string, config tail.Config) (*tail.Tail, error) {
+		current, _ := fakeTail(filename)
+
+		return current, nil
+	}
+
+	assert.Nil(t, trail.Follow(func(line Line) error { return nil }))
+	defer trail.End()
+
+	assert.Eventually(t, func() bool {
+		return len(trail.FollowedFiles()) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The skipped files written to take the place of the least recently
+	// modified ones, never going beyond MaxFiles.
+	for _, path := range files[3:] {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		assert.Nil(t, err)
+		_, err = f.WriteString("line\n")
+		assert.Nil(t, err)
+		assert.Nil(t, f.Close())
+	}
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		assert.True(t, len(trail.FollowedFiles()) <= 3)
+		time.Sleep(time.Millisecond)
+	}
+
+	actual := trail.FollowedFiles()
+	sort.Strings(actual)
+	assert.Equal(t, []string{files[0], files[3], files[4]}, actual)
+}
+
+func TestNewestFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	old := filepath.Join(dir, "old.log")
+	newer := filepath.Join(dir, "newer.log")
+	for i, path := range []string{old, newer} {
+		assert.Nil(t, ioutil.WriteFile(path, []byte{}, 0644))
+		modTime := time.Now().Add(time.Duration(i-2) * time.Hour)
+		assert.Nil(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	missing := filepath.Join(dir, "missing.log")
+	assert.Equal(t, []string{newer, old}, newestFiles([]string{old, missing, newer}, 5))
+	assert.Equal(t, []string{newer}, newestFiles([]string{old, missing, newer}, 1))
+}
+
+// appendGzipMember appends text to a file as a gzip member of its own.
+func appendGzipMember(t *testing.T, path, text string) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	_, err = writer.Write([]byte(text))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+}
+
+func TestFollowGzipMembers(t *testing.T) {
+	gzipPollInterval = time.Millisecond
+	defer func() { gzipPollInterval = 250 * time.Millisecond }()
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log.gz")
+	appendGzipMember(t, path, "before\n")
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{GzipStreamReg: regexp.MustCompile(`\.gz$`)})
+	defer trail.End()
+
+	received := make(chan string, 4)
+	trail.followFile(path, func(line Line) error {
+		assert.Equal(t, int64(-1), line.Offset)
+		received <- line.Text
+		return nil
+	}, false)
+
+	// Members appended afterwards are decoded, with lines spanning them.
+	appendGzipMember(t, path, "first\nsec")
+	appendGzipMember(t, path, "ond\n")
+
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case text := <-received:
+			assert.Equal(t, expected, text)
+		case <-time.After(5 * time.Second):
+			t.Fatal("line " + expected + " not delivered")
+		}
+	}
+
+	assert.Equal(t, []string{path}, trail.FollowedFiles())
+	trail.unfollowFile(path)
+	assert.Empty(t, trail.FollowedFiles())
+}
+
+func TestFollowGzipFromStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "new.log.gz")
+	appendGzipMember(t, path, "one\ntwo\n")
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{GzipStreamReg: regexp.MustCompile(`\.gz$`)})
+	defer trail.End()
+
+	received := make(chan string, 4)
+	trail.followFile(path, func(line Line) error {
+		received <- line.Text
+		return nil
+	}, true)
+
+	assert.Equal(t, "one", <-received)
+	assert.Equal(t, "two", <-received)
+}
+
+func TestUnfollowOldGzipStreams(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log.gz")
+	appendGzipMember(t, path, "before\n")
+
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		FileFollowDuration: 24 * time.Hour,
+		GzipStreamReg:      regexp.MustCompile(`\.gz$`),
+	})
+	defer trail.End()
+
+	trail.followFile(path, func(line Line) error { return nil }, false)
+
+	assert.Nil(t, trail.unfollowOldFiles())
+	assert.Equal(t, []string{path}, trail.FollowedFiles())
+
+	old := time.Now().Add(-48 * time.Hour)
+	assert.Nil(t, os.Chtimes(path, old, old))
+
+	assert.Nil(t, trail.unfollowOldFiles())
+	assert.Empty(t, trail.FollowedFiles())
+	assert.Eventually(t, func() bool {
+		return !followed.claimedBy(path, trail)
+	}, time.Second, time.Millisecond)
+}
+
+func TestFollowActiveWindow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	active := filepath.Join(dir, "active.log")
+	assert.Nil(t, ioutil.WriteFile(active, []byte("before\n"), 0644))
+
+	stale := filepath.Join(dir, "stale.log")
+	assert.Nil(t, ioutil.WriteFile(stale, []byte("before\n"), 0644))
+	old := time.Now().Add(-2 * time.Hour)
+	assert.Nil(t, os.Chtimes(stale, old, old))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{active, stale}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		ActiveWindow:      time.Hour,
+		DisableUnfollower: true,
+		PollChanges:       true,
+		SeekStart:         SeekFromStart,
+	})
+	assert.Equal(t, time.Hour, trail.options.FileIgnoreDuration)
+	assert.Equal(t, time.Hour, trail.options.FileFollowDuration)
+	assert.False(t, trail.options.DisableUnfollower)
+
+	lines := make(chan string, 4)
+	trail.Follow(func(line Line) error {
+		lines <- line.Text
+		return nil
+	})
+	defer trail.End()
+
+	// Files older than the window aren't followed.
+	assert.Eventually(t, func() bool {
+		return reflect.DeepEqual([]string{active}, trail.FollowedFiles())
+	}, time.Second, time.Millisecond)
+
+	appendLine := func(text string) {
+		f, err := os.OpenFile(active, os.O_APPEND|os.O_WRONLY, 0644)
+		assert.Nil(t, err)
+		_, err = f.WriteString(text + "\n")
+		assert.Nil(t, err)
+		assert.Nil(t, f.Close())
+	}
+
+	receive := func() string {
+		select {
+		case text := <-lines:
+			return text
+		case <-time.After(5 * time.Second):
+			t.Fatal("line not delivered")
+			return ""
+		}
+	}
+
+	// The tail reads the file before it is written to.
+	assert.Equal(t, "before", receive())
+
+	appendLine("while active")
+	assert.Equal(t, "while active", receive())
+
+	// The file ages out of the window, along with the line last read from
+	// it, and is unfollowed.
+	later := time.Now().Add(2 * time.Hour)
+	trail.now = func() time.Time { return later }
+	assert.Nil(t, trail.unfollowOldFiles())
+	assert.Equal(t, []string{}, trail.FollowedFiles())
+	assert.Eventually(t, func() bool {
+		return !followed.claimedBy(active, trail)
+	}, time.Second, time.Millisecond)
+
+	// Writing to it again follows it from where it went stale.
+	appendLine("after going stale")
+	events := watcher.TestData()["watchChannel"].(chan FileEvent)
+	events <- FileEvent{Name: "active.log", Path: active, Time: time.Now(), Op: fsnotify.Write}
+
+	assert.Equal(t, "after going stale", receive())
+	assert.Equal(t, []string{active}, trail.FollowedFiles())
+}
+
+func TestFollowReopenOnTruncate(t *testing.T) {
+	defer func(interval time.Duration) { truncateCheckInterval = interval }(truncateCheckInterval)
+	truncateCheckInterval = 10 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("before\n"), 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{path}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		ReopenOnTruncate:   true,
+		PollChanges:        true,
+		SeekStart:          SeekFromStart,
+	})
+
+	lines := make(chan string, 4)
+	trail.Follow(func(line Line) error {
+		lines <- line.Text
+		return nil
+	})
+	defer trail.End()
+
+	assert.Eventually(t, func() bool {
+		return reflect.DeepEqual([]string{path}, trail.FollowedFiles())
+	}, time.Second, time.Millisecond)
+
+	writeLine := func(flag int, text string) {
+		f, err := os.OpenFile(path, flag|os.O_WRONLY, 0644)
+		assert.Nil(t, err)
+		_, err = f.WriteString(text + "\n")
+		assert.Nil(t, err)
+		assert.Nil(t, f.Close())
+	}
+
+	receive := func() string {
+		select {
+		case text := <-lines:
+			return text
+		case <-time.After(5 * time.Second):
+			t.Fatal("line not delivered")
+			return ""
+		}
+	}
+
+	// The tail library only notices changes once it watches the file, which
+	// it starts doing at its end.
+	assert.Equal(t, "before", receive())
+	time.Sleep(100 * time.Millisecond)
+
+	writeLine(os.O_APPEND, "a line long enough to truncate below")
+	assert.Equal(t, "a line long enough to truncate below", receive())
+
+	// Truncated in place, the file is shorter than where the tail got.
+	writeLine(os.O_TRUNC, "short")
+	assert.Equal(t, "short", receive())
+
+	writeLine(os.O_APPEND, "after")
+	assert.Equal(t, "after", receive())
+	assert.Equal(t, []string{path}, trail.FollowedFiles())
+}
+
+func TestFollowReopenOnTruncateNoDuplicates(t *testing.T) {
+	defer func(interval time.Duration) { truncateCheckInterval = interval }(truncateCheckInterval)
+	truncateCheckInterval = 50 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("before\n"), 0644))
+
+	watcher := MockedWatcher{}
+	watcher.On("Walk").Return([]string{path}, nil)
+	watcher.On("Watch", mock.AnythingOfType("chan eye.FileEvent")).Return(nil)
+
+	trail := NewTrailWithOptions(&watcher, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		ReopenOnTruncate:   true,
+		PollChanges:        false,
+		SeekStart:          SeekFromStart,
+	})
+
+	lines := make(chan string, 8)
+	trail.Follow(func(line Line) error {
+		lines <- line.Text
+		return nil
+	})
+	defer trail.End()
+
+	assert.Eventually(t, func() bool {
+		return reflect.DeepEqual([]string{path}, trail.FollowedFiles())
+	}, time.Second, time.Millisecond)
+
+	writeLine := func(flag int, text string) {
+		f, err := os.OpenFile(path, flag|os.O_WRONLY, 0644)
+		assert.Nil(t, err)
+		_, err = f.WriteString(text + "\n")
+		assert.Nil(t, err)
+		assert.Nil(t, f.Close())
+	}
+
+	var received []string
+	collect := func(d time.Duration) {
+		timeout := time.After(d)
+		for {
+			select {
+			case text := <-lines:
+				received = append(received, text)
+			case <-timeout:
+				return
+			}
+		}
+	}
+
+	// The tail library only notices changes once it watches the file, which
+	// it starts doing at its end.
+	collect(300 * time.Millisecond)
+
+	writeLine(os.O_APPEND, "a line long enough to truncate below")
+	collect(300 * time.Millisecond)
+
+	// The tail library re-opens the truncated file by itself, which must not
+	// be followed from the start once more.
+	writeLine(os.O_TRUNC, "short")
+	collect(500 * time.Millisecond)
+
+	writeLine(os.O_APPEND, "after")
+	collect(500 * time.Millisecond)
+
+	assert.Equal(t, []string{"before", "a line long enough to truncate below", "short", "after"}, received)
+}
+
+func TestIsTruncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("line\n"), 0644))
+
+	assert.False(t, isTruncated(path, 5, 0, 0))
+	assert.True(t, isTruncated(path, 6, 0, 0))
+	assert.False(t, isTruncated(path, -1, 0, 0))
+	assert.False(t, isTruncated(filepath.Join(dir, "missing.log"), 6, 0, 0))
+}
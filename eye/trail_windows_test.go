@@ -0,0 +1,29 @@
+package eye
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnoreNormalizesSeparators(t *testing.T) {
+	trail := NewTrailWithOptions(&MockedWatcher{}, &TrailOptions{
+		FileIgnoreDuration: time.Hour,
+		PathReg:            regexp.MustCompile("/app/logs$"),
+		FullPathReg:        regexp.MustCompile(`^C:/app/logs/.*\.log$`),
+	})
+
+	// Missing files aren't old enough to be ignored.
+	assert.False(t, ignore(trail, `C:\app\logs\missing.log`))
+	assert.True(t, ignore(trail, `C:\app\other\missing.log`))
+	assert.True(t, ignore(trail, `C:\app\logs\missing.txt`))
+
+	// Patterns written with backslashes keep matching.
+	trail.options.PathReg = regexp.MustCompile(`\\app\\logs$`)
+	trail.options.FullPathReg = regexp.MustCompile(`^C:\\app\\logs\\.*\.log$`)
+
+	assert.False(t, ignore(trail, `C:\app\logs\missing.log`))
+	assert.True(t, ignore(trail, `C:\app\other\missing.log`))
+}
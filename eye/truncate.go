@@ -0,0 +1,53 @@
+package eye
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/hpcloud/tail"
+)
+
+// truncateCheckInterval is how often followed files are checked for having
+// been truncated in place, with ReopenOnTruncate.
+var truncateCheckInterval = time.Second
+
+// errTruncated ends the consumption of a tail whose file was truncated, so
+// that it is followed again from its beginning.
+var errTruncated = errors.New("file truncated")
+
+// isTruncated reports whether a file is now shorter than the offset its tail
+// reads from, as happens when it is truncated in place rather than rotated and
+// the tail library doesn't notice. Unknown offsets, which are negative, are
+// never truncated, nor are files other than the one the tail reads, identified
+// by inode and dev when known: the tail library re-opens those itself.
+func isTruncated(path string, offset int64, inode, dev uint64) bool {
+	if offset < 0 {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	if current, currentDev := infoID(info); inode != 0 && (current != inode || currentDev != dev) {
+		return false
+	}
+
+	return info.Size() < offset
+}
+
+// restartTruncated stops the tail of a truncated file and returns where to
+// follow it from again: its beginning. Lines the tail is still sending are
+// dropped, as they were read from before the truncation.
+func restartTruncated(current *tail.Tail) *tail.SeekInfo {
+	go func() {
+		for range current.Lines {
+		}
+	}()
+	current.Stop()
+
+	return &tail.SeekInfo{Offset: 0, Whence: io.SeekStart}
+}
@@ -0,0 +1,105 @@
+package eye
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// NewValidatedTrail creates a Trail like NewTrailWithOptions, failing instead
+// when the options are invalid, such as negative durations or sizes, unknown
+// policies, or settings which cancel one another out.
+func NewValidatedTrail(watcher Watcher, options *TrailOptions) (*Trail, error) {
+	if watcher == nil {
+		return nil, errors.New("a trail needs a watcher")
+	}
+
+	if options == nil {
+		return nil, errors.New("a trail needs options")
+	}
+
+	if err := validateOptions(options); err != nil {
+		return nil, err
+	}
+
+	return NewTrailWithOptions(watcher, options), nil
+}
+
+// validateOptions returns the first problem found with options.
+func validateOptions(options *TrailOptions) error {
+	for _, d := range []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"FileIgnoreDuration", options.FileIgnoreDuration},
+		{"FileFollowDuration", options.FileFollowDuration},
+		{"ActiveWindow", options.ActiveWindow},
+		{"FollowAfterRemove", options.FollowAfterRemove},
+		{"DebounceInterval", options.DebounceInterval},
+		{"NewFileGrace", options.NewFileGrace},
+		{"RateLimitInterval", options.RateLimitInterval},
+	} {
+		if d.duration < 0 {
+			return errors.New(d.name + " must not be negative, got " + d.duration.String())
+		}
+	}
+
+	for _, n := range []struct {
+		name  string
+		value int64
+	}{
+		{"MinFileSize", options.MinFileSize},
+		{"MaxFileSize", options.MaxFileSize},
+		{"MaxFiles", int64(options.MaxFiles)},
+		{"MaxLineSize", int64(options.MaxLineSize)},
+		{"HandlerBufferSize", int64(options.HandlerBufferSize)},
+	} {
+		if n.value < 0 {
+			return errors.New(n.name + " must not be negative, got " + strconv.FormatInt(n.value, 10))
+		}
+	}
+
+	// The active window replaces both durations.
+	if options.ActiveWindow == 0 {
+		if options.FileIgnoreDuration == 0 {
+			return errors.New("FileIgnoreDuration must be positive, or every file is ignored")
+		}
+
+		if options.FileFollowDuration == 0 && !options.DisableUnfollower {
+			return errors.New("FileFollowDuration must be positive, or every file is unfollowed, unless DisableUnfollower is set")
+		}
+	}
+
+	if options.MaxFileSize > 0 && options.MinFileSize > options.MaxFileSize {
+		return errors.New("MinFileSize " + strconv.FormatInt(options.MinFileSize, 10) +
+			" is larger than MaxFileSize " + strconv.FormatInt(options.MaxFileSize, 10))
+	}
+
+	if (options.RateLimitSize == 0) != (options.RateLimitInterval == 0) {
+		return errors.New("RateLimitSize and RateLimitInterval must be set together")
+	}
+
+	if options.LatestOnly && options.MaxFiles > 0 {
+		return errors.New("LatestOnly and MaxFiles can't be set together")
+	}
+
+	switch options.HandlerBufferPolicy {
+	case "", BufferBlock, BufferDropOldest:
+	default:
+		return errors.New("unknown HandlerBufferPolicy " + options.HandlerBufferPolicy)
+	}
+
+	switch options.SeekStart {
+	case "", SeekFromEnd, SeekFromStart:
+	default:
+		return errors.New("unknown SeekStart " + options.SeekStart)
+	}
+
+	switch options.InitialOrder {
+	case "", OrderPath, OrderModTime:
+	default:
+		return errors.New("unknown InitialOrder " + options.InitialOrder)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package eye
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewValidatedTrail(t *testing.T) {
+	valid := func() *TrailOptions {
+		return &TrailOptions{
+			FileIgnoreDuration: time.Hour,
+			FileFollowDuration: time.Hour,
+		}
+	}
+
+	trail, err := NewValidatedTrail(&MockedWatcher{}, valid())
+	assert.Nil(t, err)
+	assert.NotNil(t, trail)
+
+	_, err = NewValidatedTrail(nil, valid())
+	assert.EqualError(t, err, "a trail needs a watcher")
+
+	_, err = NewValidatedTrail(&MockedWatcher{}, nil)
+	assert.EqualError(t, err, "a trail needs options")
+
+	for _, test := range []struct {
+		change func(o *TrailOptions)
+		err    string
+	}{
+		{func(o *TrailOptions) { o.FileIgnoreDuration = -time.Second }, "FileIgnoreDuration must not be negative, got -1s"},
+		{func(o *TrailOptions) { o.FileFollowDuration = -time.Second }, "FileFollowDuration must not be negative, got -1s"},
+		{func(o *TrailOptions) { o.ActiveWindow = -time.Second }, "ActiveWindow must not be negative, got -1s"},
+		{func(o *TrailOptions) { o.FollowAfterRemove = -time.Second }, "FollowAfterRemove must not be negative, got -1s"},
+		{func(o *TrailOptions) { o.DebounceInterval = -time.Second }, "DebounceInterval must not be negative, got -1s"},
+		{func(o *TrailOptions) { o.NewFileGrace = -time.Second }, "NewFileGrace must not be negative, got -1s"},
+		{func(o *TrailOptions) { o.RateLimitSize, o.RateLimitInterval = 1, -time.Second }, "RateLimitInterval must not be negative, got -1s"},
+		{func(o *TrailOptions) { o.MinFileSize = -1 }, "MinFileSize must not be negative, got -1"},
+		{func(o *TrailOptions) { o.MaxFileSize = -1 }, "MaxFileSize must not be negative, got -1"},
+		{func(o *TrailOptions) { o.MaxFiles = -1 }, "MaxFiles must not be negative, got -1"},
+		{func(o *TrailOptions) { o.MaxLineSize = -1 }, "MaxLineSize must not be negative, got -1"},
+		{func(o *TrailOptions) { o.HandlerBufferSize = -1 }, "HandlerBufferSize must not be negative, got -1"},
+		{func(o *TrailOptions) { o.FileIgnoreDuration = 0 }, "FileIgnoreDuration must be positive, or every file is ignored"},
+		{func(o *TrailOptions) { o.FileFollowDuration = 0 }, "FileFollowDuration must be positive, or every file is unfollowed, unless DisableUnfollower is set"},
+		{func(o *TrailOptions) { o.MinFileSize, o.MaxFileSize = 10, 5 }, "MinFileSize 10 is larger than MaxFileSize 5"},
+		{func(o *TrailOptions) { o.RateLimitSize = 10 }, "RateLimitSize and RateLimitInterval must be set together"},
+		{func(o *TrailOptions) { o.RateLimitInterval = time.Second }, "RateLimitSize and RateLimitInterval must be set together"},
+		{func(o *TrailOptions) { o.LatestOnly, o.MaxFiles = true, 2 }, "LatestOnly and MaxFiles can't be set together"},
+		{func(o *TrailOptions) { o.HandlerBufferPolicy = "drop-newest" }, "unknown HandlerBufferPolicy drop-newest"},
+		{func(o *TrailOptions) { o.SeekStart = "middle" }, "unknown SeekStart middle"},
+		{func(o *TrailOptions) { o.InitialOrder = "size" }, "unknown InitialOrder size"},
+	} {
+		options := valid()
+		test.change(options)
+
+		trail, err := NewValidatedTrail(&MockedWatcher{}, options)
+		assert.Nil(t, trail)
+		assert.EqualError(t, err, test.err)
+	}
+
+	// Durations left to zero are fine when something else stands for them.
+	for _, options := range []*TrailOptions{
+		{ActiveWindow: time.Hour},
+		{FileIgnoreDuration: time.Hour, DisableUnfollower: true},
+		{FileIgnoreDuration: time.Hour, FileFollowDuration: time.Hour, MinFileSize: 10},
+		{FileIgnoreDuration: time.Hour, FileFollowDuration: time.Hour, RateLimitSize: 10, RateLimitInterval: time.Second},
+	} {
+		_, err := NewValidatedTrail(&MockedWatcher{}, options)
+		assert.Nil(t, err)
+	}
+}
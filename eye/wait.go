@@ -0,0 +1,68 @@
+package eye
+
+import (
+	"os"
+	"path/filepath"
+
+	fsnotify "gopkg.in/fsnotify.v1"
+)
+
+// WaitForPath blocks until path exists. Its closest existing ancestor is
+// watched for changes in the meantime, moving down as intermediate directories
+// get created. It returns false, without waiting any further, once stop is
+// closed or receives a value.
+func WaitForPath(path string, stop <-chan bool) (bool, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return false, err
+	}
+	defer watcher.Close()
+
+	watched := ""
+
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return true, nil
+		}
+
+		ancestor := existingAncestor(path)
+		if ancestor != watched {
+			if watched != "" {
+				watcher.Remove(watched)
+			}
+
+			if err := watcher.Add(ancestor); err != nil {
+				return false, err
+			}
+			watched = ancestor
+
+			// The path may have shown up before the watch was added.
+			continue
+		}
+
+		select {
+		case <-watcher.Events:
+		case err := <-watcher.Errors:
+			return false, err
+		case <-stop:
+			return false, nil
+		}
+	}
+}
+
+// existingAncestor returns the closest parent directory of path which exists.
+func existingAncestor(path string) string {
+	dir := filepath.Dir(filepath.Clean(path))
+
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,62 @@
+package eye
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app", "logs")
+
+	created := make(chan bool)
+	go func() {
+		ok, err := WaitForPath(path, nil)
+		assert.Nil(t, err)
+		created <- ok
+	}()
+
+	select {
+	case <-created:
+		t.Fatal("returned before the path was created")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "app"), 0755))
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, os.Mkdir(path, 0755))
+
+	select {
+	case ok := <-created:
+		assert.True(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("path creation was not noticed")
+	}
+
+	// Following begins once the directory exists.
+	watcher, err := NewDirectoryWatcher(path)
+	assert.Nil(t, err)
+	assert.NotNil(t, watcher)
+}
+
+func TestWaitForPathStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sauron")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	stop := make(chan bool)
+	close(stop)
+
+	ok, err := WaitForPath(filepath.Join(dir, "missing"), stop)
+
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
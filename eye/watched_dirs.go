@@ -0,0 +1,221 @@
+package eye
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/fsnotify.v1"
+)
+
+// evictedPollInterval is how often the directories whose watch was evicted
+// are checked for changes.
+var evictedPollInterval = time.Second
+
+// evictedDir is what a directory looked like when its watch was evicted, to
+// tell what changed in it once it is watched again.
+type evictedDir struct {
+	modified time.Time
+	names    map[string]bool
+}
+
+// watchedDirs watches a directory along with its subdirectories, within max
+// watches, the root included. Beyond it, the watch of the least recently
+// active directory is evicted, and the directory is polled instead until it
+// changes, when it is watched again. It is owned by the goroutine of the
+// DirectoryWatcher.
+type watchedDirs struct {
+	watcher *fsnotify.Watcher
+	root    string
+	max     int
+	logger  *logrus.Logger
+
+	// recent orders the watched subdirectories, the most recently active
+	// first.
+	recent  *list.List
+	watched map[string]*list.Element
+	evicted map[string]*evictedDir
+}
+
+// newWatchedDirs watches root and every directory below it.
+func newWatchedDirs(watcher *fsnotify.Watcher, root string, max int, logger *logrus.Logger) (*watchedDirs, error) {
+	d := &watchedDirs{
+		watcher: watcher,
+		root:    filepath.Clean(root),
+		max:     max,
+		logger:  logger,
+		recent:  list.New(),
+		watched: make(map[string]*list.Element),
+		evicted: make(map[string]*evictedDir),
+	}
+
+	if err := watcher.Add(d.root); err != nil {
+		return nil, err
+	}
+
+	d.addTree(d.root)
+
+	return d, nil
+}
+
+// addTree watches the directories below dir.
+func (d *watchedDirs) addTree(dir string) {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() || path == dir {
+			return nil
+		}
+
+		d.add(path)
+
+		return nil
+	})
+}
+
+// add watches a subdirectory, evicting the least recently active one when the
+// watches are all taken.
+func (d *watchedDirs) add(dir string) {
+	if element, ok := d.watched[dir]; ok {
+		d.recent.MoveToFront(element)
+		return
+	}
+
+	for d.max > 0 && len(d.watched)+1 >= d.max {
+		if d.recent.Len() == 0 {
+			// The root takes the only watch.
+			d.evicted[dir] = snapshotDir(dir)
+			return
+		}
+
+		d.evict(d.recent.Back().Value.(string))
+	}
+
+	if err := d.watcher.Add(dir); err != nil {
+		d.logger.WithField("path", dir).Errorln("Failed to watch: " + err.Error())
+		return
+	}
+
+	d.watched[dir] = d.recent.PushFront(dir)
+}
+
+// evict stops watching a subdirectory, polling it from now on.
+func (d *watchedDirs) evict(dir string) {
+	d.recent.Remove(d.watched[dir])
+	delete(d.watched, dir)
+	d.watcher.Remove(dir)
+	d.evicted[dir] = snapshotDir(dir)
+
+	d.logger.WithField("path", dir).Infoln("Watching " + strconv.Itoa(d.max) +
+		" directories already, evicting the least recently active one")
+}
+
+// handle keeps track of the directories affected by an event: the directory
+// of the file is active, created directories are watched, and removed ones
+// forgotten.
+func (d *watchedDirs) handle(event fsnotify.Event) {
+	if element, ok := d.watched[filepath.Dir(event.Name)]; ok {
+		d.recent.MoveToFront(element)
+	}
+
+	switch {
+	case event.Op&fsnotify.Create != 0:
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			d.add(event.Name)
+			d.addTree(event.Name)
+		}
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		d.forget(event.Name)
+	}
+}
+
+// forget stops tracking a directory which is gone, along with those below it.
+func (d *watchedDirs) forget(dir string) {
+	prefix := dir + string(filepath.Separator)
+
+	for path, element := range d.watched {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			d.recent.Remove(element)
+			delete(d.watched, path)
+			d.watcher.Remove(path)
+		}
+	}
+
+	for path := range d.evicted {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			delete(d.evicted, path)
+		}
+	}
+}
+
+// poll watches again the evicted directories which changed, passing the
+// entries created or removed while they weren't watched to send. It returns
+// false as soon as send does.
+func (d *watchedDirs) poll(send func(fsnotify.Event) bool) bool {
+	for dir, evicted := range d.evicted {
+		info, err := os.Stat(dir)
+		if err != nil {
+			delete(d.evicted, dir)
+			continue
+		}
+
+		if info.ModTime().Equal(evicted.modified) {
+			continue
+		}
+
+		// The entries are listed before the watch is added, so that those
+		// created in between are only reported by the watch.
+		current := snapshotDir(dir)
+		delete(d.evicted, dir)
+		d.add(dir)
+
+		d.logger.WithField("path", dir).Debugln("Watching again on activity")
+
+		for name := range current.names {
+			if evicted.names[name] {
+				continue
+			}
+
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				d.add(path)
+				d.addTree(path)
+			}
+
+			if !send(fsnotify.Event{Name: path, Op: fsnotify.Create}) {
+				return false
+			}
+		}
+
+		for name := range evicted.names {
+			if !current.names[name] {
+				if !send(fsnotify.Event{Name: filepath.Join(dir, name), Op: fsnotify.Remove}) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// snapshotDir records the modification time and the entries of a directory.
+// The time is read first, so that later changes are noticed.
+func snapshotDir(dir string) *evictedDir {
+	snapshot := &evictedDir{names: make(map[string]bool)}
+
+	if info, err := os.Stat(dir); err == nil {
+		snapshot.modified = info.ModTime()
+	}
+
+	if entries, err := ioutil.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			snapshot.names[entry.Name()] = true
+		}
+	}
+
+	return snapshot
+}
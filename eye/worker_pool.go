@@ -0,0 +1,106 @@
+package eye
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// WorkerPool runs line handlers on a fixed number of workers, bounding how
+// many of them run at once across every file and trail sharing the pool.
+// Lines of the same file always go to the same worker, so they are handled in
+// order.
+type WorkerPool struct {
+	queues []chan poolJob
+	wg     sync.WaitGroup
+	logger *logrus.Logger
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// errPoolClosed is returned by the handlers of a closed pool.
+var errPoolClosed = errors.New("the worker pool is closed")
+
+// poolJob is a line waiting for its handler.
+type poolJob struct {
+	line    Line
+	handler LineHandler
+}
+
+// NewWorkerPool creates a pool of the given number of workers and starts them.
+func NewWorkerPool(workers int, logger *logrus.Logger) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	if logger == nil {
+		logger = logrus.New()
+	}
+
+	p := &WorkerPool{
+		queues: make([]chan poolJob, workers),
+		logger: logger,
+	}
+
+	for i := range p.queues {
+		p.queues[i] = make(chan poolJob)
+		p.wg.Add(1)
+
+		go p.work(p.queues[i])
+	}
+
+	return p
+}
+
+// Handler wraps a handler so that it runs on the pool. The returned handler
+// blocks until a worker picks the line up. Errors of the wrapped handler are
+// logged by the pool. Lines handed over once the pool is closed are dropped,
+// with an error.
+func (p *WorkerPool) Handler(handler LineHandler) LineHandler {
+	return func(line Line) error {
+		p.mutex.RLock()
+		defer p.mutex.RUnlock()
+
+		if p.closed {
+			return errPoolClosed
+		}
+
+		hash := fnv.New32a()
+		hash.Write([]byte(line.Path))
+
+		p.queues[hash.Sum32()%uint32(len(p.queues))] <- poolJob{line: line, handler: handler}
+
+		return nil
+	}
+}
+
+// Close stops the workers once they are done with the lines handed to them,
+// waiting for the lines being handed over. Closing the pool again does
+// nothing.
+func (p *WorkerPool) Close() {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return
+	}
+	p.closed = true
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.mutex.Unlock()
+
+	p.wg.Wait()
+}
+
+// work handles the lines of a queue until it is closed.
+func (p *WorkerPool) work(queue chan poolJob) {
+	defer p.wg.Done()
+
+	for job := range queue {
+		if err := job.handler(job.line); err != nil {
+			p.logger.Errorln("Handler failed for " + job.line.Path + ": " + err.Error())
+		}
+	}
+}
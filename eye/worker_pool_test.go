@@ -0,0 +1,118 @@
+package eye
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPoolBoundsConcurrency(t *testing.T) {
+	pool := NewWorkerPool(2, nil)
+
+	var running, peak int32
+	handler := pool.Handler(func(line Line) error {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&peak)
+			if current <= max || atomic.CompareAndSwapInt32(&peak, max, current) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(file int) {
+			defer wg.Done()
+
+			for j := 0; j < 5; j++ {
+				handler(Line{Path: "file" + strconv.Itoa(file) + ".log", Text: strconv.Itoa(j)})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	pool.Close()
+
+	assert.True(t, atomic.LoadInt32(&peak) <= 2)
+	assert.True(t, atomic.LoadInt32(&peak) >= 1)
+}
+
+func TestWorkerPoolKeepsFileOrder(t *testing.T) {
+	pool := NewWorkerPool(4, nil)
+
+	var mutex sync.Mutex
+	received := make(map[string][]string)
+	handler := pool.Handler(func(line Line) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		received[line.Path] = append(received[line.Path], line.Text)
+
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(file int) {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				handler(Line{Path: "file" + strconv.Itoa(file) + ".log", Text: strconv.Itoa(j)})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	pool.Close()
+
+	for i := 0; i < 4; i++ {
+		lines := received["file"+strconv.Itoa(i)+".log"]
+		assert.Equal(t, 50, len(lines))
+
+		for j, text := range lines {
+			assert.Equal(t, strconv.Itoa(j), text)
+		}
+	}
+}
+
+func TestWorkerPoolCloseWhileHandling(t *testing.T) {
+	pool := NewWorkerPool(2, nil)
+
+	handler := pool.Handler(func(line Line) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(file int) {
+			defer wg.Done()
+
+			for j := 0; j < 20; j++ {
+				handler(Line{Path: "file" + strconv.Itoa(file) + ".log", Text: strconv.Itoa(j)})
+			}
+		}(i)
+	}
+
+	// Lines handed over while and after the pool closes are dropped rather
+	// than sent on a closed queue.
+	time.Sleep(5 * time.Millisecond)
+	pool.Close()
+	wg.Wait()
+
+	assert.Equal(t, errPoolClosed, handler(Line{Path: "late.log"}))
+	pool.Close()
+}